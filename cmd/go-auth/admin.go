@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+// subcommand runs a command of a group, like "create" of "user".
+type subcommand func(ctx context.Context, conf *programConf, args []string) error
+
+// runGroup runs the subcommand named by the first of the args.
+func runGroup(ctx context.Context, conf *programConf, group string, args []string,
+	subcommands map[string]subcommand) error {
+	if len(args) == 0 || subcommands[args[0]] == nil {
+		names := make([]string, 0, len(subcommands))
+		for name := range subcommands {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "usage: go-auth %s <%s> [flags] [args]\n", group, strings.Join(names, "|"))
+
+		return errUsage
+	}
+
+	return subcommands[args[0]](ctx, conf, args[1:])
+}
+
+// parseArgs parses the flags and checks the count of the positional args.
+func parseArgs(flags *flag.FlagSet, args []string, usage string, nArgs int) error {
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go-auth "+usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller.
+	}
+
+	if flags.NArg() != nArgs {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	return nil
+}
+
+// setupDB connects to the database, migrating it up unless the auto migration is off.
+func setupDB(ctx context.Context, conf *programConf) (*database.Database, error) {
+	var migrations fs.FS
+
+	if conf.AutoMigrate {
+		migrations = storage.Migrations()
+	}
+
+	return database.Setup(ctx, conf.DBUri, migrations) //nolint:wrapcheck // wrapped by the caller.
+}
+
+// readPassword reads the first line of the stdin.
+func readPassword() (string, error) {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+
+	return strings.TrimRight(password, "\r\n"), nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/service/bootstrap"
+)
+
+// runBootstrap creates the first go-auth root, the password is the first line of the stdin.
+// Refused if a root exists.
+func runBootstrap(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "bootstrap <username> < password", 1); err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	dbUser, err := bootstrap.CreateRoot(ctx, dbInstance, model.UserCreds{
+		UserUsernme: model.UserUsernme{Username: flags.Arg(0)},
+		Password:    password,
+	})
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "the go-auth root %s was created, id %s\n", dbUser.Username, dbUser.ID)
+
+	return nil
+}
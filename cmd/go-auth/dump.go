@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/service/backup"
+)
+
+const (
+	secretFileMode = 0o600
+	publicFileMode = 0o644
+)
+
+// runDump writes the full state dump to a file or the stdout.
+func runDump(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	output := flags.String("o", "-", "output file, - for the stdout")
+	withKeys := flags.Bool("keys", false, "include the signing keys")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	dump, err := backup.Export(ctx, dbInstance)
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	if *withKeys {
+		if dump.Keys, err = readKeys(conf); err != nil {
+			return fmt.Errorf("dump: %w", err)
+		}
+	}
+
+	var writer io.Writer = os.Stdout
+
+	if *output != "-" {
+		// the dump holds the password hashes.
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, secretFileMode)
+		if err != nil {
+			return fmt.Errorf("dump: %w", err)
+		}
+
+		defer file.Close()
+
+		writer = file
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(dump); err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	return nil
+}
+
+// runRestore restores the state dump to an empty database.
+func runRestore(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	withKeys := flags.Bool("keys", false, "write the dumped signing keys to the configured paths, never overwrites")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go-auth restore [flags] <file>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	raw, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	var dump model.StateDump
+
+	if err = json.Unmarshal(raw, &dump); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	if *withKeys && dump.Keys == nil {
+		return fmt.Errorf("restore: %w: the dump has no keys", errUsage)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	if err = backup.Restore(ctx, dbInstance, &dump); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	if *withKeys {
+		if err = writeKeys(conf, dump.Keys); err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func readKeys(conf *programConf) (*model.DumpKeys, error) {
+	privatePem, err := os.ReadFile(conf.PrivatePemPath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller.
+	}
+
+	publicPem, err := os.ReadFile(conf.PublicPemPath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller.
+	}
+
+	return &model.DumpKeys{PrivatePem: string(privatePem), PublicPem: string(publicPem)}, nil
+}
+
+func writeKeys(conf *programConf, keys *model.DumpKeys) error {
+	if err := writeNewFile(conf.PrivatePemPath, []byte(keys.PrivatePem), secretFileMode); err != nil {
+		return err
+	}
+
+	return writeNewFile(conf.PublicPemPath, []byte(keys.PublicPem), publicFileMode)
+}
+
+// writeNewFile writes the file, failing if it exists.
+func writeNewFile(path string, content []byte, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller.
+	}
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err //nolint:wrapcheck // wrapped by the caller.
+}
@@ -2,18 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/eldarbr/go-auth/internal/model"
 	"github.com/eldarbr/go-auth/internal/service/encrypt"
-	"github.com/eldarbr/go-auth/internal/service/handler"
-	"github.com/eldarbr/go-auth/internal/service/server"
-	"github.com/eldarbr/go-auth/pkg/cache"
 	"github.com/eldarbr/go-auth/pkg/config"
-	"github.com/eldarbr/go-auth/pkg/database"
 )
 
 type programConf struct {
@@ -26,17 +26,33 @@ type programConf struct {
 	PprofServingURI     string        `yaml:"pprofServingUri"`
 	EnableTLSServing    bool          `yaml:"enableTlsServing"`
 	AuthTokenTTL        time.Duration `yaml:"authTokenTtl"`
+	ImpersonationTTL    time.Duration `yaml:"impersonationTokenTtl"` // caps the impersonation tokens' TTL.
 	RateLimitRequests   int           `yaml:"rateLimitRequests"`
 	RateLimitTTL        int64         `yaml:"rateLimitTtl"`
 	RateLimitCapacity   int           `yaml:"rateLimitCapacity"`
 	CookieSessionDomain string        `yaml:"cookieSessionDomain"`
+	UserPurgeAfter      time.Duration `yaml:"userPurgeAfter"`  // 0 - never purge the deleted users.
+	TokenAttributes     []string      `yaml:"tokenAttributes"` // user attributes copied into the tokens.
+	GenerateKeys        bool          `yaml:"generateKeys"`    // generate the key pair on start if none exists.
+	KeyAlgorithm        string        `yaml:"keyAlgorithm"`    // of the generated keys, rsa or ecdsa.
+	KeyBits             int           `yaml:"keyBits"`         // of the generated keys, 0 - the algorithm's default.
+	AutoMigrate         bool          `yaml:"autoMigrate"`     // migrate the database up on start, on by default.
+
+	WebhookDeliveryPeriod time.Duration `yaml:"webhookDeliveryPeriod"` // 0 - never deliver the webhooks.
+	WebhookTimeout        time.Duration `yaml:"webhookTimeout"`        // of a delivery attempt.
+	WebhookRetention      time.Duration `yaml:"webhookRetention"`      // of the delivered events, 0 - keep.
+
+	Registration model.RegistrationPolicy `yaml:"registration"` // self-registration, disabled by default.
 }
 
 const (
 	CacheAutoEvictPeriodSeconds = 120
-	DBMigrationsPath            = "file://./sql" // expect the migrations to be next to the app.
+	JanitorPeriod               = time.Hour
+	ConfigPath                  = "secret/config.yaml"
 )
 
+var errUnknownCommand = errors.New("unknown command")
+
 func (conf *programConf) setDefaults() {
 	if conf == nil {
 		return
@@ -45,95 +61,90 @@ func (conf *programConf) setDefaults() {
 	conf.RateLimitRequests = 2
 	conf.RateLimitTTL = 10
 	conf.RateLimitCapacity = 100
+	conf.ImpersonationTTL = 15 * time.Minute
+	conf.KeyAlgorithm = encrypt.KeyAlgorithmRSA
+	conf.AutoMigrate = true
+	conf.WebhookDeliveryPeriod = 10 * time.Second
+	conf.WebhookTimeout = 10 * time.Second
+	conf.WebhookRetention = 7 * 24 * time.Hour
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run executes the command given in the arguments, serve by default.
+// Returns the exit code.
+func run() int {
 	var conf programConf
 
 	conf.setDefaults()
 
-	programContext, programContextStop := signal.NotifyContext(context.Background(), syscall.SIGINT)
-
-	defer programContextStop()
-
-	err := config.ParseConfig("secret/config.yaml", &conf)
-	if err != nil {
-		log.Println(err)
+	flags := flag.NewFlagSet("go-auth", flag.ContinueOnError)
+	configPath := flags.String("config", ConfigPath, "the config file")
 
-		return
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go-auth [--config file] [command] [args]")
+		fmt.Fprintln(flags.Output(),
+			"commands: serve (default), user, role, service, token, keys, migrate, bootstrap, import, dump, restore")
+		flags.PrintDefaults()
 	}
 
-	if conf.PprofServingURI != "" {
-		log.Println("Starting pprof http")
-
-		go func() {
-			//nolint:gosec // not an exposed to prod server, it's ok.
-			log.Println(http.ListenAndServe(conf.PprofServingURI, server.NewPprofServemux()))
-		}()
+	if err := flags.Parse(os.Args[1:]); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 1
 	}
 
-	jwtService, jwtErr := encrypt.NewJWTService(conf.PrivatePemPath, conf.PublicPemPath, conf.AuthTokenTTL)
-	if jwtErr != nil {
-		log.Println(jwtErr)
-
-		return
-	}
+	programContext, programContextStop := signal.NotifyContext(context.Background(), syscall.SIGINT)
 
-	dbInstance, err := database.Setup(programContext, conf.DBUri, DBMigrationsPath)
-	if err != nil {
-		log.Println(err)
+	defer programContextStop()
 
-		return
+	command, args := "serve", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
 
-	log.Println("Database setup ok")
-
-	cache := cache.NewCache(conf.RateLimitTTL, conf.RateLimitCapacity)
-
-	go cache.AutoEvict(CacheAutoEvictPeriodSeconds * time.Second)
+	// the keys are made before the deployment is configured, without a config they take the paths as flags.
+	err := config.ParseConfig(*configPath, &conf)
+	if err != nil && (command != "keys" || !errors.Is(err, os.ErrNotExist)) {
+		log.Println(err)
 
-	var serv *http.Server
-	{
-		authHandl := handler.NewAuthHandl(dbInstance, jwtService, cache, conf.RateLimitRequests, conf.CookieSessionDomain)
-		manageHandl := handler.NewManageHandl(dbInstance, jwtService, cache, conf.RateLimitRequests)
-		router := server.NewRouter(handler.CommonHandl{}, authHandl, manageHandl,
-			handler.NewIPRateLimitHandl(conf.RateLimitRequests, cache))
-		serv = server.NewServer(conf.ServingURI, router)
+		return 1
 	}
 
-	if conf.EnableTLSServing {
-		go func() {
-			err = serv.ListenAndServeTLS(conf.SslCertfilePath, conf.SslKeyfilePath)
-
-			programContextStop()
-		}()
-	} else {
-		go func() {
-			err = serv.ListenAndServe()
-
-			programContextStop()
-		}()
+	switch command {
+	case "serve":
+		serve(programContext, programContextStop, &conf)
+	case "user":
+		err = runUser(programContext, &conf, args)
+	case "role":
+		err = runRole(programContext, &conf, args)
+	case "service":
+		err = runService(programContext, &conf, args)
+	case "token":
+		err = runToken(programContext, &conf, args)
+	case "keys":
+		err = runKeys(programContext, &conf, args)
+	case "migrate":
+		err = runMigrate(programContext, &conf, args)
+	case "import":
+		err = runImport(programContext, &conf, args)
+	case "dump":
+		err = runDump(programContext, &conf, args)
+	case "restore":
+		err = runRestore(programContext, &conf, args)
+	case "bootstrap":
+		err = runBootstrap(programContext, &conf, args)
+	default:
+		err = fmt.Errorf("%w: %s", errUnknownCommand, command)
 	}
 
-	<-programContext.Done()
-
 	if err != nil {
 		log.Println(err)
-	}
-
-	log.Println("shutting down")
-
-	{
-		shutdownContext, shutdownContextCancel := context.WithTimeout(programContext, time.Second*15)
-		defer shutdownContextCancel()
 
-		err = serv.Shutdown(shutdownContext)
-
-		cache.StopAutoEvict()
-		dbInstance.ClosePool()
+		return 1
 	}
 
-	if err != nil {
-		log.Println(err)
-	}
+	return 0
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eldarbr/go-auth/internal/service/importer"
+)
+
+var (
+	errUsage        = errors.New("bad usage")
+	errImportFailed = errors.New("some rows were not imported")
+)
+
+// runImport imports users in bulk from a csv or jsonl file, "-" is the stdin.
+// The report is printed to the stdout.
+func runImport(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "rows format: csv or jsonl, by the file extension by default")
+	chunkSize := flags.Int("chunk-size", 0, "rows per transaction, 0 - all the rows in one transaction")
+	dryRun := flags.Bool("dry-run", false, "roll back the import, only report what would happen")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go-auth import [flags] <file>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	filename := flags.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+
+	input := os.Stdin
+
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+
+		defer file.Close()
+
+		input = file
+	}
+
+	rows, parseErrors, err := importer.Parse(input, *format)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	report, err := importer.Import(ctx, dbInstance, rows, parseErrors, importer.Options{
+		ChunkSize: *chunkSize,
+		DryRun:    *dryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(report); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if report.Failed > 0 {
+		return errImportFailed
+	}
+
+	return nil
+}
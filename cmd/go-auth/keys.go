@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+var errKeyPairIncomplete = errors.New("only one of the key pair files exists")
+
+// runKeys generates and inspects the token signing keys.
+func runKeys(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "keys", args, map[string]subcommand{
+		"generate": runKeysGenerate,
+		"inspect":  runKeysInspect,
+	})
+}
+
+// runKeysGenerate writes a new key pair, by default to the configured paths. Never overwrites the files.
+func runKeysGenerate(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("keys generate", flag.ContinueOnError)
+	algorithm := flags.String("algorithm", conf.KeyAlgorithm, "the key algorithm, rsa or ecdsa")
+	bits := flags.Int("bits", conf.KeyBits, "the key size, 2048, 3072, 4096 for rsa, 256, 384, 521 for ecdsa, 0 - default")
+	privatePath := flags.String("private", conf.PrivatePemPath, "the private key file")
+	publicPath := flags.String("public", conf.PublicPemPath, "the public key file")
+
+	if err := parseArgs(flags, args, "keys generate [flags]", 0); err != nil {
+		return fmt.Errorf("keys generate: %w", err)
+	}
+
+	if *privatePath == "" || *publicPath == "" {
+		return fmt.Errorf("keys generate: %w: the private and the public key files are required", errUsage)
+	}
+
+	if err := generateKeyFiles(*privatePath, *publicPath, *algorithm, *bits); err != nil {
+		return fmt.Errorf("keys generate: %w", err)
+	}
+
+	return nil
+}
+
+// runKeysInspect prints the algorithm, the fingerprint and the JWK thumbprint of a key.
+// A private key is inspected by its public part.
+func runKeysInspect(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("keys inspect", flag.ContinueOnError)
+	path := flags.String("key", conf.PublicPemPath, "the public or private key file")
+
+	if err := parseArgs(flags, args, "keys inspect [flags]", 0); err != nil {
+		return fmt.Errorf("keys inspect: %w", err)
+	}
+
+	if *path == "" {
+		return fmt.Errorf("keys inspect: %w: the key file is required", errUsage)
+	}
+
+	keyPem, err := os.ReadFile(*path)
+	if err != nil {
+		return fmt.Errorf("keys inspect: %w", err)
+	}
+
+	publicKey, err := encrypt.ParsePublicKeyPEM(keyPem)
+	if err != nil {
+		return fmt.Errorf("keys inspect: %w", err)
+	}
+
+	info, err := encrypt.InspectPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("keys inspect: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(info); err != nil {
+		return fmt.Errorf("keys inspect: %w", err)
+	}
+
+	return nil
+}
+
+// generateKeyFiles writes a new key pair, failing if any of the files exists.
+func generateKeyFiles(privatePath, publicPath, algorithm string, bits int) error {
+	for _, path := range []string{privatePath, publicPath} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", path, os.ErrExist)
+		}
+	}
+
+	privatePem, publicPem, err := encrypt.GenerateKeyPair(algorithm, bits)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller.
+	}
+
+	if err = writeNewFile(privatePath, privatePem, secretFileMode); err != nil {
+		return err
+	}
+
+	return writeNewFile(publicPath, publicPem, publicFileMode)
+}
+
+// ensureKeyFiles generates the key pair on the first start if none exists.
+// Returns true if the keys were generated.
+func ensureKeyFiles(conf *programConf) (bool, error) {
+	_, privateErr := os.Stat(conf.PrivatePemPath)
+	_, publicErr := os.Stat(conf.PublicPemPath)
+
+	privateMissing, publicMissing := errors.Is(privateErr, os.ErrNotExist), errors.Is(publicErr, os.ErrNotExist)
+
+	switch {
+	case privateMissing && publicMissing:
+		return true, generateKeyFiles(conf.PrivatePemPath, conf.PublicPemPath, conf.KeyAlgorithm, conf.KeyBits)
+	case privateMissing || publicMissing:
+		return false, errKeyPairIncomplete
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+// runMigrate applies, reverts and inspects the embedded migrations.
+func runMigrate(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "migrate", args, map[string]subcommand{
+		"up":      runMigrateUp,
+		"down":    runMigrateDown,
+		"goto":    runMigrateGoto,
+		"version": runMigrateVersion,
+		"force":   runMigrateForce,
+	})
+}
+
+// runMigrateUp applies the pending migrations.
+func runMigrateUp(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "apply the next `n` migrations, 0 - all the pending")
+
+	if err := parseArgs(flags, args, "migrate up [flags]", 0); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+
+	if *steps < 0 {
+		flags.Usage()
+
+		return fmt.Errorf("migrate up: %w", errUsage)
+	}
+
+	return withMigrator(conf, "migrate up", func(migrator *database.Migrator) error {
+		return migrator.Up(*steps)
+	})
+}
+
+// runMigrateDown reverts the last migration, or several.
+func runMigrateDown(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "revert the last `n` migrations")
+	all := flags.Bool("all", false, "revert all the migrations, dropping all the data")
+
+	if err := parseArgs(flags, args, "migrate down [flags]", 0); err != nil {
+		return fmt.Errorf("migrate down: %w", err)
+	}
+
+	if *steps <= 0 && !*all {
+		flags.Usage()
+
+		return fmt.Errorf("migrate down: %w", errUsage)
+	}
+
+	if *all {
+		*steps = 0
+	}
+
+	return withMigrator(conf, "migrate down", func(migrator *database.Migrator) error {
+		return migrator.Down(*steps)
+	})
+}
+
+// runMigrateGoto migrates up or down to the version.
+func runMigrateGoto(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("migrate goto", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "migrate goto <version>", 1); err != nil {
+		return fmt.Errorf("migrate goto: %w", err)
+	}
+
+	version, err := strconv.ParseUint(flags.Arg(0), 10, 0)
+	if err != nil || version == 0 {
+		return fmt.Errorf("migrate goto: %w: the version is a positive number", errUsage)
+	}
+
+	return withMigrator(conf, "migrate goto", func(migrator *database.Migrator) error {
+		return migrator.Goto(uint(version))
+	})
+}
+
+// runMigrateVersion prints the current version.
+func runMigrateVersion(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("migrate version", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "migrate version", 0); err != nil {
+		return fmt.Errorf("migrate version: %w", err)
+	}
+
+	return withMigrator(conf, "migrate version", func(*database.Migrator) error { return nil })
+}
+
+// runMigrateForce sets the version without migrating, to recover from a failed migration.
+func runMigrateForce(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("migrate force", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "migrate force <version>", 1); err != nil {
+		return fmt.Errorf("migrate force: %w", err)
+	}
+
+	version, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || version < -1 {
+		return fmt.Errorf("migrate force: %w: the version is a number, -1 - no migrations", errUsage)
+	}
+
+	return withMigrator(conf, "migrate force", func(migrator *database.Migrator) error {
+		return migrator.Force(version)
+	})
+}
+
+// withMigrator runs the migration and prints the version it has left the database at.
+func withMigrator(conf *programConf, command string, migration func(*database.Migrator) error) error {
+	migrator, err := database.NewMigrator(conf.DBUri, storage.Migrations())
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	defer migrator.Close() //nolint:errcheck // the result is printed already.
+
+	if err = migration(migrator); err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	if dirty {
+		fmt.Fprintf(os.Stdout, "version %d, dirty: fix the database and force the version\n", version)
+	} else {
+		fmt.Fprintf(os.Stdout, "version %d\n", version)
+	}
+
+	return nil
+}
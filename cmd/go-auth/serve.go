@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/bootstrap"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/handler"
+	"github.com/eldarbr/go-auth/internal/service/janitor"
+	"github.com/eldarbr/go-auth/internal/service/server"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/cache"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+// serve runs the server until the program context is done.
+func serve(programContext context.Context, programContextStop context.CancelFunc, conf *programConf) {
+	var err error
+
+	if conf.PprofServingURI != "" {
+		log.Println("Starting pprof http")
+
+		go func() {
+			//nolint:gosec // not an exposed to prod server, it's ok.
+			log.Println(http.ListenAndServe(conf.PprofServingURI, server.NewPprofServemux()))
+		}()
+	}
+
+	if !conf.Registration.ValidFormat() {
+		log.Println("the registration policy is not valid")
+
+		return
+	}
+
+	if conf.GenerateKeys {
+		generated, keysErr := ensureKeyFiles(conf)
+		if keysErr != nil {
+			log.Println(keysErr)
+
+			return
+		} else if generated {
+			log.Println("generated a new token signing key pair")
+		}
+	}
+
+	jwtService, jwtErr := encrypt.NewJWTService(conf.PrivatePemPath, conf.PublicPemPath, conf.AuthTokenTTL)
+	if jwtErr != nil {
+		log.Println(jwtErr)
+
+		return
+	}
+
+	if keyInfo, keyErr := encrypt.InspectPublicKey(jwtService.PublicKey()); keyErr == nil {
+		log.Printf("signing the tokens with %s, key %s", keyInfo.SigningMethod, keyInfo.Fingerprint)
+	}
+
+	dbInstance, err := setupDB(programContext, conf)
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	log.Println("Database setup ok")
+
+	if !conf.AutoMigrate {
+		log.Println("the automatic migration is off, migrate with the migrate command")
+	}
+
+	err = checkRegistrationRoles(programContext, dbInstance, conf.Registration)
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	setupToken, err := issueSetupToken(programContext, dbInstance)
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	cache := cache.NewCache(conf.RateLimitTTL, conf.RateLimitCapacity)
+
+	go cache.AutoEvict(CacheAutoEvictPeriodSeconds * time.Second)
+
+	if conf.UserPurgeAfter > 0 {
+		go janitor.Every(programContext, JanitorPeriod, "purge deleted users",
+			janitor.PurgeDeletedUsers(dbInstance, conf.UserPurgeAfter))
+	}
+
+	go janitor.Every(programContext, JanitorPeriod, "purge expired roles", janitor.PurgeExpiredRoles(dbInstance))
+
+	go janitor.Every(programContext, conf.WebhookDeliveryPeriod, "deliver webhooks",
+		webhook.NewDispatcher(dbInstance, conf.WebhookTimeout).Deliver)
+
+	if conf.WebhookRetention > 0 {
+		go janitor.Every(programContext, JanitorPeriod, "purge delivered webhooks",
+			janitor.PurgeDeliveredWebhooks(dbInstance, conf.WebhookRetention))
+	}
+
+	var serv *http.Server
+	{
+		authHandl := handler.NewAuthHandl(dbInstance, jwtService, cache, conf.RateLimitRequests,
+			conf.CookieSessionDomain, conf.TokenAttributes, conf.Registration, setupToken)
+		manageHandl := handler.NewManageHandl(dbInstance, jwtService, cache, conf.RateLimitRequests,
+			conf.TokenAttributes, conf.ImpersonationTTL)
+		router := server.NewRouter(handler.CommonHandl{}, authHandl, manageHandl,
+			handler.NewIPRateLimitHandl(conf.RateLimitRequests, cache))
+		serv = server.NewServer(conf.ServingURI, router)
+	}
+
+	if conf.EnableTLSServing {
+		go func() {
+			err = serv.ListenAndServeTLS(conf.SslCertfilePath, conf.SslKeyfilePath)
+
+			programContextStop()
+		}()
+	} else {
+		go func() {
+			err = serv.ListenAndServe()
+
+			programContextStop()
+		}()
+	}
+
+	<-programContext.Done()
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	log.Println("shutting down")
+
+	{
+		shutdownContext, shutdownContextCancel := context.WithTimeout(programContext, time.Second*15)
+		defer shutdownContextCancel()
+
+		err = serv.Shutdown(shutdownContext)
+
+		cache.StopAutoEvict()
+		dbInstance.ClosePool()
+	}
+
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// issueSetupToken prints a one-time token to set up the first root at /auth/setup, if there is no root.
+// Returns nil if the setup is not needed.
+func issueSetupToken(ctx context.Context, dbInstance *database.Database) (*bootstrap.SetupToken, error) {
+	needsRoot, err := bootstrap.NeedsRoot(ctx, dbInstance.GetPool())
+	if err != nil {
+		return nil, fmt.Errorf("issueSetupToken: %w", err)
+	}
+
+	if !needsRoot {
+		return nil, nil //nolint:nilnil // no token is needed.
+	}
+
+	setupToken, err := bootstrap.NewSetupToken()
+	if err != nil {
+		return nil, fmt.Errorf("issueSetupToken: %w", err)
+	}
+
+	log.Printf("there is no go-auth root, set it up at POST /auth/setup with the one-time token %s", setupToken)
+
+	return setupToken, nil
+}
+
+// checkRegistrationRoles tests if the roles the registration policy grants, in any mode, are defined.
+func checkRegistrationRoles(ctx context.Context, dbInstance *database.Database,
+	policy model.RegistrationPolicy) error {
+	for _, roles := range policy.DefaultRoles {
+		for _, role := range roles {
+			serviceRoles, err := storage.TableServicesRoles.GetByServiceName(ctx, dbInstance.GetPool(),
+				role.ServiceName)
+			if err != nil && !errors.Is(err, database.ErrNoRows) {
+				return fmt.Errorf("checkRegistrationRoles: %w", err)
+			}
+
+			if !slices.ContainsFunc(serviceRoles, func(serviceRole storage.ServiceRole) bool {
+				return serviceRole.Name == role.UserRole
+			}) {
+				return fmt.Errorf("checkRegistrationRoles: registration role %s of %s: %w", role.UserRole,
+					role.ServiceName, storage.ErrUnknownRole)
+			}
+		}
+	}
+
+	return nil
+}
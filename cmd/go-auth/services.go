@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+var errBadServiceName = errors.New("the service name is not valid")
+
+// runService adds and lists the services.
+func runService(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "service", args, map[string]subcommand{
+		"add":  runServiceAdd,
+		"list": runServiceList,
+	})
+}
+
+// runServiceAdd adds a service along with the default roles catalog.
+func runServiceAdd(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("service add", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "service add <name>", 1); err != nil {
+		return fmt.Errorf("service add: %w", err)
+	}
+
+	if !(model.ServiceRequest{Name: flags.Arg(0)}).ValidFormat() {
+		return fmt.Errorf("service add: %w", errBadServiceName)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("service add: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("service add: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableServices.Add(ctx, tx, &storage.Service{Name: flags.Arg(0)})
+	if err == nil {
+		err = storage.TableServicesRoles.AddDefaults(ctx, tx, flags.Arg(0))
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("service add: %w", err)
+	}
+
+	return nil
+}
+
+// runServiceList prints the services' names.
+func runServiceList(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("service list", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "service list", 0); err != nil {
+		return fmt.Errorf("service list: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("service list: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	dbServices, err := storage.TableServices.GetAll(ctx, dbInstance.GetPool())
+	if err != nil {
+		return fmt.Errorf("service list: %w", err)
+	}
+
+	for _, dbService := range dbServices {
+		fmt.Fprintln(os.Stdout, dbService.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/handler"
+)
+
+var errUserNotActive = errors.New("the user is not active")
+
+// runToken issues and decodes the tokens.
+func runToken(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "token", args, map[string]subcommand{
+		"issue":  runTokenIssue,
+		"decode": runTokenDecode,
+	})
+}
+
+// runTokenIssue prints a token of the user, the same as issued on the authentication.
+func runTokenIssue(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", 0, "the token expires no later than after the duration, 0 - the configured ttl")
+
+	if err := parseArgs(flags, args, "token issue [flags] <username>", 1); err != nil {
+		return fmt.Errorf("token issue: %w", err)
+	}
+
+	jwtService, err := encrypt.NewJWTService(conf.PrivatePemPath, conf.PublicPemPath, conf.AuthTokenTTL)
+	if err != nil {
+		return fmt.Errorf("token issue: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("token issue: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	dbUser, err := storage.TableUsers.GetByUsername(ctx, dbInstance.GetPool(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("token issue: %w", err)
+	}
+
+	if dbUser.Status != storage.UserStatusTypeActive {
+		return fmt.Errorf("token issue: %w", errUserNotActive)
+	}
+
+	var notAfter *time.Time
+
+	if *ttl > 0 {
+		expires := time.Now().Add(*ttl)
+		notAfter = &expires
+	}
+
+	token, _, err := handler.IssueUserToken(ctx, dbInstance.GetPool(), jwtService, dbUser, conf.TokenAttributes,
+		notAfter)
+	if err != nil {
+		return fmt.Errorf("token issue: %w", err)
+	}
+
+	fmt.Fprintln(os.Stdout, token)
+
+	return nil
+}
+
+// runTokenDecode verifies the token with the public key and prints its claims.
+func runTokenDecode(_ context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("token decode", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "token decode <token>", 1); err != nil {
+		return fmt.Errorf("token decode: %w", err)
+	}
+
+	jwtService, err := encrypt.NewJWTService(conf.PrivatePemPath, conf.PublicPemPath, conf.AuthTokenTTL)
+	if err != nil {
+		return fmt.Errorf("token decode: %w", err)
+	}
+
+	claims, err := jwtService.ValidateToken(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("token decode: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(claims); err != nil {
+		return fmt.Errorf("token decode: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+var errBadCreds = errors.New("the username or the password is not valid")
+
+// runUser manages the users: create, list, passwd and delete.
+func runUser(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "user", args, map[string]subcommand{
+		"create": runUserCreate,
+		"list":   runUserList,
+		"passwd": runUserPasswd,
+		"delete": runUserDelete,
+	})
+}
+
+// runRole grants and revokes the users' roles.
+func runRole(ctx context.Context, conf *programConf, args []string) error {
+	return runGroup(ctx, conf, "role", args, map[string]subcommand{
+		"grant":  runRoleGrant,
+		"revoke": runRoleRevoke,
+	})
+}
+
+// runUserCreate creates a user, the password is the first line of the stdin.
+func runUserCreate(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "user create <username> < password", 1); err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	creds := model.UserCreds{UserUsernme: model.UserUsernme{Username: flags.Arg(0)}, Password: password}
+	if !creds.ValidFormat() {
+		return fmt.Errorf("user create: %w", errBadCreds)
+	}
+
+	hashedPassword, err := encrypt.PasswordEncrypt(creds.Password)
+	if err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	dbUser, err := storage.TableUsers.Add(ctx, tx, &storage.AddUser{
+		Username: creds.Username,
+		Password: hashedPassword,
+	})
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserCreated, model.PrepareWebhookUser(dbUser))
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("user create: %w", err)
+	}
+
+	fmt.Fprintln(os.Stdout, dbUser.ID)
+
+	return nil
+}
+
+// runUserList prints the users ordered by the username.
+func runUserList(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("user list", flag.ContinueOnError)
+	serviceName := flags.String("service", "", "list the users having a role in the service")
+	userRole := flags.String("role", "", "list the users having the role")
+	search := flags.String("search", "", "list the users whose username contains the string")
+	deleted := flags.Bool("deleted", false, "list the soft deleted users instead")
+	limit := flags.Int("limit", 1000, "the users to list at most")
+
+	if err := parseArgs(flags, args, "user list [flags]", 0); err != nil {
+		return fmt.Errorf("user list: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("user list: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	filter := storage.UserListFilter{ //nolint:exhaustruct // no cursor nor dates.
+		ServiceName: *serviceName,
+		UserRole:    *userRole,
+		Search:      *search,
+		SortBy:      storage.UserListSortUsername,
+		Limit:       *limit,
+		Deleted:     *deleted,
+	}
+
+	dbUsers, err := storage.TableUsers.List(ctx, dbInstance.GetPool(), &filter)
+	if err != nil {
+		return fmt.Errorf("user list: %w", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tUSERNAME\tSTATUS\tCREATED")
+
+	for _, dbUser := range dbUsers {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", dbUser.ID, dbUser.Username, dbUser.Status,
+			dbUser.CreatedTS.UTC().Format(time.RFC3339))
+	}
+
+	return writer.Flush() //nolint:wrapcheck // the stdout.
+}
+
+// runUserPasswd sets the user's password to the first line of the stdin.
+func runUserPasswd(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("user passwd", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "user passwd <username> < password", 1); err != nil {
+		return fmt.Errorf("user passwd: %w", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("user passwd: %w", err)
+	}
+
+	creds := model.UserCreds{UserUsernme: model.UserUsernme{Username: flags.Arg(0)}, Password: password}
+	if !creds.ValidFormat() {
+		return fmt.Errorf("user passwd: %w", errBadCreds)
+	}
+
+	hashedPassword, err := encrypt.PasswordEncrypt(creds.Password)
+	if err != nil {
+		return fmt.Errorf("user passwd: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("user passwd: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	err = storage.TableUsers.UpdateByUsername(ctx, dbInstance.GetPool(), &storage.AddUser{
+		Username: creds.Username,
+		Password: hashedPassword,
+	}, creds.Username)
+	if err != nil {
+		return fmt.Errorf("user passwd: %w", err)
+	}
+
+	return nil
+}
+
+// runUserDelete soft deletes the user, or removes it for good with the purge flag.
+func runUserDelete(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("user delete", flag.ContinueOnError)
+	purge := flags.Bool("purge", false, "remove the user for good")
+
+	if err := parseArgs(flags, args, "user delete [flags] <username>", 1); err != nil {
+		return fmt.Errorf("user delete: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("user delete: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("user delete: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	// a soft deleted user is not found, its deletion has been published already.
+	dbUser, err := storage.TableUsers.GetByUsername(ctx, tx, flags.Arg(0))
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserDeleted, model.PrepareWebhookUser(dbUser))
+	}
+
+	switch {
+	case *purge && (err == nil || errors.Is(err, database.ErrNoRows)):
+		err = storage.TableUsers.DeleteByUsername(ctx, tx, flags.Arg(0))
+	case err == nil:
+		err = storage.TableUsers.SoftDeleteByID(ctx, tx, dbUser.ID)
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("user delete: %w", err)
+	}
+
+	return nil
+}
+
+// runRoleGrant grants the user a role in the service, optionally until a moment.
+func runRoleGrant(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("role grant", flag.ContinueOnError)
+	expiresIn := flags.Duration("expires-in", 0, "the role expires after the duration, 0 - never")
+
+	if err := parseArgs(flags, args, "role grant [flags] <username> <service> <role>", 3); err != nil {
+		return fmt.Errorf("role grant: %w", err)
+	}
+
+	var expiresAt *time.Time
+
+	if *expiresIn > 0 {
+		expires := time.Now().Add(*expiresIn)
+		expiresAt = &expires
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("role grant: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("role grant: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	var dbRole *storage.UserRole
+
+	dbUser, err := storage.TableUsers.GetByUsername(ctx, tx, flags.Arg(0))
+	if err == nil {
+		dbRole, err = storage.TableUsersRoles.Add(ctx, tx, &storage.AddUserRole{
+			ValidFrom:   nil,
+			ExpiresAt:   expiresAt,
+			UserID:      dbUser.ID,
+			UserRole:    flags.Arg(2),
+			ServiceName: flags.Arg(1),
+		})
+	}
+
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("role grant: %w", err)
+	}
+
+	return nil
+}
+
+// runRoleRevoke revokes the user's role in the service.
+func runRoleRevoke(ctx context.Context, conf *programConf, args []string) error {
+	flags := flag.NewFlagSet("role revoke", flag.ContinueOnError)
+
+	if err := parseArgs(flags, args, "role revoke <username> <service>", 2); err != nil {
+		return fmt.Errorf("role revoke: %w", err)
+	}
+
+	dbInstance, err := setupDB(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("role revoke: %w", err)
+	}
+
+	defer dbInstance.ClosePool()
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("role revoke: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	var dbRole *storage.UserRole
+
+	dbUser, err := storage.TableUsers.GetByUsername(ctx, tx, flags.Arg(0))
+	if err == nil {
+		dbRole, err = storage.TableUsersRoles.GetByUserIDAndServiceName(ctx, tx, dbUser.ID, flags.Arg(1))
+	}
+
+	if err == nil {
+		err = storage.TableUsersRoles.DeleteByID(ctx, tx, dbRole.ID)
+	}
+
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserRoleRevoked, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("role revoke: %w", err)
+	}
+
+	return nil
+}
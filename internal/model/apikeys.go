@@ -0,0 +1,111 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+const (
+	CapAPIKeyNameMaxlen = 40
+	CapAPIKeyRolesMax   = 50
+)
+
+// APIKeyRequest describes a new API key, scoped to the roles of its owner.
+type APIKeyRequest struct {
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+	Name      string            `json:"name"`
+	Roles     []UserRoleRequest `json:"roles"`
+}
+
+type APIKeyInfo struct {
+	CreatedTS  time.Time               `json:"createdTs"`
+	ExpiresAt  *time.Time              `json:"expiresAt"`
+	LastUsedTS *time.Time              `json:"lastUsedTs"`
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	Hint       string                  `json:"hint"`
+	Roles      []encrypt.ClaimUserRole `json:"roles"`
+}
+
+// APIKeyCreateResponse holds the key itself, it is shown only once.
+type APIKeyCreateResponse struct {
+	Key string `json:"key"`
+	APIKeyInfo
+}
+
+type APIKeysResponse struct {
+	UserID string       `json:"userId"`
+	Keys   []APIKeyInfo `json:"keys"`
+}
+
+// ValidFormat tests if the name is like a group name, the roles are valid and unique,
+// and the key expires in the future.
+func (req APIKeyRequest) ValidFormat() bool {
+	if len(req.Name) > CapAPIKeyNameMaxlen || !regexpValidGroupName.MatchString(req.Name) {
+		return false
+	}
+
+	if len(req.Roles) == 0 || len(req.Roles) > CapAPIKeyRolesMax {
+		return false
+	}
+
+	for i, role := range req.Roles {
+		if !role.ValidFormat() || slices.Contains(req.Roles[:i], role) {
+			return false
+		}
+	}
+
+	return req.ExpiresAt == nil || req.ExpiresAt.After(time.Now())
+}
+
+// PrepareAPIKeyRoles converts the requested roles to the database model ones.
+func PrepareAPIKeyRoles(roles []UserRoleRequest) []storage.APIKeyRole {
+	keyRoles := make([]storage.APIKeyRole, 0, len(roles))
+
+	for _, role := range roles {
+		keyRoles = append(keyRoles, storage.APIKeyRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+	}
+
+	return keyRoles
+}
+
+// APIKeyScope returns the effective roles of the key's owner the key is scoped to.
+// The roles the owner has lost since the key was made are not in the scope.
+func APIKeyScope(effective []storage.UserRole, keyRoles []storage.APIKeyRole) []storage.UserRole {
+	scope := make([]storage.UserRole, 0, len(keyRoles))
+
+	for _, role := range effective {
+		if slices.Contains(keyRoles, storage.APIKeyRole{ServiceName: role.ServiceName, UserRole: role.UserRole}) {
+			scope = append(scope, role)
+		}
+	}
+
+	return scope
+}
+
+// PrepareAPIKeys converts database model API keys to the response entries.
+func PrepareAPIKeys(dbKeys []storage.APIKey) []APIKeyInfo {
+	keys := make([]APIKeyInfo, 0, len(dbKeys))
+
+	for _, dbEntry := range dbKeys {
+		roles := make([]encrypt.ClaimUserRole, 0, len(dbEntry.Roles))
+		for _, role := range dbEntry.Roles {
+			roles = append(roles, encrypt.ClaimUserRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+		}
+
+		keys = append(keys, APIKeyInfo{
+			CreatedTS:  dbEntry.CreatedTS,
+			ExpiresAt:  dbEntry.ExpiresAt,
+			LastUsedTS: dbEntry.LastUsedTS,
+			ID:         dbEntry.ID,
+			Name:       dbEntry.Name,
+			Hint:       dbEntry.Hint,
+			Roles:      roles,
+		})
+	}
+
+	return keys
+}
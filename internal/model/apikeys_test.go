@@ -0,0 +1,64 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	role := model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeUser}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	roles := []model.UserRoleRequest{role}
+
+	assert.True(t, model.APIKeyRequest{ExpiresAt: nil, Name: "ci", Roles: roles}.ValidFormat())
+	assert.True(t, model.APIKeyRequest{ExpiresAt: &future, Name: "ci", Roles: roles}.ValidFormat())
+	assert.False(t, model.APIKeyRequest{ExpiresAt: &past, Name: "ci", Roles: roles}.ValidFormat())
+	assert.False(t, model.APIKeyRequest{ExpiresAt: nil, Name: "", Roles: roles}.ValidFormat())
+	assert.False(t, model.APIKeyRequest{ExpiresAt: nil, Name: "ci", Roles: nil}.ValidFormat())
+	assert.False(t, model.APIKeyRequest{ExpiresAt: nil, Name: "ci", Roles: append(roles, role)}.ValidFormat())
+}
+
+func TestAPIKeyScope(t *testing.T) {
+	t.Parallel()
+
+	effective := []storage.UserRole{
+		{AddUserRole: storage.AddUserRole{ServiceName: "service1", UserRole: storage.UserRoleTypeAdmin}}, //nolint:exhaustruct,lll // other fields are not used.
+		{AddUserRole: storage.AddUserRole{ServiceName: "service2", UserRole: storage.UserRoleTypeUser}},  //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	// the owner has lost the service3 role.
+	scope := model.APIKeyScope(effective, []storage.APIKeyRole{
+		{ServiceName: "service2", UserRole: storage.UserRoleTypeUser},
+		{ServiceName: "service3", UserRole: storage.UserRoleTypeUser},
+	})
+
+	require.Len(t, scope, 1)
+	assert.Equal(t, "service2", scope[0].ServiceName)
+	assert.Empty(t, model.APIKeyScope(effective, nil))
+}
+
+func TestPrepareAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := model.PrepareAPIKeys([]storage.APIKey{{ //nolint:exhaustruct // other fields are not used.
+		ID:      "123",
+		Name:    "ci",
+		Hint:    "gak_abcdef",
+		KeyHash: "secret",
+		Roles:   []storage.APIKeyRole{{ServiceName: "service", UserRole: storage.UserRoleTypeUser}},
+	}})
+
+	require.Len(t, keys, 1)
+	assert.Equal(t, "gak_abcdef", keys[0].Hint)
+	assert.Equal(t, []encrypt.ClaimUserRole{{ServiceName: "service", UserRole: storage.UserRoleTypeUser}}, keys[0].Roles)
+}
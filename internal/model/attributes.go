@@ -0,0 +1,132 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+type AttributeDefinitionRequest struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	MaxLength int    `json:"maxLength,omitempty"`
+}
+
+type AttributeDefinitionsResponse struct {
+	Attributes []AttributeDefinitionRequest `json:"attributes"`
+}
+
+type AttributeDeleteResponse struct {
+	Name          string `json:"name"`
+	UsersAffected int64  `json:"usersAffected"`
+}
+
+type UserAttributesResponse struct {
+	Attributes map[string]any `json:"attributes"`
+	UserID     string         `json:"userId"`
+}
+
+const (
+	CapAttributeNameMaxlen   = 40
+	CapAttributeStringMaxlen = 1000
+)
+
+var (
+	ErrUnknownAttribute  = errors.New("unknown attribute")
+	ErrBadAttributeValue = errors.New("bad attribute value")
+)
+
+var regexpValidAttributeName = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9_]*$")
+
+// ValidFormat tests if the definition has a valid name and a known type.
+// The max length is only allowed for the strings and can't exceed CapAttributeStringMaxlen.
+func (req AttributeDefinitionRequest) ValidFormat() bool {
+	if len(req.Name) > CapAttributeNameMaxlen || !regexpValidAttributeName.MatchString(req.Name) {
+		return false
+	}
+
+	switch req.Type {
+	case storage.AttributeValueTypeString:
+		return req.MaxLength >= 0 && req.MaxLength <= CapAttributeStringMaxlen
+	case storage.AttributeValueTypeNumber, storage.AttributeValueTypeBoolean:
+		return req.MaxLength == 0
+	default:
+		return false
+	}
+}
+
+// Definition converts the request to the database model. A string is limited
+// to CapAttributeStringMaxlen if no max length is requested.
+func (req AttributeDefinitionRequest) Definition() storage.AttributeDefinition {
+	definition := storage.AttributeDefinition{
+		Name:      req.Name,
+		ValueType: req.Type,
+		MaxLength: req.MaxLength,
+	}
+
+	if definition.ValueType == storage.AttributeValueTypeString && definition.MaxLength == 0 {
+		definition.MaxLength = CapAttributeStringMaxlen
+	}
+
+	return definition
+}
+
+// ValidateAttributes tests the decoded json attributes against the definitions.
+// A nil value is valid for any defined attribute, it stands for the removal.
+func ValidateAttributes(attributes map[string]any, definitions []storage.AttributeDefinition) error {
+	definitionsMap := make(map[string]storage.AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		definitionsMap[definition.Name] = definition
+	}
+
+	for name, value := range attributes {
+		definition, ok := definitionsMap[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownAttribute, name)
+		}
+
+		if value != nil && !validateAttributeValue(value, definition) {
+			return fmt.Errorf("%w: %s", ErrBadAttributeValue, name)
+		}
+	}
+
+	return nil
+}
+
+func validateAttributeValue(value any, definition storage.AttributeDefinition) bool {
+	switch typed := value.(type) {
+	case string:
+		return definition.ValueType == storage.AttributeValueTypeString &&
+			utf8.RuneCountInString(typed) <= definition.MaxLength
+	case float64:
+		return definition.ValueType == storage.AttributeValueTypeNumber
+	case bool:
+		return definition.ValueType == storage.AttributeValueTypeBoolean
+	default:
+		return false
+	}
+}
+
+// PrepareTokenAttributes picks the named attributes to be included in the token.
+// Returns nil if the user has none of them.
+func PrepareTokenAttributes(attributes map[string]any, names []string) map[string]any {
+	var picked map[string]any
+
+	for _, name := range names {
+		value, ok := attributes[name]
+		if !ok {
+			continue
+		}
+
+		if picked == nil {
+			picked = make(map[string]any, len(names))
+		}
+
+		picked[name] = value
+	}
+
+	return picked
+}
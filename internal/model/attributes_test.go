@@ -0,0 +1,83 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeDefinitionRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.AttributeDefinitionRequest{Name: "displayName", Type: "string"}.ValidFormat())
+	assert.True(t, model.AttributeDefinitionRequest{Name: "locale", Type: "string", MaxLength: 10}.ValidFormat())
+	assert.True(t, model.AttributeDefinitionRequest{Name: "floor_no", Type: "number"}.ValidFormat())
+	assert.True(t, model.AttributeDefinitionRequest{Name: "external", Type: "boolean"}.ValidFormat())
+
+	assert.False(t, model.AttributeDefinitionRequest{Name: "", Type: "string"}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "1name", Type: "string"}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "display name", Type: "string"}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: strings.Repeat("a", 41), Type: "string"}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "name", Type: "object"}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "name", Type: "string", MaxLength: -1}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "name", Type: "string", MaxLength: 1001}.ValidFormat())
+	assert.False(t, model.AttributeDefinitionRequest{Name: "name", Type: "number", MaxLength: 10}.ValidFormat())
+}
+
+func TestAttributeDefinitionRequestDefinition(t *testing.T) {
+	t.Parallel()
+
+	definition := model.AttributeDefinitionRequest{Name: "name", Type: "string"}.Definition()
+	assert.Equal(t, model.CapAttributeStringMaxlen, definition.MaxLength)
+
+	definition = model.AttributeDefinitionRequest{Name: "name", Type: "string", MaxLength: 5}.Definition()
+	assert.Equal(t, 5, definition.MaxLength)
+
+	definition = model.AttributeDefinitionRequest{Name: "name", Type: "number"}.Definition()
+	assert.Equal(t, 0, definition.MaxLength)
+}
+
+func TestValidateAttributes(t *testing.T) {
+	t.Parallel()
+
+	definitions := []storage.AttributeDefinition{
+		{Name: "displayName", ValueType: storage.AttributeValueTypeString, MaxLength: 5},
+		{Name: "floor", ValueType: storage.AttributeValueTypeNumber},
+		{Name: "external", ValueType: storage.AttributeValueTypeBoolean},
+	}
+
+	require.NoError(t, model.ValidateAttributes(map[string]any{}, definitions))
+	require.NoError(t, model.ValidateAttributes(map[string]any{
+		"displayName": "Алиса",
+		"floor":       float64(3),
+		"external":    true,
+	}, definitions))
+	require.NoError(t, model.ValidateAttributes(map[string]any{"displayName": nil}, definitions))
+
+	require.ErrorIs(t, model.ValidateAttributes(map[string]any{"department": "it"}, definitions),
+		model.ErrUnknownAttribute)
+	require.ErrorIs(t, model.ValidateAttributes(map[string]any{"displayName": "too long"}, definitions),
+		model.ErrBadAttributeValue)
+	require.ErrorIs(t, model.ValidateAttributes(map[string]any{"floor": "3"}, definitions),
+		model.ErrBadAttributeValue)
+	require.ErrorIs(t, model.ValidateAttributes(map[string]any{"external": float64(1)}, definitions),
+		model.ErrBadAttributeValue)
+	require.ErrorIs(t, model.ValidateAttributes(map[string]any{"displayName": []any{"a"}}, definitions),
+		model.ErrBadAttributeValue)
+}
+
+func TestPrepareTokenAttributes(t *testing.T) {
+	t.Parallel()
+
+	attributes := map[string]any{"displayName": "name", "locale": "en", "floor": float64(3)}
+
+	assert.Nil(t, model.PrepareTokenAttributes(attributes, nil))
+	assert.Nil(t, model.PrepareTokenAttributes(attributes, []string{"department"}))
+	assert.Nil(t, model.PrepareTokenAttributes(nil, []string{"locale"}))
+	assert.Equal(t, map[string]any{"displayName": "name", "locale": "en"},
+		model.PrepareTokenAttributes(attributes, []string{"displayName", "locale", "department"}))
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+type AuditEventInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	ActorID   *string   `json:"actorId,omitempty"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Outcome   string    `json:"outcome"`
+	Details   string    `json:"details"`
+	ID        int64     `json:"id"`
+}
+
+type AuditLogResponse struct {
+	NextCursor string           `json:"nextCursor,omitempty"`
+	Events     []AuditEventInfo `json:"events"`
+}
+
+const (
+	CapAuditLogDefaultLimit = 50
+	CapAuditLogMaxLimit     = 500
+)
+
+// ParseAuditLogQuery converts the query parameters of the audit log request to the storage filter.
+// The cursor is the ID of the last event of the previous page.
+func ParseAuditLogQuery(query url.Values) (*storage.AuditEventFilter, error) {
+	filter := storage.AuditEventFilter{ //nolint:exhaustruct // the rest is filled below.
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+		IP:     query.Get("ip"),
+		Limit:  CapAuditLogDefaultLimit,
+	}
+
+	switch outcome := query.Get("outcome"); outcome {
+	case "", storage.AuditOutcomeSuccess, storage.AuditOutcomeFailure, storage.AuditOutcomeDenied:
+		filter.Outcome = outcome
+	default:
+		return nil, ErrBadListQuery
+	}
+
+	var err error
+
+	if filter.CreatedAfter, err = parseOptionalTime(query.Get("createdAfter")); err != nil {
+		return nil, err
+	}
+
+	if filter.CreatedBefore, err = parseOptionalTime(query.Get("createdBefore")); err != nil {
+		return nil, err
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 || filter.Limit > CapAuditLogMaxLimit {
+			return nil, ErrBadListQuery
+		}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || beforeID < 1 {
+			return nil, ErrBadListQuery
+		}
+
+		filter.BeforeID = &beforeID
+	}
+
+	return &filter, nil
+}
+
+// PrepareAuditLog converts a page of the database model events to the response.
+// The page is expected to be fetched with a limit one bigger than the filter's one,
+// the extra event only signals that there is a next page.
+func PrepareAuditLog(filter *storage.AuditEventFilter, dbEvents []storage.AuditEvent) AuditLogResponse {
+	response := AuditLogResponse{
+		Events: make([]AuditEventInfo, 0, len(dbEvents)),
+	}
+
+	if len(dbEvents) > filter.Limit {
+		dbEvents = dbEvents[:filter.Limit]
+		response.NextCursor = strconv.FormatInt(dbEvents[len(dbEvents)-1].ID, 10)
+	}
+
+	for _, dbEvent := range dbEvents {
+		response.Events = append(response.Events, AuditEventInfo{
+			CreatedTS: dbEvent.CreatedTS,
+			ActorID:   dbEvent.ActorID,
+			Actor:     dbEvent.Actor,
+			Action:    dbEvent.Action,
+			Target:    dbEvent.Target,
+			IP:        dbEvent.IP,
+			UserAgent: dbEvent.UserAgent,
+			Outcome:   dbEvent.Outcome,
+			Details:   dbEvent.Details,
+			ID:        dbEvent.ID,
+		})
+	}
+
+	return response
+}
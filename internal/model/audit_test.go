@@ -0,0 +1,70 @@
+package model_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuditLogQuery(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseAuditLogQuery(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, model.CapAuditLogDefaultLimit, filter.Limit)
+	assert.Nil(t, filter.BeforeID)
+
+	filter, err = model.ParseAuditLogQuery(url.Values{
+		"actor":        {"root"},
+		"action":       {"POST /manage/users"},
+		"outcome":      {storage.AuditOutcomeDenied},
+		"createdAfter": {"2024-01-02T03:04:05Z"},
+		"limit":        {"10"},
+		"cursor":       {"42"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "root", filter.Actor)
+	assert.Equal(t, "POST /manage/users", filter.Action)
+	assert.Equal(t, storage.AuditOutcomeDenied, filter.Outcome)
+	assert.NotNil(t, filter.CreatedAfter)
+	assert.Equal(t, 10, filter.Limit)
+	require.NotNil(t, filter.BeforeID)
+	assert.Equal(t, int64(42), *filter.BeforeID)
+
+	for _, query := range []url.Values{
+		{"outcome": {"unknown"}},
+		{"limit": {"0"}},
+		{"limit": {"501"}},
+		{"cursor": {"-1"}},
+		{"cursor": {"abc"}},
+		{"createdBefore": {"yesterday"}},
+	} {
+		_, err = model.ParseAuditLogQuery(query)
+		require.ErrorIs(t, err, model.ErrBadListQuery, query)
+	}
+}
+
+func TestPrepareAuditLog(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseAuditLogQuery(url.Values{"limit": {"2"}})
+	require.NoError(t, err)
+
+	events := []storage.AuditEvent{
+		{ID: 3, Action: "action3"}, //nolint:exhaustruct // other fields are not used.
+		{ID: 2, Action: "action2"}, //nolint:exhaustruct // other fields are not used.
+		{ID: 1, Action: "action1"}, //nolint:exhaustruct // other fields are not used.
+	}
+
+	response := model.PrepareAuditLog(filter, events)
+	require.Len(t, response.Events, 2)
+	assert.Equal(t, "2", response.NextCursor)
+
+	response = model.PrepareAuditLog(filter, events[2:])
+	require.Len(t, response.Events, 1)
+	assert.Empty(t, response.NextCursor)
+}
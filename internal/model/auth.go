@@ -2,6 +2,7 @@ package model
 
 import (
 	"regexp"
+	"time"
 
 	"github.com/eldarbr/go-auth/internal/service/encrypt"
 )
@@ -20,10 +21,33 @@ type UserCreds struct {
 	Password string `json:"password"`
 }
 
+// SetupRequest holds the one-time setup token and the credentials of the first root.
+type SetupRequest struct {
+	Token string `json:"token"`
+	UserCreds
+}
+
 type UserTokenResponse struct {
 	AuthResponse
 }
 
+// ImpersonationResponse is a short-lived token of the user, issued to the requester.
+type ImpersonationResponse struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	AuthResponse
+}
+
+// MeResponse is the current user's info, read from the database rather than the token.
+type MeResponse struct {
+	CreatedTS   time.Time           `json:"createdTs"`
+	LastLoginTS *time.Time          `json:"lastLoginTs"`
+	Attributes  map[string]any      `json:"attributes"`
+	Permissions map[string][]string `json:"permissions"`
+	UserInfoResponse
+}
+
 const (
 	CapUserCredsUsernameMinlen = 4
 	CapUserCredsUsernameMaxlen = 20
@@ -36,17 +60,26 @@ const (
 // 1) which lengths are not less than MINlen and are not greater than MAXlen;
 // 2) which consist of printable ascii character.
 // 3) username only consists of digits or letters.
+func (req SetupRequest) ValidFormat() bool {
+	return req.Token != "" && req.UserCreds.ValidFormat()
+}
+
 func (creds UserCreds) ValidFormat() bool {
-	valid := len(creds.Username) >= CapUserCredsUsernameMinlen &&
-		len(creds.Username) <= CapUserCredsUsernameMaxlen &&
+	valid := creds.UserUsernme.ValidFormat() &&
 		len(creds.Password) >= CapUserCredsPasswordMinlen &&
 		len(creds.Password) <= CapUserCredsPasswordMaxlen &&
-		validateUsername(creds.Username) &&
 		encrypt.IsPrintableASCII(creds.Password)
 
 	return valid
 }
 
+// ValidFormat tests if the username is valid, see UserCreds.ValidFormat.
+func (username UserUsernme) ValidFormat() bool {
+	return len(username.Username) >= CapUserCredsUsernameMinlen &&
+		len(username.Username) <= CapUserCredsUsernameMaxlen &&
+		validateUsername(username.Username)
+}
+
 var regexpValidUsername = regexp.MustCompile("^[0-9A-z]+$")
 
 func validateUsername(username string) bool {
@@ -82,3 +82,12 @@ func TestCredsValidationValid(t *testing.T) {
 
 	assert.True(t, creds.ValidFormat())
 }
+
+func TestSetupRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	creds := model.UserCreds{UserUsernme: model.UserUsernme{Username: "username"}, Password: "password"}
+
+	assert.True(t, model.SetupRequest{Token: "token", UserCreds: creds}.ValidFormat())
+	assert.False(t, model.SetupRequest{Token: "", UserCreds: creds}.ValidFormat())
+}
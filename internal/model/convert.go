@@ -23,3 +23,158 @@ func PrepareClaims(dbRoles []storage.UserRole) []encrypt.ClaimUserRole {
 
 	return claimGroups
 }
+
+// PrepareUserRoles converts database model user roles to the detailed response entries.
+func PrepareUserRoles(dbRoles []storage.UserRole) []UserRoleInfo {
+	roles := make([]UserRoleInfo, 0, len(dbRoles))
+
+	for _, dbEntry := range dbRoles {
+		roles = append(roles, UserRoleInfo{
+			ID:        dbEntry.ID,
+			CreatedTS: dbEntry.CreatedTS,
+			RoleValidity: RoleValidity{
+				ValidFrom: dbEntry.ValidFrom,
+				ExpiresAt: dbEntry.ExpiresAt,
+			},
+			UserRoleRequest: UserRoleRequest{
+				ServiceName: dbEntry.ServiceName,
+				UserRole:    dbEntry.UserRole,
+			},
+		})
+	}
+
+	return roles
+}
+
+// PrepareServices converts database model services to the response entries.
+func PrepareServices(dbServices []storage.Service) []ServiceRequest {
+	services := make([]ServiceRequest, 0, len(dbServices))
+
+	for _, dbEntry := range dbServices {
+		services = append(services, ServiceRequest{Name: dbEntry.Name})
+	}
+
+	return services
+}
+
+// PrepareServiceRoles converts database model service roles to the response entries.
+func PrepareServiceRoles(dbRoles []storage.ServiceRole) []ServiceRoleInfo {
+	roles := make([]ServiceRoleInfo, 0, len(dbRoles))
+
+	for _, dbEntry := range dbRoles {
+		roles = append(roles, ServiceRoleInfo{
+			CreatedTS: dbEntry.CreatedTS,
+			ServiceRoleRequest: ServiceRoleRequest{
+				Name:        dbEntry.Name,
+				Description: dbEntry.Description,
+			},
+		})
+	}
+
+	return roles
+}
+
+// PrepareServiceMembers converts database model service members to the response entries.
+func PrepareServiceMembers(dbMembers []storage.ServiceMember) []ServiceMemberInfo {
+	members := make([]ServiceMemberInfo, 0, len(dbMembers))
+
+	for _, dbEntry := range dbMembers {
+		members = append(members, ServiceMemberInfo{
+			UserID:       dbEntry.UserID,
+			Username:     dbEntry.Username,
+			UserRoleInfo: PrepareUserRoles([]storage.UserRole{dbEntry.UserRole})[0],
+		})
+	}
+
+	return members
+}
+
+// PrepareUserStatus converts database model user to the status response.
+func PrepareUserStatus(dbUser *storage.User) UserStatusResponse {
+	return UserStatusResponse{
+		UserID:          dbUser.ID,
+		StatusChangedTS: dbUser.StatusChangedTS,
+		UserStatusRequest: UserStatusRequest{
+			Status: dbUser.Status,
+			Reason: dbUser.StatusReason,
+		},
+	}
+}
+
+// PrepareMe converts database model user, their roles and permissions to the current user's info.
+func PrepareMe(dbUser *storage.User, dbRoles []storage.UserRole, dbPermissions []storage.RolePermission) MeResponse {
+	return MeResponse{
+		CreatedTS:   dbUser.CreatedTS,
+		LastLoginTS: dbUser.LastLoginTS,
+		Attributes:  dbUser.Attributes,
+		Permissions: PreparePermissionClaims(dbPermissions),
+		UserInfoResponse: UserInfoResponse{
+			UserID:   dbUser.ID,
+			Username: dbUser.Username,
+			Status:   dbUser.Status,
+			Roles:    PrepareClaims(dbRoles),
+		},
+	}
+}
+
+// PrepareAttributeDefinitions converts database model attribute definitions to the model ones.
+func PrepareAttributeDefinitions(dbDefinitions []storage.AttributeDefinition) []AttributeDefinitionRequest {
+	definitions := make([]AttributeDefinitionRequest, 0, len(dbDefinitions))
+
+	for _, dbEntry := range dbDefinitions {
+		definitions = append(definitions, AttributeDefinitionRequest{
+			Name:      dbEntry.Name,
+			Type:      dbEntry.ValueType,
+			MaxLength: dbEntry.MaxLength,
+		})
+	}
+
+	return definitions
+}
+
+// PrepareGroups converts database model groups to the model ones.
+func PrepareGroups(dbGroups []storage.Group) []GroupInfo {
+	groups := make([]GroupInfo, 0, len(dbGroups))
+
+	for _, dbEntry := range dbGroups {
+		groups = append(groups, GroupInfo{
+			CreatedTS:    dbEntry.CreatedTS,
+			GroupRequest: GroupRequest{Name: dbEntry.Name},
+		})
+	}
+
+	return groups
+}
+
+// PrepareGroupRoles converts database model group roles to the model ones.
+func PrepareGroupRoles(dbRoles []storage.GroupRole) []UserRoleInfo {
+	roles := make([]UserRoleInfo, 0, len(dbRoles))
+
+	for _, dbEntry := range dbRoles {
+		roles = append(roles, UserRoleInfo{ //nolint:exhaustruct // the groups' roles are not time-bound.
+			CreatedTS: dbEntry.CreatedTS,
+			UserRoleRequest: UserRoleRequest{
+				ServiceName: dbEntry.ServiceName,
+				UserRole:    dbEntry.UserRole,
+			},
+			ID: dbEntry.ID,
+		})
+	}
+
+	return roles
+}
+
+// PrepareGroupMembers converts database model group members to the model ones.
+func PrepareGroupMembers(dbMembers []storage.GroupMember) []GroupMemberInfo {
+	members := make([]GroupMemberInfo, 0, len(dbMembers))
+
+	for _, dbEntry := range dbMembers {
+		members = append(members, GroupMemberInfo{
+			CreatedTS:          dbEntry.CreatedTS,
+			Username:           dbEntry.Username,
+			GroupMemberRequest: GroupMemberRequest{UserID: dbEntry.UserID},
+		})
+	}
+
+	return members
+}
@@ -3,6 +3,7 @@ package model_test
 import (
 	"flag"
 	"testing"
+	"time"
 
 	"github.com/eldarbr/go-auth/internal/model"
 	"github.com/eldarbr/go-auth/internal/provider/storage"
@@ -58,3 +59,34 @@ func TestPrepareClaimsManyRoles(t *testing.T) {
 	assert.Equal(t, storage.UserRoleTypeRoot, converted[2].UserRole)
 	assert.Equal(t, storage.UserRoleTypeUser, converted[3].UserRole)
 }
+
+func TestPrepareMe(t *testing.T) {
+	t.Parallel()
+
+	lastLogin := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dbUser := storage.User{ //nolint:exhaustruct // other fields are not used.
+		CreatedTS:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		LastLoginTS: &lastLogin,
+		AddUser:     storage.AddUser{Username: "username", Password: "hash"},
+		ID:          "123",
+		Status:      storage.UserStatusTypeActive,
+	}
+	dbRoles := []storage.UserRole{
+		{AddUserRole: storage.AddUserRole{UserID: "123", ServiceName: "service1", UserRole: storage.UserRoleTypeUser}}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	dbPermissions := []storage.RolePermission{
+		{ServiceName: "service1", UserRole: storage.UserRoleTypeUser, Permission: "invoices:read"}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	converted := model.PrepareMe(&dbUser, dbRoles, dbPermissions)
+
+	assert.Equal(t, "123", converted.UserID)
+	assert.Equal(t, "username", converted.Username)
+	assert.Equal(t, storage.UserStatusTypeActive, converted.Status)
+	assert.Equal(t, dbUser.CreatedTS, converted.CreatedTS)
+	assert.Equal(t, &lastLogin, converted.LastLoginTS)
+	require.Len(t, converted.Roles, 1)
+	assert.Equal(t, "service1", converted.Roles[0].ServiceName)
+	assert.Equal(t, map[string][]string{"service1": {"invoices:read"}}, converted.Permissions)
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+)
+
+// StateDumpVersion is bumped on every incompatible change of the StateDump format.
+const StateDumpVersion = 1
+
+// StateDump is the full state of go-auth, independent of the database schema.
+type StateDump struct {
+	CreatedTS   time.Time                    `json:"createdTs"`
+	Keys        *DumpKeys                    `json:"keys,omitempty"`
+	Services    []DumpService                `json:"services"`
+	Attributes  []AttributeDefinitionRequest `json:"attributes"`
+	Users       []DumpUser                   `json:"users"`
+	Groups      []DumpGroup                  `json:"groups"`
+	Invitations []DumpInvitation             `json:"invitations,omitempty"`
+	Webhooks    []DumpWebhook                `json:"webhooks,omitempty"`
+	Version     int                          `json:"version"`
+}
+
+// DumpService lists the service's roles and permissions catalogs. The dumps made before
+// the roles catalog existed have no roles, such services get the default ones.
+// The RolePermissions are the permissions granted by the roles, and the Hierarchy are the roles
+// directly implied by the roles, both keyed by the role name.
+type DumpService struct {
+	Name            string                     `json:"name"`
+	Roles           []ServiceRoleRequest       `json:"roles,omitempty"`
+	Permissions     []ServicePermissionRequest `json:"permissions,omitempty"`
+	RolePermissions map[string][]string        `json:"rolePermissions,omitempty"`
+	Hierarchy       map[string][]string        `json:"hierarchy,omitempty"`
+}
+
+type DumpUser struct {
+	CreatedTS       time.Time      `json:"createdTs"`
+	StatusChangedTS *time.Time     `json:"statusChangedTs,omitempty"`
+	DeletedTS       *time.Time     `json:"deletedTs,omitempty"`
+	LastLoginTS     *time.Time     `json:"lastLoginTs,omitempty"`
+	Attributes      map[string]any `json:"attributes"`
+	ID              string         `json:"id"`
+	Username        string         `json:"username"`
+	PasswordHash    string         `json:"passwordHash"`
+	Status          string         `json:"status"`
+	StatusReason    string         `json:"statusReason,omitempty"`
+	Roles           []DumpUserRole `json:"roles"`
+	APIKeys         []DumpAPIKey   `json:"apiKeys,omitempty"`
+}
+
+type DumpUserRole struct {
+	CreatedTS   time.Time  `json:"createdTs"`
+	ValidFrom   *time.Time `json:"validFrom,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	ServiceName string     `json:"serviceName"`
+	UserRole    string     `json:"userRole"`
+	ID          uint       `json:"id"`
+}
+
+// DumpAPIKey is a key of the user along with the roles it is scoped to. Only the hash of the key is dumped,
+// which is all the key is verified with, so the restored keys stay valid.
+type DumpAPIKey struct {
+	CreatedTS  time.Time         `json:"createdTs"`
+	ExpiresAt  *time.Time        `json:"expiresAt,omitempty"`
+	LastUsedTS *time.Time        `json:"lastUsedTs,omitempty"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Hint       string            `json:"hint"`
+	KeyHash    string            `json:"keyHash"`
+	Roles      []UserRoleRequest `json:"roles"`
+}
+
+type DumpGroup struct {
+	Name    string            `json:"name"`
+	Roles   []UserRoleRequest `json:"roles"`
+	Members []string          `json:"members"`
+}
+
+// DumpInvitation is an invitation, pending or not. The invite tokens only name the invitations,
+// so the pending ones stay redeemable if the signing keys are restored too.
+type DumpInvitation struct {
+	CreatedTS  time.Time         `json:"createdTs"`
+	ExpiresAt  time.Time         `json:"expiresAt"`
+	RedeemedTS *time.Time        `json:"redeemedTs,omitempty"`
+	CreatedBy  *string           `json:"createdBy,omitempty"`
+	RedeemedBy *string           `json:"redeemedBy,omitempty"`
+	ID         string            `json:"id"`
+	Roles      []UserRoleRequest `json:"roles"`
+}
+
+// DumpWebhook is a webhook subscription along with the secret its deliveries are signed with,
+// so the receivers keep verifying them. The outbox is not dumped, the undelivered events are lost.
+type DumpWebhook struct {
+	CreatedTS time.Time `json:"createdTs"`
+	CreatedBy *string   `json:"createdBy,omitempty"`
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+}
+
+// DumpKeys are the PEM encoded token signing keys.
+type DumpKeys struct {
+	PrivatePem string `json:"privatePem"`
+	PublicPem  string `json:"publicPem"`
+}
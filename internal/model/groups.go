@@ -0,0 +1,149 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+type GroupRequest struct {
+	Name string `json:"name"`
+}
+
+type GroupInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	GroupRequest
+}
+
+type GroupsResponse struct {
+	Groups []GroupInfo `json:"groups"`
+}
+
+type GroupRolesResponse struct {
+	GroupName string         `json:"groupName"`
+	Roles     []UserRoleInfo `json:"roles"`
+}
+
+type GroupMemberRequest struct {
+	UserID string `json:"userId"`
+}
+
+type GroupMemberInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	Username  string    `json:"username"`
+	GroupMemberRequest
+}
+
+type GroupMembersResponse struct {
+	GroupName string            `json:"groupName"`
+	Members   []GroupMemberInfo `json:"members"`
+}
+
+const (
+	RoleSourceDirect = "direct"
+	RoleSourceGroup  = "group"
+)
+
+// RoleSource tells where an effective role of the user came from.
+type RoleSource struct {
+	encrypt.ClaimUserRole
+	Source    string `json:"source"`
+	GroupName string `json:"groupName,omitempty"`
+}
+
+const CapGroupNameMaxlen = 40
+
+var regexpValidGroupName = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.-]*$")
+
+// ValidFormat tests if the group name is not too long and consists of letters, digits and _.- only.
+func (req GroupRequest) ValidFormat() bool {
+	return len(req.Name) <= CapGroupNameMaxlen && regexpValidGroupName.MatchString(req.Name)
+}
+
+// ValidFormat tests if the member is referenced by a uuid.
+func (req GroupMemberRequest) ValidFormat() bool {
+	return ValidUUID(req.UserID)
+}
+
+// EffectiveRoles merges the directly granted roles with the ones granted through the groups.
+// A role the user has several times is listed once. The groups' roles don't expire,
+// so a direct grant also held through a group loses its expiry.
+func EffectiveRoles(direct []storage.UserRole, viaGroups []storage.UserGroupRole) []storage.UserRole {
+	type roleKey struct {
+		userID, serviceName, userRole string
+	}
+
+	seen := make(map[roleKey]int, len(direct)+len(viaGroups))
+	effective := make([]storage.UserRole, 0, len(direct)+len(viaGroups))
+
+	for _, role := range direct {
+		key := roleKey{role.UserID, role.ServiceName, role.UserRole}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = len(effective)
+
+		effective = append(effective, role)
+	}
+
+	for _, role := range viaGroups {
+		key := roleKey{role.UserID, role.ServiceName, role.UserRole}
+		if idx, ok := seen[key]; ok {
+			effective[idx].ExpiresAt = nil
+
+			continue
+		}
+
+		seen[key] = len(effective)
+
+		effective = append(effective, storage.UserRole{
+			CreatedTS: role.CreatedTS,
+			AddUserRole: storage.AddUserRole{ //nolint:exhaustruct // the groups' roles are not time-bound.
+				UserID:      role.UserID,
+				UserRole:    role.UserRole,
+				ServiceName: role.ServiceName,
+			},
+			ID: 0,
+		})
+	}
+
+	return effective
+}
+
+// EarliestExpiry returns the moment the first of the roles expires, nil if none of them expires.
+func EarliestExpiry(roles []storage.UserRole) *time.Time {
+	var earliest *time.Time
+
+	for _, role := range roles {
+		if role.ExpiresAt != nil && (earliest == nil || role.ExpiresAt.Before(*earliest)) {
+			earliest = role.ExpiresAt
+		}
+	}
+
+	return earliest
+}
+
+// PrepareRoleSources lists every grant of the user's effective roles.
+func PrepareRoleSources(direct []storage.UserRole, viaGroups []storage.UserGroupRole) []RoleSource {
+	sources := make([]RoleSource, 0, len(direct)+len(viaGroups))
+
+	for _, role := range direct {
+		sources = append(sources, RoleSource{
+			ClaimUserRole: encrypt.ClaimUserRole{ServiceName: role.ServiceName, UserRole: role.UserRole},
+			Source:        RoleSourceDirect,
+		})
+	}
+
+	for _, role := range viaGroups {
+		sources = append(sources, RoleSource{
+			ClaimUserRole: encrypt.ClaimUserRole{ServiceName: role.ServiceName, UserRole: role.UserRole},
+			Source:        RoleSourceGroup,
+			GroupName:     role.GroupName,
+		})
+	}
+
+	return sources
+}
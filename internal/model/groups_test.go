@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.GroupRequest{Name: "devs"}.ValidFormat())
+	assert.True(t, model.GroupRequest{Name: "team-1.on_call"}.ValidFormat())
+	assert.False(t, model.GroupRequest{Name: ""}.ValidFormat())
+	assert.False(t, model.GroupRequest{Name: "-devs"}.ValidFormat())
+	assert.False(t, model.GroupRequest{Name: "dev ops"}.ValidFormat())
+	assert.False(t, model.GroupRequest{Name: strings.Repeat("a", 41)}.ValidFormat())
+}
+
+func TestEffectiveRoles(t *testing.T) {
+	t.Parallel()
+
+	direct := []storage.UserRole{
+		{AddUserRole: storage.AddUserRole{UserID: "1", ServiceName: "service1", UserRole: storage.UserRoleTypeUser}, ID: 1},  //nolint:exhaustruct,lll // other fields are not used.
+		{AddUserRole: storage.AddUserRole{UserID: "2", ServiceName: "service1", UserRole: storage.UserRoleTypeAdmin}, ID: 2}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+	viaGroups := []storage.UserGroupRole{
+		{UserID: "1", GroupRole: storage.GroupRole{AddGroupRole: storage.AddGroupRole{GroupName: "devs", ServiceName: "service1", UserRole: storage.UserRoleTypeUser}}},  //nolint:exhaustruct,lll // other fields are not used.
+		{UserID: "1", GroupRole: storage.GroupRole{AddGroupRole: storage.AddGroupRole{GroupName: "devs", ServiceName: "service1", UserRole: storage.UserRoleTypeAdmin}}}, //nolint:exhaustruct,lll // other fields are not used.
+		{UserID: "1", GroupRole: storage.GroupRole{AddGroupRole: storage.AddGroupRole{GroupName: "ops", ServiceName: "service2", UserRole: storage.UserRoleTypeRoot}}},   //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	effective := model.EffectiveRoles(direct, viaGroups)
+
+	// the duplicate user role in service1 of the user 1 is merged.
+	assert.Len(t, effective, 4)
+	assert.Equal(t, uint(1), effective[0].ID)
+	assert.Equal(t, []encrypt.ClaimUserRole{
+		{ServiceName: "service1", UserRole: storage.UserRoleTypeUser},
+		{ServiceName: "service1", UserRole: storage.UserRoleTypeAdmin},
+		{ServiceName: "service1", UserRole: storage.UserRoleTypeAdmin},
+		{ServiceName: "service2", UserRole: storage.UserRoleTypeRoot},
+	}, model.PrepareClaims(effective))
+	assert.Equal(t, "2", effective[1].UserID)
+	assert.Equal(t, "1", effective[2].UserID)
+
+	sources := model.PrepareRoleSources(direct[:1], viaGroups)
+
+	assert.Len(t, sources, 4)
+	assert.Equal(t, model.RoleSourceDirect, sources[0].Source)
+	assert.Empty(t, sources[0].GroupName)
+	assert.Equal(t, model.RoleSourceGroup, sources[1].Source)
+	assert.Equal(t, "devs", sources[1].GroupName)
+	assert.Equal(t, "ops", sources[3].GroupName)
+}
+
+func TestEffectiveRolesExpiry(t *testing.T) {
+	t.Parallel()
+
+	soon := time.Now().Add(time.Hour)
+	later := time.Now().Add(2 * time.Hour)
+
+	direct := []storage.UserRole{
+		{AddUserRole: storage.AddUserRole{ExpiresAt: &later, UserID: "1", ServiceName: "service1", UserRole: "user"}}, //nolint:exhaustruct,lll // other fields are not used.
+		{AddUserRole: storage.AddUserRole{ExpiresAt: &soon, UserID: "1", ServiceName: "service2", UserRole: "admin"}}, //nolint:exhaustruct,lll // other fields are not used.
+		{AddUserRole: storage.AddUserRole{ExpiresAt: nil, UserID: "1", ServiceName: "service3", UserRole: "admin"}},   //nolint:exhaustruct,lll // other fields are not used.
+	}
+	viaGroups := []storage.UserGroupRole{
+		{UserID: "1", GroupRole: storage.GroupRole{AddGroupRole: storage.AddGroupRole{GroupName: "devs", ServiceName: "service2", UserRole: "admin"}}}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	assert.Nil(t, model.EarliestExpiry(nil))
+	assert.Equal(t, &soon, model.EarliestExpiry(direct))
+
+	// the admin role in service2 is held through the group too, so it doesn't expire.
+	effective := model.EffectiveRoles(direct, viaGroups)
+
+	assert.Len(t, effective, 3)
+	assert.Nil(t, effective[1].ExpiresAt)
+	assert.Equal(t, &later, model.EarliestExpiry(effective))
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+type RoleImplicationRequest struct {
+	ImpliedRole string `json:"impliedRole"`
+}
+
+type RoleImpliedResponse struct {
+	ServiceName  string   `json:"serviceName"`
+	UserRole     string   `json:"userRole"`
+	ImpliedRoles []string `json:"impliedRoles"`
+}
+
+// ValidFormat tests if the implied role is well-formed.
+func (req RoleImplicationRequest) ValidFormat() bool {
+	return validateUserRole(req.ImpliedRole)
+}
+
+// PrepareRoleHierarchy converts database model role implications to the hierarchy the claims are checked against.
+func PrepareRoleHierarchy(dbImplications []storage.RoleImplication) encrypt.RoleHierarchy {
+	hierarchy := make(encrypt.RoleHierarchy)
+
+	for _, dbEntry := range dbImplications {
+		if hierarchy[dbEntry.ServiceName] == nil {
+			hierarchy[dbEntry.ServiceName] = make(map[string][]string)
+		}
+
+		hierarchy[dbEntry.ServiceName][dbEntry.UserRole] = append(hierarchy[dbEntry.ServiceName][dbEntry.UserRole],
+			dbEntry.ImpliedRole)
+	}
+
+	return hierarchy
+}
+
+// PrepareImpliedRoles lists the names of the roles directly implied by the role.
+func PrepareImpliedRoles(dbImplications []storage.RoleImplication, roleName string) []string {
+	implied := make([]string, 0, len(dbImplications))
+
+	for _, dbEntry := range dbImplications {
+		if dbEntry.UserRole == roleName {
+			implied = append(implied, dbEntry.ImpliedRole)
+		}
+	}
+
+	return implied
+}
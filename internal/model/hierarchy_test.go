@@ -0,0 +1,60 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleImplicationRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.RoleImplicationRequest{ImpliedRole: "editor"}.ValidFormat())
+	assert.False(t, model.RoleImplicationRequest{ImpliedRole: ""}.ValidFormat())
+	assert.False(t, model.RoleImplicationRequest{ImpliedRole: "super user"}.ValidFormat())
+}
+
+func TestPrepareRoleHierarchy(t *testing.T) {
+	t.Parallel()
+
+	dbImplications := []storage.RoleImplication{
+		{ServiceName: "service1", UserRole: "root", ImpliedRole: "admin"},   //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service1", UserRole: "admin", ImpliedRole: "user"},   //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service1", UserRole: "admin", ImpliedRole: "editor"}, //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service2", UserRole: "editor", ImpliedRole: "user"},  //nolint:exhaustruct // other fields are not used.
+	}
+
+	hierarchy := model.PrepareRoleHierarchy(dbImplications)
+
+	assert.Equal(t, encrypt.RoleHierarchy{
+		"service1": {"root": {"admin"}, "admin": {"user", "editor"}},
+		"service2": {"editor": {"user"}},
+	}, hierarchy)
+	assert.Equal(t, []string{"user", "editor"}, model.PrepareImpliedRoles(dbImplications, "admin"))
+	assert.Empty(t, model.PrepareImpliedRoles(dbImplications, "user"))
+
+	assert.True(t, hierarchy.Implies("service1", "root", "user"))
+	assert.True(t, hierarchy.Implies("service1", "user", "user"))
+	assert.False(t, hierarchy.Implies("service1", "user", "admin"))
+	assert.False(t, hierarchy.Implies("service2", "root", "user"))
+
+	claims := encrypt.AuthCustomClaims{ //nolint:exhaustruct // other fields are not used.
+		Roles: []encrypt.ClaimUserRole{
+			{ServiceName: "service1", UserRole: "root"},
+			{ServiceName: "service2", UserRole: "user"},
+		},
+	}
+	required := []encrypt.ClaimUserRole{
+		{ServiceName: "service1", UserRole: "editor"},
+		{ServiceName: "service2", UserRole: "editor"},
+	}
+
+	assert.True(t, claims.ContainAny(required, hierarchy))
+	assert.False(t, claims.ContainAll(required, hierarchy))
+	assert.True(t, claims.ContainAll(required[:1], hierarchy))
+	assert.False(t, claims.ContainAny(required[:1], nil))
+	assert.False(t, claims.ContainAll(nil, hierarchy))
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+// ImportRow is a user to be imported. Either the password or
+// the bcrypt passwordHash is expected.
+type ImportRow struct {
+	UserUsernme
+	Password     string            `json:"password"`
+	PasswordHash string            `json:"passwordHash"`
+	Roles        []UserRoleRequest `json:"roles"`
+	Line         int               `json:"-"`
+}
+
+type ImportRowError struct {
+	Username string `json:"username"`
+	Error    string `json:"error"`
+	Line     int    `json:"line"`
+}
+
+type ImportReport struct {
+	Errors   []ImportRowError `json:"errors"`
+	Total    int              `json:"total"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	DryRun   bool             `json:"dryRun"`
+}
+
+var (
+	ErrImportBadUsername = errors.New("bad username")
+	ErrImportBadPassword = errors.New("bad password, exactly one of password and passwordHash is expected")
+	ErrImportBadRole     = errors.New("bad role")
+	ErrImportDupRole     = errors.New("more than one role in a service")
+)
+
+// Validate tests if the row is valid to be imported.
+// The password is validated with UserCreds.ValidFormat, the hash is expected to be a bcrypt hash.
+func (row ImportRow) Validate() error {
+	if !row.UserUsernme.ValidFormat() {
+		return ErrImportBadUsername
+	}
+
+	switch {
+	case row.Password != "" && row.PasswordHash == "":
+		if !(UserCreds{UserUsernme: row.UserUsernme, Password: row.Password}).ValidFormat() {
+			return ErrImportBadPassword
+		}
+	case row.Password == "" && row.PasswordHash != "":
+		if !encrypt.IsPasswordHash(row.PasswordHash) {
+			return ErrImportBadPassword
+		}
+	default:
+		return ErrImportBadPassword
+	}
+
+	services := make(map[string]bool, len(row.Roles))
+
+	for _, role := range row.Roles {
+		if !role.ValidFormat() {
+			return ErrImportBadRole
+		}
+
+		if services[role.ServiceName] {
+			return ErrImportDupRole
+		}
+
+		services[role.ServiceName] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportRowValidate(t *testing.T) {
+	t.Parallel()
+
+	hash, err := encrypt.PasswordEncrypt("password")
+	require.NoError(t, err)
+
+	username := model.UserUsernme{Username: "username"}
+	role := model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeUser}
+
+	assert.NoError(t, model.ImportRow{UserUsernme: username, Password: "password"}.Validate())
+	assert.NoError(t, model.ImportRow{UserUsernme: username, PasswordHash: hash}.Validate())
+	assert.NoError(t, model.ImportRow{
+		UserUsernme: username,
+		Password:    "password",
+		Roles:       []model.UserRoleRequest{role, {ServiceName: "service2", UserRole: storage.UserRoleTypeAdmin}},
+	}.Validate())
+
+	assert.ErrorIs(t, model.ImportRow{UserUsernme: model.UserUsernme{Username: "u"}, Password: "password"}.Validate(),
+		model.ErrImportBadUsername)
+	assert.ErrorIs(t, model.ImportRow{UserUsernme: username}.Validate(), model.ErrImportBadPassword)
+	assert.ErrorIs(t, model.ImportRow{UserUsernme: username, Password: "password", PasswordHash: hash}.Validate(),
+		model.ErrImportBadPassword)
+	assert.ErrorIs(t, model.ImportRow{UserUsernme: username, PasswordHash: "password"}.Validate(),
+		model.ErrImportBadPassword)
+	assert.ErrorIs(t, model.ImportRow{
+		UserUsernme: username,
+		Password:    "password",
+		Roles:       []model.UserRoleRequest{{ServiceName: "service", UserRole: "super user"}},
+	}.Validate(), model.ErrImportBadRole)
+	assert.ErrorIs(t, model.ImportRow{
+		UserUsernme: username,
+		Password:    "password",
+		Roles:       []model.UserRoleRequest{role, {ServiceName: "service", UserRole: storage.UserRoleTypeAdmin}},
+	}.Validate(), model.ErrImportDupRole)
+}
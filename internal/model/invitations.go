@@ -0,0 +1,126 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+const (
+	CapInvitationRolesMax = 50
+	CapInvitationTTLMax   = 30 * 24 * time.Hour
+	DefaultInvitationTTL  = 7 * 24 * time.Hour
+)
+
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusRedeemed = "redeemed"
+	InvitationStatusExpired  = "expired"
+)
+
+// InvitationRequest describes a new invitation, the invitee is granted the Roles on redeem.
+// The invitation expires in DefaultInvitationTTL if ExpiresAt is omitted.
+type InvitationRequest struct {
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+	Roles     []UserRoleRequest `json:"roles"`
+}
+
+type InvitationInfo struct {
+	CreatedTS  time.Time               `json:"createdTs"`
+	ExpiresAt  time.Time               `json:"expiresAt"`
+	RedeemedTS *time.Time              `json:"redeemedTs"`
+	CreatedBy  *string                 `json:"createdBy"`
+	RedeemedBy *string                 `json:"redeemedBy"`
+	ID         string                  `json:"id"`
+	Status     string                  `json:"status"`
+	Roles      []encrypt.ClaimUserRole `json:"roles"`
+}
+
+// InvitationCreateResponse holds the invite token to be passed on to the invitee.
+type InvitationCreateResponse struct {
+	Token string `json:"token"`
+	InvitationInfo
+}
+
+type InvitationsResponse struct {
+	Invitations []InvitationInfo `json:"invitations"`
+}
+
+// RedeemInvitationRequest holds the invite token and the credentials the invitee has chosen.
+type RedeemInvitationRequest struct {
+	Token string `json:"token"`
+	UserCreds
+}
+
+// ValidFormat tests if the roles are valid and unique, and the invitation expires in the future,
+// no later than in CapInvitationTTLMax.
+func (req InvitationRequest) ValidFormat() bool {
+	if len(req.Roles) > CapInvitationRolesMax {
+		return false
+	}
+
+	for i, role := range req.Roles {
+		if !role.ValidFormat() || slices.Contains(req.Roles[:i], role) {
+			return false
+		}
+	}
+
+	return req.ExpiresAt == nil ||
+		(req.ExpiresAt.After(time.Now()) && req.ExpiresAt.Before(time.Now().Add(CapInvitationTTLMax)))
+}
+
+func (req RedeemInvitationRequest) ValidFormat() bool {
+	return req.Token != "" && req.UserCreds.ValidFormat()
+}
+
+// PrepareInvitationRoles converts the requested roles to the database model ones.
+func PrepareInvitationRoles(roles []UserRoleRequest) []storage.InvitationRole {
+	invitationRoles := make([]storage.InvitationRole, 0, len(roles))
+
+	for _, role := range roles {
+		invitationRoles = append(invitationRoles,
+			storage.InvitationRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+	}
+
+	return invitationRoles
+}
+
+// InvitationStatus tells if the invitation is pending, redeemed or expired at the moment.
+func InvitationStatus(invitation storage.Invitation, now time.Time) string {
+	switch {
+	case invitation.RedeemedTS != nil:
+		return InvitationStatusRedeemed
+	case !invitation.ExpiresAt.After(now):
+		return InvitationStatusExpired
+	default:
+		return InvitationStatusPending
+	}
+}
+
+// PrepareInvitations converts database model invitations to the response entries.
+func PrepareInvitations(dbInvitations []storage.Invitation) []InvitationInfo {
+	invitations := make([]InvitationInfo, 0, len(dbInvitations))
+	now := time.Now()
+
+	for _, dbEntry := range dbInvitations {
+		roles := make([]encrypt.ClaimUserRole, 0, len(dbEntry.Roles))
+		for _, role := range dbEntry.Roles {
+			roles = append(roles, encrypt.ClaimUserRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+		}
+
+		invitations = append(invitations, InvitationInfo{
+			CreatedTS:  dbEntry.CreatedTS,
+			ExpiresAt:  dbEntry.ExpiresAt,
+			RedeemedTS: dbEntry.RedeemedTS,
+			CreatedBy:  dbEntry.CreatedBy,
+			RedeemedBy: dbEntry.RedeemedBy,
+			ID:         dbEntry.ID,
+			Status:     InvitationStatus(dbEntry, now),
+			Roles:      roles,
+		})
+	}
+
+	return invitations
+}
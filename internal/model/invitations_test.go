@@ -0,0 +1,61 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	role := model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeUser}
+	roles := []model.UserRoleRequest{role}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tooFar := time.Now().Add(model.CapInvitationTTLMax + time.Hour)
+
+	assert.True(t, model.InvitationRequest{ExpiresAt: nil, Roles: roles}.ValidFormat())
+	assert.True(t, model.InvitationRequest{ExpiresAt: nil, Roles: nil}.ValidFormat())
+	assert.True(t, model.InvitationRequest{ExpiresAt: &future, Roles: roles}.ValidFormat())
+	assert.False(t, model.InvitationRequest{ExpiresAt: &past, Roles: roles}.ValidFormat())
+	assert.False(t, model.InvitationRequest{ExpiresAt: &tooFar, Roles: roles}.ValidFormat())
+	assert.False(t, model.InvitationRequest{ExpiresAt: nil, Roles: append(roles, role)}.ValidFormat())
+}
+
+func TestRedeemInvitationRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	creds := model.UserCreds{UserUsernme: model.UserUsernme{Username: "username"}, Password: "password"}
+
+	assert.True(t, model.RedeemInvitationRequest{Token: "token", UserCreds: creds}.ValidFormat())
+	assert.False(t, model.RedeemInvitationRequest{Token: "", UserCreds: creds}.ValidFormat())
+	assert.False(t, model.RedeemInvitationRequest{Token: "token", UserCreds: model.UserCreds{}}.ValidFormat()) //nolint:exhaustruct,lll // testing.
+}
+
+func TestPrepareInvitations(t *testing.T) {
+	t.Parallel()
+
+	redeemed := time.Now()
+
+	invitations := model.PrepareInvitations([]storage.Invitation{
+		{ID: "1", ExpiresAt: time.Now().Add(time.Hour)},                         //nolint:exhaustruct // other fields are not used.
+		{ID: "2", ExpiresAt: time.Now().Add(-time.Hour)},                        //nolint:exhaustruct // other fields are not used.
+		{ID: "3", ExpiresAt: time.Now().Add(-time.Hour), RedeemedTS: &redeemed}, //nolint:exhaustruct // other fields are not used.
+		{ //nolint:exhaustruct // other fields are not used.
+			ID:    "4",
+			Roles: []storage.InvitationRole{{ServiceName: "service", UserRole: storage.UserRoleTypeUser}},
+		},
+	})
+
+	require.Len(t, invitations, 4)
+	assert.Equal(t, model.InvitationStatusPending, invitations[0].Status)
+	assert.Equal(t, model.InvitationStatusExpired, invitations[1].Status)
+	assert.Equal(t, model.InvitationStatusRedeemed, invitations[2].Status)
+	require.Len(t, invitations[3].Roles, 1)
+	assert.Equal(t, "service", invitations[3].Roles[0].ServiceName)
+}
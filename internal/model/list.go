@@ -0,0 +1,192 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+type UserListItem struct {
+	CreatedTS time.Time  `json:"createdTs"`
+	DeletedTS *time.Time `json:"deletedTs,omitempty"`
+	UserInfoResponse
+}
+
+type UserListResponse struct {
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Users      []UserListItem `json:"users"`
+}
+
+const (
+	CapUserListDefaultLimit = 50
+	CapUserListMaxLimit     = 200
+)
+
+var ErrBadListQuery = errors.New("bad list query")
+
+// userListCursor is the opaque cursor handed out to the clients.
+// The sorting is a part of the cursor, so it can't be used with another sorting.
+type userListCursor struct {
+	SortBy    string `json:"s"`
+	SortValue string `json:"v"`
+	ID        string `json:"i"`
+	SortDesc  bool   `json:"d"`
+}
+
+// ParseUserListQuery converts the query parameters of the users list request to the storage filter.
+func ParseUserListQuery(query url.Values) (*storage.UserListFilter, error) {
+	filter := storage.UserListFilter{ //nolint:exhaustruct // the rest is filled below.
+		ServiceName: query.Get("service"),
+		UserRole:    query.Get("role"),
+		Search:      query.Get("q"),
+		SearchMode:  storage.UserListSearchSubstring,
+		SortBy:      storage.UserListSortUsername,
+		Limit:       CapUserListDefaultLimit,
+		Deleted:     query.Get("deleted") == "true",
+	}
+
+	if filter.UserRole != "" && !validateUserRole(filter.UserRole) {
+		return nil, ErrBadListQuery
+	}
+
+	switch query.Get("match") {
+	case "", storage.UserListSearchSubstring:
+	case storage.UserListSearchPrefix:
+		filter.SearchMode = storage.UserListSearchPrefix
+	default:
+		return nil, ErrBadListQuery
+	}
+
+	switch query.Get("sort") {
+	case "", "username":
+	case "createdTs":
+		filter.SortBy = storage.UserListSortCreatedTS
+	default:
+		return nil, ErrBadListQuery
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return nil, ErrBadListQuery
+	}
+
+	var err error
+
+	if filter.CreatedAfter, err = parseOptionalTime(query.Get("createdAfter")); err != nil {
+		return nil, err
+	}
+
+	if filter.CreatedBefore, err = parseOptionalTime(query.Get("createdBefore")); err != nil {
+		return nil, err
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 || filter.Limit > CapUserListMaxLimit {
+			return nil, ErrBadListQuery
+		}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.After, err = decodeUserListCursor(cursor, filter.SortBy, filter.SortDesc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &filter, nil
+}
+
+// PrepareUserList converts a page of database model users and their roles to the response.
+// The page is expected to be fetched with a limit one bigger than the filter's one,
+// the extra user only signals that there is a next page.
+func PrepareUserList(filter *storage.UserListFilter, dbUsers []storage.User,
+	dbRoles []storage.UserRole) UserListResponse {
+	response := UserListResponse{
+		Users: make([]UserListItem, 0, len(dbUsers)),
+	}
+
+	if len(dbUsers) > filter.Limit {
+		dbUsers = dbUsers[:filter.Limit]
+		response.NextCursor = encodeUserListCursor(filter, dbUsers[len(dbUsers)-1])
+	}
+
+	rolesByUser := make(map[string][]storage.UserRole, len(dbUsers))
+	for _, dbRole := range dbRoles {
+		rolesByUser[dbRole.UserID] = append(rolesByUser[dbRole.UserID], dbRole)
+	}
+
+	for _, dbUser := range dbUsers {
+		response.Users = append(response.Users, UserListItem{
+			CreatedTS: dbUser.CreatedTS,
+			DeletedTS: dbUser.DeletedTS,
+			UserInfoResponse: UserInfoResponse{
+				UserID:   dbUser.ID,
+				Username: dbUser.Username,
+				Status:   dbUser.Status,
+				Roles:    PrepareClaims(rolesByUser[dbUser.ID]),
+			},
+		})
+	}
+
+	return response
+}
+
+func encodeUserListCursor(filter *storage.UserListFilter, lastUser storage.User) string {
+	cursor := userListCursor{
+		SortBy:    filter.SortBy,
+		SortDesc:  filter.SortDesc,
+		SortValue: lastUser.Username,
+		ID:        lastUser.ID,
+	}
+
+	if filter.SortBy == storage.UserListSortCreatedTS {
+		cursor.SortValue = lastUser.CreatedTS.Format(time.RFC3339Nano)
+	}
+
+	raw, _ := json.Marshal(cursor) //nolint:errchkjson // plain strings and a bool.
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeUserListCursor(encoded, sortBy string, sortDesc bool) (*storage.UserListCursor, error) {
+	var cursor userListCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(raw, &cursor) != nil {
+		return nil, ErrBadListQuery
+	}
+
+	if cursor.SortBy != sortBy || cursor.SortDesc != sortDesc || !ValidUUID(cursor.ID) {
+		return nil, ErrBadListQuery
+	}
+
+	if sortBy == storage.UserListSortCreatedTS {
+		if _, err = time.Parse(time.RFC3339Nano, cursor.SortValue); err != nil {
+			return nil, ErrBadListQuery
+		}
+	}
+
+	return &storage.UserListCursor{SortValue: cursor.SortValue, ID: cursor.ID}, nil
+}
+
+func parseOptionalTime(str string) (*time.Time, error) {
+	if str == "" {
+		return nil, nil //nolint:nilnil // no time is a valid result.
+	}
+
+	parsed, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return nil, ErrBadListQuery
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,128 @@
+package model_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserListQueryDefaults(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseUserListQuery(url.Values{})
+
+	require.NoError(t, err)
+	assert.Equal(t, storage.UserListSortUsername, filter.SortBy)
+	assert.Equal(t, storage.UserListSearchSubstring, filter.SearchMode)
+	assert.Equal(t, model.CapUserListDefaultLimit, filter.Limit)
+	assert.False(t, filter.SortDesc)
+	assert.Nil(t, filter.After)
+	assert.Nil(t, filter.CreatedAfter)
+	assert.Nil(t, filter.CreatedBefore)
+}
+
+func TestParseUserListQueryFull(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseUserListQuery(url.Values{
+		"service":       {"service"},
+		"role":          {storage.UserRoleTypeAdmin},
+		"q":             {"dou"},
+		"match":         {"prefix"},
+		"sort":          {"createdTs"},
+		"order":         {"desc"},
+		"limit":         {"10"},
+		"createdAfter":  {"2024-01-02T03:04:05Z"},
+		"createdBefore": {"2024-02-02T03:04:05+03:00"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "service", filter.ServiceName)
+	assert.Equal(t, storage.UserRoleTypeAdmin, filter.UserRole)
+	assert.Equal(t, "dou", filter.Search)
+	assert.Equal(t, storage.UserListSearchPrefix, filter.SearchMode)
+	assert.Equal(t, storage.UserListSortCreatedTS, filter.SortBy)
+	assert.True(t, filter.SortDesc)
+	assert.Equal(t, 10, filter.Limit)
+	require.NotNil(t, filter.CreatedAfter)
+	assert.True(t, filter.CreatedAfter.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	require.NotNil(t, filter.CreatedBefore)
+	assert.True(t, filter.CreatedBefore.Equal(time.Date(2024, 2, 2, 0, 4, 5, 0, time.UTC)))
+}
+
+func TestParseUserListQueryInvalid(t *testing.T) {
+	t.Parallel()
+
+	queries := []url.Values{
+		{"role": {"super user"}},
+		{"match": {"regexp"}},
+		{"sort": {"password"}},
+		{"order": {"random"}},
+		{"limit": {"0"}},
+		{"limit": {"100000"}},
+		{"limit": {"ten"}},
+		{"createdAfter": {"yesterday"}},
+		{"cursor": {"not a cursor"}},
+	}
+
+	for _, query := range queries {
+		_, err := model.ParseUserListQuery(query)
+		require.ErrorIs(t, err, model.ErrBadListQuery, query)
+	}
+}
+
+func TestUserListCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseUserListQuery(url.Values{"limit": {"2"}, "sort": {"createdTs"}})
+	require.NoError(t, err)
+
+	createdTS := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	users := []storage.User{
+		{ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c1", AddUser: storage.AddUser{Username: "user1"}},                       //nolint:exhaustruct,lll // other fields are not used.
+		{ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c2", AddUser: storage.AddUser{Username: "user2"}, CreatedTS: createdTS}, //nolint:exhaustruct,lll // other fields are not used.
+		{ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c3", AddUser: storage.AddUser{Username: "user3"}},                       //nolint:exhaustruct,lll // other fields are not used.
+	}
+	roles := []storage.UserRole{
+		{AddUserRole: storage.AddUserRole{UserID: users[1].ID, ServiceName: "service", UserRole: storage.UserRoleTypeUser}}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	response := model.PrepareUserList(filter, users, roles)
+
+	require.Len(t, response.Users, 2)
+	assert.Empty(t, response.Users[0].Roles)
+	require.Len(t, response.Users[1].Roles, 1)
+	assert.Equal(t, "service", response.Users[1].Roles[0].ServiceName)
+	require.NotEmpty(t, response.NextCursor)
+
+	nextFilter, err := model.ParseUserListQuery(url.Values{"sort": {"createdTs"}, "cursor": {response.NextCursor}})
+	require.NoError(t, err)
+	require.NotNil(t, nextFilter.After)
+	assert.Equal(t, users[1].ID, nextFilter.After.ID)
+	assert.Equal(t, createdTS.Format(time.RFC3339Nano), nextFilter.After.SortValue)
+
+	// The cursor is bound to the sorting.
+	_, err = model.ParseUserListQuery(url.Values{"cursor": {response.NextCursor}})
+	require.ErrorIs(t, err, model.ErrBadListQuery)
+}
+
+func TestUserListLastPage(t *testing.T) {
+	t.Parallel()
+
+	filter, err := model.ParseUserListQuery(url.Values{"limit": {"2"}})
+	require.NoError(t, err)
+
+	users := []storage.User{
+		{ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c1", AddUser: storage.AddUser{Username: "user1"}}, //nolint:exhaustruct,lll // other fields are not used.
+	}
+
+	response := model.PrepareUserList(filter, users, nil)
+
+	require.Len(t, response.Users, 1)
+	assert.Empty(t, response.NextCursor)
+}
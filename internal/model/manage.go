@@ -1,9 +1,187 @@
 package model
 
-import "github.com/eldarbr/go-auth/internal/service/encrypt"
+import (
+	"regexp"
+	"time"
+	"unicode/utf8"
 
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+)
+
+// UserInfoResponse lists the user's effective roles. The RoleSources tell
+// where each of them came from, they are only filled for a single user's info.
 type UserInfoResponse struct {
-	UserID   string                  `json:"userId"`
-	Username string                  `json:"username"`
-	Roles    []encrypt.ClaimUserRole `json:"roles"`
+	UserID      string                  `json:"userId"`
+	Username    string                  `json:"username"`
+	Status      string                  `json:"status"`
+	Roles       []encrypt.ClaimUserRole `json:"roles"`
+	RoleSources []RoleSource            `json:"roleSources,omitempty"`
+}
+
+type UserStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type UserStatusResponse struct {
+	StatusChangedTS *time.Time `json:"statusChangedTs"`
+	UserID          string     `json:"userId"`
+	UserStatusRequest
+}
+
+type UserRoleRequest struct {
+	ServiceName string `json:"serviceName" yaml:"serviceName"`
+	UserRole    string `json:"userRole" yaml:"userRole"`
+}
+
+// RoleValidity bounds the role grant in time, the nil bounds are open.
+type RoleValidity struct {
+	ValidFrom *time.Time `json:"validFrom,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// UserRoleGrantRequest is a role granted to a user, optionally for a time only.
+type UserRoleGrantRequest struct {
+	RoleValidity
+	UserRoleRequest
+}
+
+type UserRoleInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	RoleValidity
+	UserRoleRequest
+	ID uint `json:"id"`
+}
+
+type UserRolesResponse struct {
+	UserID string         `json:"userId"`
+	Roles  []UserRoleInfo `json:"roles"`
+}
+
+type ServiceRequest struct {
+	Name string `json:"name"`
+}
+
+type ServicesResponse struct {
+	Services []ServiceRequest `json:"services"`
+}
+
+// ServiceDeleteResponse counts the users' and the groups' grants, and the API keys' and the invitations' scopes
+// the deletion removes.
+type ServiceDeleteResponse struct {
+	Name           string `json:"name"`
+	RolesAffected  int    `json:"rolesAffected"`
+	ScopesAffected int    `json:"scopesAffected"`
+	DryRun         bool   `json:"dryRun"`
+}
+
+type ServiceRoleRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ServiceRoleInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	ServiceRoleRequest
+}
+
+type ServiceRolesResponse struct {
+	ServiceName string            `json:"serviceName"`
+	Roles       []ServiceRoleInfo `json:"roles"`
+}
+
+// ServiceRoleDeleteResponse counts the users' and the groups' grants, and the API keys' and the invitations'
+// scopes the deletion removes.
+type ServiceRoleDeleteResponse struct {
+	ServiceName    string `json:"serviceName"`
+	Name           string `json:"name"`
+	GrantsAffected int    `json:"grantsAffected"`
+	ScopesAffected int    `json:"scopesAffected"`
+	DryRun         bool   `json:"dryRun"`
+}
+
+type ServiceMemberRequest struct {
+	UserUsernme
+}
+
+type ServiceMemberInfo struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	UserRoleInfo
+}
+
+type ServiceMembersResponse struct {
+	ServiceName string              `json:"serviceName"`
+	Members     []ServiceMemberInfo `json:"members"`
+}
+
+// MyOwnServiceName is the name under which go-auth keeps its own roles.
+const MyOwnServiceName = "go-auth"
+
+const (
+	CapServiceNameMinlen      = 1
+	CapServiceNameMaxlen      = 20
+	CapUserStatusReasonMaxlen = 200
+	CapUserRoleMaxlen         = 40
+	CapRoleDescriptionMaxlen  = 200
+)
+
+// ValidFormat tests if the role request is valid.
+// A valid role request has a service name of an allowed length and a well-formed user role.
+// Whether the role is defined in the service is checked by the database.
+func (req UserRoleRequest) ValidFormat() bool {
+	return validateServiceName(req.ServiceName) && validateUserRole(req.UserRole)
+}
+
+// ValidFormat tests if the role request is valid and the grant expires in the future, after it becomes valid.
+func (req UserRoleGrantRequest) ValidFormat() bool {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return false
+	}
+
+	if req.ExpiresAt != nil && req.ValidFrom != nil && !req.ValidFrom.Before(*req.ExpiresAt) {
+		return false
+	}
+
+	return req.UserRoleRequest.ValidFormat()
+}
+
+// ValidFormat tests if the status is known and the reason is not too long.
+func (req UserStatusRequest) ValidFormat() bool {
+	switch req.Status {
+	case storage.UserStatusTypeActive, storage.UserStatusTypeDisabled, storage.UserStatusTypeLocked:
+	default:
+		return false
+	}
+
+	return utf8.RuneCountInString(req.Reason) <= CapUserStatusReasonMaxlen
+}
+
+// ValidFormat tests if the service name has an allowed length.
+func (req ServiceRequest) ValidFormat() bool {
+	return validateServiceName(req.Name)
+}
+
+// ValidFormat tests if the role name is well-formed and the description is not too long.
+func (req ServiceRoleRequest) ValidFormat() bool {
+	return validateUserRole(req.Name) && utf8.RuneCountInString(req.Description) <= CapRoleDescriptionMaxlen
+}
+
+var regexpValidUUID = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// ValidUUID tests if the string is a textual representation of a uuid.
+func ValidUUID(str string) bool {
+	return regexpValidUUID.MatchString(str)
+}
+
+func validateServiceName(serviceName string) bool {
+	return len(serviceName) >= CapServiceNameMinlen &&
+		len(serviceName) <= CapServiceNameMaxlen
+}
+
+var regexpValidUserRole = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.-]*$")
+
+func validateUserRole(userRole string) bool {
+	return len(userRole) <= CapUserRoleMaxlen && regexpValidUserRole.MatchString(userRole)
 }
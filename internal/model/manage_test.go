@@ -0,0 +1,105 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRoleRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeUser}.ValidFormat())
+	assert.True(t, model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeRoot}.ValidFormat())
+	assert.False(t, model.UserRoleRequest{ServiceName: "", UserRole: storage.UserRoleTypeUser}.ValidFormat())
+	assert.False(t, model.UserRoleRequest{ServiceName: "service", UserRole: ""}.ValidFormat())
+	assert.True(t, model.UserRoleRequest{ServiceName: "service", UserRole: "billing-viewer"}.ValidFormat())
+	assert.False(t, model.UserRoleRequest{ServiceName: "service", UserRole: "super user"}.ValidFormat())
+	assert.False(t, model.UserRoleRequest{ServiceName: "service", UserRole: strings.Repeat("a", 41)}.ValidFormat())
+	assert.False(t, model.UserRoleRequest{
+		ServiceName: "a service with a way too long name",
+		UserRole:    storage.UserRoleTypeAdmin,
+	}.ValidFormat())
+}
+
+func TestUserRoleGrantRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	role := model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeAdmin}
+	past := time.Now().Add(-time.Hour)
+	soon := time.Now().Add(time.Hour)
+	later := time.Now().Add(2 * time.Hour)
+
+	assert.True(t, model.UserRoleGrantRequest{UserRoleRequest: role}.ValidFormat()) //nolint:exhaustruct // no bounds.
+	assert.True(t, model.UserRoleGrantRequest{
+		RoleValidity:    model.RoleValidity{ValidFrom: &past, ExpiresAt: &soon},
+		UserRoleRequest: role,
+	}.ValidFormat())
+	assert.True(t, model.UserRoleGrantRequest{
+		RoleValidity:    model.RoleValidity{ValidFrom: &later, ExpiresAt: nil},
+		UserRoleRequest: role,
+	}.ValidFormat())
+	assert.False(t, model.UserRoleGrantRequest{
+		RoleValidity:    model.RoleValidity{ValidFrom: nil, ExpiresAt: &past},
+		UserRoleRequest: role,
+	}.ValidFormat())
+	assert.False(t, model.UserRoleGrantRequest{
+		RoleValidity:    model.RoleValidity{ValidFrom: &later, ExpiresAt: &soon},
+		UserRoleRequest: role,
+	}.ValidFormat())
+	assert.False(t, model.UserRoleGrantRequest{
+		RoleValidity:    model.RoleValidity{ValidFrom: nil, ExpiresAt: &soon},
+		UserRoleRequest: model.UserRoleRequest{ServiceName: "service", UserRole: ""},
+	}.ValidFormat())
+}
+
+func TestServiceRoleRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.ServiceRoleRequest{Name: "editor"}.ValidFormat())
+	assert.True(t, model.ServiceRoleRequest{Name: "oncall", Description: "paged on incidents"}.ValidFormat())
+	assert.False(t, model.ServiceRoleRequest{Name: ""}.ValidFormat())
+	assert.False(t, model.ServiceRoleRequest{Name: "-editor"}.ValidFormat())
+	assert.False(t, model.ServiceRoleRequest{Name: "editor", Description: strings.Repeat("a", 201)}.ValidFormat())
+}
+
+func TestValidUUID(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.ValidUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	assert.True(t, model.ValidUUID("6BA7B810-9DAD-11D1-80B4-00C04FD430C8"))
+	assert.False(t, model.ValidUUID(""))
+	assert.False(t, model.ValidUUID("6ba7b810-9dad-11d1-80b4"))
+	assert.False(t, model.ValidUUID("6ba7b810-9dad-11d1-80b4-00c04fd430cz"))
+	assert.False(t, model.ValidUUID("'; DROP TABLE users; --"))
+}
+
+func TestServiceRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.ServiceRequest{Name: "service"}.ValidFormat())
+	assert.True(t, model.ServiceRequest{Name: "s"}.ValidFormat())
+	assert.False(t, model.ServiceRequest{Name: ""}.ValidFormat())
+	assert.False(t, model.ServiceRequest{Name: "a service with a way too long name"}.ValidFormat())
+}
+
+func TestUserStatusRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.UserStatusRequest{Status: storage.UserStatusTypeActive, Reason: ""}.ValidFormat())
+	assert.True(t, model.UserStatusRequest{Status: storage.UserStatusTypeDisabled, Reason: "left"}.ValidFormat())
+	assert.True(t, model.UserStatusRequest{
+		Status: storage.UserStatusTypeLocked,
+		Reason: strings.Repeat("ы", model.CapUserStatusReasonMaxlen),
+	}.ValidFormat())
+	assert.False(t, model.UserStatusRequest{Status: "", Reason: ""}.ValidFormat())
+	assert.False(t, model.UserStatusRequest{Status: "banned", Reason: ""}.ValidFormat())
+	assert.False(t, model.UserStatusRequest{
+		Status: storage.UserStatusTypeLocked,
+		Reason: strings.Repeat("a", model.CapUserStatusReasonMaxlen+1),
+	}.ValidFormat())
+}
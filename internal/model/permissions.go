@@ -0,0 +1,145 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+// The permissions of go-auth itself, checked on the /manage routes.
+// The go-auth root is granted all of them.
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionRolesWrite       = "roles:write"
+	PermissionServicesRead     = "services:read"
+	PermissionServicesWrite    = "services:write"
+	PermissionGroupsRead       = "groups:read"
+	PermissionGroupsWrite      = "groups:write"
+	PermissionAttributesRead   = "attributes:read"
+	PermissionAttributesWrite  = "attributes:write"
+	PermissionStateExport      = "state:export"
+	PermissionInvitationsRead  = "invitations:read"
+	PermissionInvitationsWrite = "invitations:write"
+	PermissionWebhooksRead     = "webhooks:read"
+	PermissionWebhooksWrite    = "webhooks:write"
+)
+
+// ManagePermissions is the permissions catalog of go-auth.
+var ManagePermissions = []ServicePermissionRequest{
+	{Name: PermissionUsersRead, Description: "look up the users, their roles and attributes"},
+	{Name: PermissionUsersWrite, Description: "create, import, change and delete the users"},
+	{Name: PermissionRolesWrite, Description: "grant and revoke the roles of the users and the groups"},
+	{Name: PermissionServicesRead, Description: "look up the services"},
+	{Name: PermissionServicesWrite, Description: "manage the services, their roles and permissions"},
+	{Name: PermissionGroupsRead, Description: "look up the groups and their members"},
+	{Name: PermissionGroupsWrite, Description: "manage the groups and their members"},
+	{Name: PermissionAttributesRead, Description: "look up the attributes schema"},
+	{Name: PermissionAttributesWrite, Description: "manage the attributes schema"},
+	{Name: PermissionStateExport, Description: "dump the full state"},
+	{Name: PermissionInvitationsRead, Description: "look up the invitations"},
+	{Name: PermissionInvitationsWrite, Description: "invite the users with pre-assigned roles and revoke the invitations"},
+	{Name: PermissionWebhooksRead, Description: "look up the webhooks and their dead letters"},
+	{
+		Name:        PermissionWebhooksWrite,
+		Description: "subscribe the webhooks to the events, remove them and retry their dead letters",
+	},
+}
+
+type ServicePermissionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ServicePermissionInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	ServicePermissionRequest
+}
+
+type ServicePermissionsResponse struct {
+	ServiceName string                  `json:"serviceName"`
+	Permissions []ServicePermissionInfo `json:"permissions"`
+}
+
+type RolePermissionRequest struct {
+	Permission string `json:"permission"`
+}
+
+type RolePermissionsResponse struct {
+	ServiceName string   `json:"serviceName"`
+	UserRole    string   `json:"userRole"`
+	Permissions []string `json:"permissions"`
+}
+
+const CapPermissionNameMaxlen = 100
+
+var regexpValidPermissionName = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.:-]*$")
+
+// ValidFormat tests if the permission name is well-formed and the description is not too long.
+// The names are like invoices:read.
+func (req ServicePermissionRequest) ValidFormat() bool {
+	return validatePermissionName(req.Name) &&
+		utf8.RuneCountInString(req.Description) <= CapRoleDescriptionMaxlen
+}
+
+// ValidFormat tests if the permission name is well-formed.
+func (req RolePermissionRequest) ValidFormat() bool {
+	return validatePermissionName(req.Permission)
+}
+
+func validatePermissionName(name string) bool {
+	return len(name) <= CapPermissionNameMaxlen && regexpValidPermissionName.MatchString(name)
+}
+
+// PreparePermissionClaims groups the permissions by the service. Every permission is listed once
+// and the lists are sorted. Returns nil if there are no permissions.
+func PreparePermissionClaims(dbPermissions []storage.RolePermission) map[string][]string {
+	if len(dbPermissions) == 0 {
+		return nil
+	}
+
+	claims := make(map[string][]string)
+
+	for _, dbEntry := range dbPermissions {
+		if !slices.Contains(claims[dbEntry.ServiceName], dbEntry.Permission) {
+			claims[dbEntry.ServiceName] = append(claims[dbEntry.ServiceName], dbEntry.Permission)
+		}
+	}
+
+	for _, permissions := range claims {
+		slices.Sort(permissions)
+	}
+
+	return claims
+}
+
+// PrepareServicePermissions converts database model service permissions to the response entries.
+func PrepareServicePermissions(dbPermissions []storage.ServicePermission) []ServicePermissionInfo {
+	permissions := make([]ServicePermissionInfo, 0, len(dbPermissions))
+
+	for _, dbEntry := range dbPermissions {
+		permissions = append(permissions, ServicePermissionInfo{
+			CreatedTS: dbEntry.CreatedTS,
+			ServicePermissionRequest: ServicePermissionRequest{
+				Name:        dbEntry.Name,
+				Description: dbEntry.Description,
+			},
+		})
+	}
+
+	return permissions
+}
+
+// PrepareRolePermissions lists the names of the role's permissions.
+func PrepareRolePermissions(dbPermissions []storage.RolePermission) []string {
+	permissions := make([]string, 0, len(dbPermissions))
+
+	for _, dbEntry := range dbPermissions {
+		permissions = append(permissions, dbEntry.Permission)
+	}
+
+	return permissions
+}
@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServicePermissionRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, model.ServicePermissionRequest{Name: "invoices:read"}.ValidFormat())
+	assert.True(t, model.ServicePermissionRequest{Name: "billing.reports:export", Description: "d"}.ValidFormat())
+	assert.False(t, model.ServicePermissionRequest{Name: ""}.ValidFormat())
+	assert.False(t, model.ServicePermissionRequest{Name: ":read"}.ValidFormat())
+	assert.False(t, model.ServicePermissionRequest{Name: "invoices read"}.ValidFormat())
+	assert.False(t, model.ServicePermissionRequest{Name: strings.Repeat("a", 101)}.ValidFormat())
+	assert.False(t, model.ServicePermissionRequest{
+		Name:        "invoices:read",
+		Description: strings.Repeat("a", 201),
+	}.ValidFormat())
+
+	assert.True(t, model.RolePermissionRequest{Permission: "invoices:read"}.ValidFormat())
+	assert.False(t, model.RolePermissionRequest{Permission: ""}.ValidFormat())
+
+	for _, permission := range model.ManagePermissions {
+		assert.True(t, permission.ValidFormat(), permission.Name)
+	}
+}
+
+func TestPreparePermissionClaims(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, model.PreparePermissionClaims(nil))
+
+	dbPermissions := []storage.RolePermission{
+		{ServiceName: "service1", UserRole: "editor", Permission: "invoices:write"}, //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service1", UserRole: "editor", Permission: "invoices:read"},  //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service1", UserRole: "viewer", Permission: "invoices:read"},  //nolint:exhaustruct // other fields are not used.
+		{ServiceName: "service2", UserRole: "user", Permission: "reports:read"},     //nolint:exhaustruct // other fields are not used.
+	}
+
+	assert.Equal(t, map[string][]string{
+		"service1": {"invoices:read", "invoices:write"},
+		"service2": {"reports:read"},
+	}, model.PreparePermissionClaims(dbPermissions))
+}
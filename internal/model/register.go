@@ -0,0 +1,138 @@
+package model
+
+import (
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	RegistrationModeDisabled = "disabled"
+	RegistrationModeOpen     = "open"
+	RegistrationModeDomain   = "domain"
+
+	CapRegistrationDifficultyMax = 32
+	CapRegisterEmailMaxlen       = 254
+)
+
+// RegistrationPolicy governs the self-registration at /auth/register. The registration is disabled by default.
+// In the domain mode only the emails of the EmailDomains may sign up. The new users are granted the DefaultRoles
+// of the mode. The email is saved to the EmailAttribute once verified, and in the domain mode the roles are
+// withheld until then, as the email's domain is what the roles are granted for. A non-zero ProofOfWorkBits
+// requires solving a challenge of the difficulty before signing up.
+type RegistrationPolicy struct {
+	DefaultRoles    map[string][]UserRoleRequest `yaml:"defaultRoles"`
+	Mode            string                       `yaml:"mode"`
+	EmailAttribute  string                       `yaml:"emailAttribute"`
+	EmailDomains    []string                     `yaml:"emailDomains"`
+	ProofOfWorkBits int                          `yaml:"proofOfWorkBits"`
+}
+
+// RegisterRequest holds the chosen credentials, the email in the domain mode, and the solved challenge
+// if a proof of work is required.
+type RegisterRequest struct {
+	Email     string `json:"email,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
+	Solution  string `json:"solution,omitempty"`
+	UserCreds
+}
+
+// ChallengeResponse is a proof-of-work challenge. The solution is a string such that the sha256 of
+// "<challenge>:<username>:<solution>" starts with the difficulty zero bits.
+type ChallengeResponse struct {
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+}
+
+// VerifyEmailRequest holds the token of the verification of the email given on signing up.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ValidFormat tests if the mode is known, the domain mode names the domains and the attribute
+// the verified emails are saved to, and the roles of the modes are valid.
+func (policy RegistrationPolicy) ValidFormat() bool {
+	switch policy.Mode {
+	case "", RegistrationModeDisabled, RegistrationModeOpen:
+	case RegistrationModeDomain:
+		if len(policy.EmailDomains) == 0 || policy.EmailAttribute == "" {
+			return false
+		}
+	default:
+		return false
+	}
+
+	for mode, roles := range policy.DefaultRoles {
+		if mode != RegistrationModeOpen && mode != RegistrationModeDomain {
+			return false
+		}
+
+		for i, role := range roles {
+			if !role.ValidFormat() || slices.Contains(roles[:i], role) {
+				return false
+			}
+		}
+	}
+
+	return policy.ProofOfWorkBits >= 0 && policy.ProofOfWorkBits <= CapRegistrationDifficultyMax
+}
+
+// SignUpRoles are the roles granted on signing up. None in the domain mode, until the email is verified.
+func (policy RegistrationPolicy) SignUpRoles() []UserRoleRequest {
+	if policy.Mode != RegistrationModeOpen {
+		return nil
+	}
+
+	return policy.DefaultRoles[RegistrationModeOpen]
+}
+
+// VerifiedRoles are the roles granted once the email is verified.
+func (policy RegistrationPolicy) VerifiedRoles() []UserRoleRequest {
+	if policy.Mode != RegistrationModeDomain {
+		return nil
+	}
+
+	return policy.DefaultRoles[RegistrationModeDomain]
+}
+
+// Enabled tests if the users may sign up.
+func (policy RegistrationPolicy) Enabled() bool {
+	return policy.Mode == RegistrationModeOpen || policy.Mode == RegistrationModeDomain
+}
+
+// AllowsEmail tests if the email may sign up. The email is optional in the open mode.
+func (policy RegistrationPolicy) AllowsEmail(email string) bool {
+	switch policy.Mode {
+	case RegistrationModeOpen:
+		return true
+	case RegistrationModeDomain:
+		at := strings.LastIndexByte(email, '@')
+
+		return at >= 0 && slices.ContainsFunc(policy.EmailDomains, func(domain string) bool {
+			return strings.EqualFold(domain, email[at+1:])
+		})
+	default:
+		return false
+	}
+}
+
+// ValidFormat tests the credentials and the email's format, if the email is given.
+func (req RegisterRequest) ValidFormat() bool {
+	if !req.UserCreds.ValidFormat() {
+		return false
+	}
+
+	if req.Email == "" {
+		return true
+	}
+
+	address, err := mail.ParseAddress(req.Email)
+
+	return err == nil && address.Address == req.Email && len(req.Email) <= CapRegisterEmailMaxlen
+}
+
+func (req VerifyEmailRequest) ValidFormat() bool {
+	return req.Token != ""
+}
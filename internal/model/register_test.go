@@ -0,0 +1,103 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationPolicyValidFormat(t *testing.T) {
+	t.Parallel()
+
+	role := model.UserRoleRequest{ServiceName: "service", UserRole: storage.UserRoleTypeUser}
+
+	assert.True(t, model.RegistrationPolicy{}.ValidFormat()) //nolint:exhaustruct // disabled by default.
+	assert.True(t, model.RegistrationPolicy{                 //nolint:exhaustruct // other fields are not used.
+		Mode:            model.RegistrationModeOpen,
+		DefaultRoles:    map[string][]model.UserRoleRequest{model.RegistrationModeOpen: {role}},
+		ProofOfWorkBits: 20,
+	}.ValidFormat())
+	assert.True(t, model.RegistrationPolicy{ //nolint:exhaustruct // other fields are not used.
+		Mode:           model.RegistrationModeDomain,
+		EmailDomains:   []string{"example.com"},
+		EmailAttribute: "email",
+		DefaultRoles: map[string][]model.UserRoleRequest{
+			model.RegistrationModeOpen:   {role},
+			model.RegistrationModeDomain: {role},
+		},
+	}.ValidFormat())
+	assert.False(t, model.RegistrationPolicy{Mode: model.RegistrationModeDomain}.ValidFormat()) //nolint:exhaustruct,lll // testing.
+	assert.False(t, model.RegistrationPolicy{Mode: "closed"}.ValidFormat())                     //nolint:exhaustruct,lll // testing.
+	assert.False(t, model.RegistrationPolicy{                                                   //nolint:exhaustruct // other fields are not used.
+		Mode:         model.RegistrationModeDomain,
+		EmailDomains: []string{"example.com"},
+	}.ValidFormat())
+	assert.False(t, model.RegistrationPolicy{ //nolint:exhaustruct // other fields are not used.
+		Mode:         model.RegistrationModeOpen,
+		DefaultRoles: map[string][]model.UserRoleRequest{model.RegistrationModeOpen: {role, role}},
+	}.ValidFormat())
+	assert.False(t, model.RegistrationPolicy{ //nolint:exhaustruct // other fields are not used.
+		Mode:         model.RegistrationModeOpen,
+		DefaultRoles: map[string][]model.UserRoleRequest{model.RegistrationModeDisabled: {role}},
+	}.ValidFormat())
+	assert.False(t, model.RegistrationPolicy{ //nolint:exhaustruct // other fields are not used.
+		Mode:            model.RegistrationModeOpen,
+		ProofOfWorkBits: model.CapRegistrationDifficultyMax + 1,
+	}.ValidFormat())
+}
+
+func TestRegistrationPolicyRoles(t *testing.T) {
+	t.Parallel()
+
+	openRoles := []model.UserRoleRequest{{ServiceName: "service", UserRole: storage.UserRoleTypeUser}}
+	domainRoles := []model.UserRoleRequest{{ServiceName: "service", UserRole: storage.UserRoleTypeAdmin}}
+	policy := model.RegistrationPolicy{ //nolint:exhaustruct // other fields are not used.
+		DefaultRoles: map[string][]model.UserRoleRequest{
+			model.RegistrationModeOpen:   openRoles,
+			model.RegistrationModeDomain: domainRoles,
+		},
+	}
+
+	policy.Mode = model.RegistrationModeOpen
+	assert.Equal(t, openRoles, policy.SignUpRoles())
+	assert.Empty(t, policy.VerifiedRoles())
+
+	// the domain roles are withheld until the email is verified.
+	policy.Mode = model.RegistrationModeDomain
+	assert.Empty(t, policy.SignUpRoles())
+	assert.Equal(t, domainRoles, policy.VerifiedRoles())
+
+	policy.Mode = model.RegistrationModeDisabled
+	assert.Empty(t, policy.SignUpRoles())
+	assert.Empty(t, policy.VerifiedRoles())
+}
+
+func TestRegistrationPolicyAllowsEmail(t *testing.T) {
+	t.Parallel()
+
+	open := model.RegistrationPolicy{Mode: model.RegistrationModeOpen}                                            //nolint:exhaustruct,lll // testing.
+	domain := model.RegistrationPolicy{Mode: model.RegistrationModeDomain, EmailDomains: []string{"example.com"}} //nolint:exhaustruct,lll // testing.
+
+	assert.True(t, open.AllowsEmail(""))
+	assert.True(t, open.AllowsEmail("user@other.org"))
+	assert.True(t, domain.AllowsEmail("user@example.com"))
+	assert.True(t, domain.AllowsEmail("user@EXAMPLE.com"))
+	assert.False(t, domain.AllowsEmail(""))
+	assert.False(t, domain.AllowsEmail("user@sub.example.com"))
+	assert.False(t, domain.AllowsEmail("user@example.com.evil.org"))
+	assert.False(t, model.RegistrationPolicy{}.AllowsEmail("user@example.com")) //nolint:exhaustruct // disabled.
+}
+
+func TestRegisterRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	creds := model.UserCreds{UserUsernme: model.UserUsernme{Username: "username"}, Password: "password"}
+
+	assert.True(t, model.RegisterRequest{UserCreds: creds}.ValidFormat())                                    //nolint:exhaustruct,lll // testing.
+	assert.True(t, model.RegisterRequest{Email: "user@example.com", UserCreds: creds}.ValidFormat())         //nolint:exhaustruct,lll // testing.
+	assert.False(t, model.RegisterRequest{Email: "User <user@example.com>", UserCreds: creds}.ValidFormat()) //nolint:exhaustruct,lll // testing.
+	assert.False(t, model.RegisterRequest{Email: "user", UserCreds: creds}.ValidFormat())                    //nolint:exhaustruct,lll // testing.
+	assert.False(t, model.RegisterRequest{Email: "user@example.com"}.ValidFormat())                          //nolint:exhaustruct,lll // testing.
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+)
+
+// The events the webhooks are subscribed to.
+const (
+	WebhookEventUserCreated       = "user.created"
+	WebhookEventUserStatusChanged = "user.status_changed"
+	WebhookEventUserDeleted       = "user.deleted"
+	WebhookEventUserRestored      = "user.restored"
+	WebhookEventUserRoleGranted   = "user.role_granted"
+	WebhookEventUserRoleUpdated   = "user.role_updated"
+	WebhookEventUserRoleRevoked   = "user.role_revoked"
+
+	WebhookEventGroupRoleGranted   = "group.role_granted"
+	WebhookEventGroupRoleRevoked   = "group.role_revoked"
+	WebhookEventGroupMemberAdded   = "group.member_added"
+	WebhookEventGroupMemberRemoved = "group.member_removed"
+
+	WebhookEventUserEmailVerificationRequested = "user.email_verification_requested"
+)
+
+// WebhookEventTypes lists the known events.
+var WebhookEventTypes = []string{
+	WebhookEventUserCreated,
+	WebhookEventUserStatusChanged,
+	WebhookEventUserDeleted,
+	WebhookEventUserRestored,
+	WebhookEventUserRoleGranted,
+	WebhookEventUserRoleUpdated,
+	WebhookEventUserRoleRevoked,
+	WebhookEventGroupRoleGranted,
+	WebhookEventGroupRoleRevoked,
+	WebhookEventGroupMemberAdded,
+	WebhookEventGroupMemberRemoved,
+	WebhookEventUserEmailVerificationRequested,
+}
+
+const CapWebhookURLMaxlen = 2000
+
+// WebhookEvent is the body of a webhook delivery, the Data depends on the Type.
+type WebhookEvent struct {
+	CreatedTS time.Time `json:"createdTs"`
+	Data      any       `json:"data"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+}
+
+// WebhookUserData is the data of the user events.
+type WebhookUserData struct {
+	UserID   string                 `json:"userId"`
+	Username string                 `json:"username,omitempty"`
+	Status   storage.UserStatusType `json:"status,omitempty"`
+	Reason   string                 `json:"reason,omitempty"`
+}
+
+// WebhookEmailVerificationData is the data of the email verification requests. The subscriber mails
+// the Token to the Email, the user verifies the email with it at /auth/register/verify.
+type WebhookEmailVerificationData struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+}
+
+// WebhookRoleData is the data of the role events, the role as it is after the change.
+type WebhookRoleData struct {
+	UserID string `json:"userId"`
+	UserRoleInfo
+}
+
+// WebhookGroupRoleData is the data of the group role events, every member of the group gains or loses the role.
+type WebhookGroupRoleData struct {
+	GroupName string `json:"groupName"`
+	UserRoleInfo
+}
+
+// WebhookGroupMemberData is the data of the group membership events, the member gains or loses the Roles
+// of the group.
+type WebhookGroupMemberData struct {
+	GroupName string         `json:"groupName"`
+	UserID    string         `json:"userId"`
+	Roles     []UserRoleInfo `json:"roles"`
+}
+
+// WebhookRequest describes a new webhook, no Events subscribe it to every event.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type WebhookInfo struct {
+	CreatedTS time.Time `json:"createdTs"`
+	CreatedBy *string   `json:"createdBy"`
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+}
+
+// WebhookCreateResponse holds the secret the deliveries are signed with, it is shown once.
+type WebhookCreateResponse struct {
+	Secret string `json:"secret"`
+	WebhookInfo
+}
+
+type WebhooksResponse struct {
+	Webhooks []WebhookInfo `json:"webhooks"`
+}
+
+type WebhookDeliveryInfo struct {
+	CreatedTS time.Time       `json:"createdTs"`
+	DeadTS    *time.Time      `json:"deadTs"`
+	EventType string          `json:"eventType"`
+	LastError string          `json:"lastError"`
+	Payload   json.RawMessage `json:"payload"`
+	ID        int64           `json:"id"`
+	Attempts  int             `json:"attempts"`
+}
+
+type WebhookDeadLettersResponse struct {
+	WebhookID   string                `json:"webhookId"`
+	DeadLetters []WebhookDeliveryInfo `json:"deadLetters"`
+}
+
+// ValidFormat tests if the URL is an absolute http(s) one and the events are known and unique.
+func (req WebhookRequest) ValidFormat() bool {
+	if len(req.URL) > CapWebhookURLMaxlen {
+		return false
+	}
+
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil || (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || parsedURL.Host == "" {
+		return false
+	}
+
+	for i, event := range req.Events {
+		if !slices.Contains(WebhookEventTypes, event) || slices.Contains(req.Events[:i], event) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PrepareWebhooks converts database model webhooks to the response entries, without the secrets.
+func PrepareWebhooks(dbWebhooks []storage.Webhook) []WebhookInfo {
+	webhooks := make([]WebhookInfo, 0, len(dbWebhooks))
+
+	for _, dbEntry := range dbWebhooks {
+		events := dbEntry.Events
+		if events == nil {
+			events = []string{}
+		}
+
+		webhooks = append(webhooks, WebhookInfo{
+			CreatedTS: dbEntry.CreatedTS,
+			CreatedBy: dbEntry.CreatedBy,
+			ID:        dbEntry.ID,
+			URL:       dbEntry.URL,
+			Events:    events,
+		})
+	}
+
+	return webhooks
+}
+
+// PrepareWebhookDeliveries converts database model deliveries to the response entries.
+func PrepareWebhookDeliveries(dbDeliveries []storage.WebhookDelivery) []WebhookDeliveryInfo {
+	deliveries := make([]WebhookDeliveryInfo, 0, len(dbDeliveries))
+
+	for _, dbEntry := range dbDeliveries {
+		deliveries = append(deliveries, WebhookDeliveryInfo{
+			CreatedTS: dbEntry.CreatedTS,
+			DeadTS:    dbEntry.DeadTS,
+			EventType: dbEntry.EventType,
+			LastError: dbEntry.LastError,
+			Payload:   dbEntry.Payload,
+			ID:        dbEntry.ID,
+			Attempts:  dbEntry.Attempts,
+		})
+	}
+
+	return deliveries
+}
+
+// PrepareWebhookUser converts the database model user to the data of the user events.
+func PrepareWebhookUser(dbUser *storage.User) WebhookUserData {
+	return WebhookUserData{
+		UserID:   dbUser.ID,
+		Username: dbUser.Username,
+		Status:   dbUser.Status,
+		Reason:   dbUser.StatusReason,
+	}
+}
+
+// PrepareWebhookRole converts the database model role to the data of the role events.
+func PrepareWebhookRole(dbRole storage.UserRole) WebhookRoleData {
+	return WebhookRoleData{
+		UserID:       dbRole.UserID,
+		UserRoleInfo: PrepareUserRoles([]storage.UserRole{dbRole})[0],
+	}
+}
+
+// PrepareWebhookGroupRole converts the database model group role to the data of the group role events.
+func PrepareWebhookGroupRole(dbRole storage.GroupRole) WebhookGroupRoleData {
+	return WebhookGroupRoleData{
+		GroupName:    dbRole.GroupName,
+		UserRoleInfo: PrepareGroupRoles([]storage.GroupRole{dbRole})[0],
+	}
+}
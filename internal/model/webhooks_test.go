@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRequestValidFormat(t *testing.T) {
+	t.Parallel()
+
+	created := []string{model.WebhookEventUserCreated}
+	tooLong := "https://example.com/" + strings.Repeat("a", model.CapWebhookURLMaxlen)
+
+	assert.True(t, model.WebhookRequest{URL: "https://example.com/hook", Events: nil}.ValidFormat())
+	assert.True(t, model.WebhookRequest{URL: "http://localhost:8080/hook?a=b", Events: created}.ValidFormat())
+	assert.True(t, model.WebhookRequest{URL: "https://example.com", Events: model.WebhookEventTypes}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: "", Events: nil}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: "/hook", Events: nil}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: "ftp://example.com/hook", Events: nil}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: tooLong, Events: nil}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: "https://example.com", Events: []string{"user.unknown"}}.ValidFormat())
+	assert.False(t, model.WebhookRequest{URL: "https://example.com", Events: append(created, created...)}.ValidFormat())
+}
+
+func TestPrepareWebhooks(t *testing.T) {
+	t.Parallel()
+
+	webhooks := model.PrepareWebhooks([]storage.Webhook{
+		{ID: "1", URL: "https://example.com", Secret: "secret"},    //nolint:exhaustruct // other fields are not used.
+		{ID: "2", Events: []string{model.WebhookEventUserDeleted}}, //nolint:exhaustruct // other fields are not used.
+	})
+
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, "https://example.com", webhooks[0].URL)
+	assert.Empty(t, webhooks[0].Events)
+	assert.NotNil(t, webhooks[0].Events)
+	assert.Equal(t, []string{model.WebhookEventUserDeleted}, webhooks[1].Events)
+}
+
+func TestPrepareWebhookRole(t *testing.T) {
+	t.Parallel()
+
+	role := model.PrepareWebhookRole(storage.UserRole{ //nolint:exhaustruct // other fields are not used.
+		ID: 7,
+		AddUserRole: storage.AddUserRole{ //nolint:exhaustruct // not time-bound.
+			UserID:      "user",
+			ServiceName: "service",
+			UserRole:    storage.UserRoleTypeAdmin,
+		},
+	})
+
+	assert.Equal(t, "user", role.UserID)
+	assert.Equal(t, uint(7), role.ID)
+	assert.Equal(t, "service", role.ServiceName)
+	assert.Equal(t, storage.UserRoleTypeAdmin, role.UserRole)
+}
+
+func TestPrepareWebhookGroupRole(t *testing.T) {
+	t.Parallel()
+
+	role := model.PrepareWebhookGroupRole(storage.GroupRole{ //nolint:exhaustruct // other fields are not used.
+		ID: 3,
+		AddGroupRole: storage.AddGroupRole{
+			GroupName:   "group",
+			ServiceName: "service",
+			UserRole:    storage.UserRoleTypeUser,
+		},
+	})
+
+	assert.Equal(t, "group", role.GroupName)
+	assert.Equal(t, uint(3), role.ID)
+	assert.Equal(t, "service", role.ServiceName)
+	assert.Equal(t, storage.UserRoleTypeUser, role.UserRole)
+}
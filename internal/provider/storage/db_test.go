@@ -3,6 +3,8 @@ package storage_test
 import (
 	"context"
 	"flag"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,7 +23,7 @@ func TestMain(m *testing.M) {
 
 	if testDBUri != nil && *testDBUri != "" {
 		// Not checking the error as if there is an error, the tests won't run.
-		testDB, _ = database.Setup(context.Background(), *testDBUri, "file://./sql")
+		testDB, _ = database.Setup(context.Background(), *testDBUri, storage.Migrations())
 
 		defer testDB.ClosePool()
 	}
@@ -54,6 +56,46 @@ func TestNilArguments(t *testing.T) {
 	require.ErrorIs(t, err, database.ErrNilArgument)
 	require.ErrorIs(t, storage.TableUsersRoles.Insert(context.Background(), testDB.GetPool(), nil),
 		database.ErrNilArgument)
+	require.ErrorIs(t, storage.TableUsers.Insert(context.Background(), testDB.GetPool(), nil),
+		database.ErrNilArgument)
+
+	_, err = storage.TableUsers.MergeAttributesByID(context.Background(), testDB.GetPool(), nil, "")
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+	require.ErrorIs(t, storage.TableAttributeDefinitions.Add(context.Background(), testDB.GetPool(), nil),
+		database.ErrNilArgument)
+
+	_, err = storage.TableGroupsRoles.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableServicesRoles.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableServicesPermissions.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableRolesPermissions.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableRolesHierarchy.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableAPIKeys.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableInvitations.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
+
+	_, err = storage.TableWebhooks.Add(context.Background(), testDB.GetPool(), nil)
+
+	require.ErrorIs(t, err, database.ErrNilArgument)
 }
 
 func TestNilDB(t *testing.T) {
@@ -71,6 +113,42 @@ func TestNilDB(t *testing.T) {
 	_, err = storage.TableUsers.GetByUsername(context.Background(), nil, "")
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 
+	_, err = storage.TableUsers.GetByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsers.Insert(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.List(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.CountAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsers.UpdateStatusByID(context.Background(), nil, nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsers.UpdateLastLoginByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.MergeAttributesByID(context.Background(), nil, nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.DeleteAttribute(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsers.SoftDeleteByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsers.RestoreByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsers.PurgeDeleted(context.Background(), nil, time.Now())
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
 	err = storage.TableUsers.DeleteByUsername(context.Background(), nil, "")
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 
@@ -83,6 +161,9 @@ func TestNilDB(t *testing.T) {
 	_, err = storage.TableServices.GetByServiceName(context.Background(), nil, "")
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 
+	_, err = storage.TableServices.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
 	err = storage.TableServices.Delete(context.Background(), nil, "")
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 
@@ -101,8 +182,197 @@ func TestNilDB(t *testing.T) {
 	_, err = storage.TableUsersRoles.GetByUserID(context.Background(), nil, "")
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 
+	_, err = storage.TableUsersRoles.GetAllByUserID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.GetByUserIDs(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.GetByUserIDAndServiceName(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.GetMembersByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.CountByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
 	err = storage.TableUsersRoles.DeleteByID(context.Background(), nil, 0)
 	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.GetByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.DeleteExpired(context.Background(), nil, time.Time{})
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableUsersRoles.DeleteByDeletedUsers(context.Background(), nil, time.Time{})
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableUsersRoles.ResetIDSequence(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableAttributeDefinitions.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableAttributeDefinitions.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableAttributeDefinitions.Delete(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesRoles.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableServicesRoles.AddDefaults(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesRoles.GetByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesRoles.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesRoles.CountGrants(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesRoles.CountScopes(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableServicesRoles.LockGrants(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableServicesRoles.Delete(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesPermissions.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesPermissions.GetByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableServicesPermissions.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableServicesPermissions.Delete(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesPermissions.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesPermissions.GetByRole(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesPermissions.GetByRoles(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesPermissions.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableRolesPermissions.Delete(context.Background(), nil, "", "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesHierarchy.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesHierarchy.GetByServiceName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesHierarchy.GetByServiceNames(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableRolesHierarchy.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableRolesHierarchy.Delete(context.Background(), nil, "", "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableAPIKeys.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableAPIKeys.GetByHash(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableAPIKeys.GetByUserID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableAPIKeys.UpdateLastUsedByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableAPIKeys.DeleteByID(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableInvitations.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableInvitations.GetByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableInvitations.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableInvitations.Redeem(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableInvitations.DeleteByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableWebhooks.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableWebhooks.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableWebhooks.DeleteByID(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableWebhookDeliveries.Enqueue(context.Background(), nil, "", nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableWebhookDeliveries.Claim(context.Background(), nil, 0, 0)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroups.Add(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroups.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroups.GetByName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableGroups.Delete(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsRoles.Add(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsRoles.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsRoles.GetByGroupName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsRoles.GetByUserIDs(context.Background(), nil, nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableGroupsRoles.DeleteByID(context.Background(), nil, "", 0)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableGroupsUsers.Add(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsUsers.GetAll(context.Background(), nil)
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	_, err = storage.TableGroupsUsers.GetByGroupName(context.Background(), nil, "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
+
+	err = storage.TableGroupsUsers.Delete(context.Background(), nil, "", "")
+	require.ErrorIs(t, err, database.ErrDBNotInitilized)
 }
 
 func TestUsersValidAddAndGet(t *testing.T) {
@@ -137,6 +407,11 @@ func TestUsersValidAddAndGet(t *testing.T) {
 		require.NotNil(t, dbUser)
 		assert.Equal(t, user.Username, dbUser.Username)
 		assert.Equal(t, user.Password, dbUser.Password)
+
+		dbUserByID, err := storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), dbUser.ID)
+		require.NoError(t, err)
+		require.NotNil(t, dbUserByID)
+		assert.Equal(t, *dbUser, *dbUserByID)
 	}
 }
 
@@ -450,6 +725,9 @@ func setupForValidUserGroupsAddAndGet(t *testing.T, suffix string) map[string]st
 	for _, service := range services {
 		err := storage.TableServices.Add(context.Background(), testDB.GetPool(), &service)
 		require.NoError(t, err)
+
+		err = storage.TableServicesRoles.AddDefaults(context.Background(), testDB.GetPool(), service.Name)
+		require.NoError(t, err)
 	}
 
 	users := []storage.AddUser{
@@ -553,3 +831,1124 @@ func TestUsersGroupsValidUpdateAndDelete(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+func TestServicesRenameKeepsRoles(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "3")
+
+	for _, username := range []string{"username113", "username213"} {
+		_, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+			UserID:      idsMap[username],
+			UserRole:    storage.UserRoleTypeUser,
+			ServiceName: "service113",
+		})
+		require.NoError(t, err)
+	}
+
+	cnt, err := storage.TableUsersRoles.CountByServiceName(context.Background(), testDB.GetPool(), "service113")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+
+	err = storage.TableServices.Update(context.Background(), testDB.GetPool(),
+		&storage.Service{Name: "renamed113"}, "service113")
+	require.NoError(t, err)
+
+	cnt, err = storage.TableUsersRoles.CountByServiceName(context.Background(), testDB.GetPool(), "renamed113")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+
+	services, err := storage.TableServices.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+	assert.Contains(t, services, storage.Service{Name: "renamed113"})
+	assert.NotContains(t, services, storage.Service{Name: "service113"})
+
+	err = storage.TableServices.Delete(context.Background(), testDB.GetPool(), "renamed113")
+	require.NoError(t, err)
+
+	cnt, err = storage.TableUsersRoles.CountByServiceName(context.Background(), testDB.GetPool(), "renamed113")
+	require.NoError(t, err)
+	assert.Equal(t, 0, cnt)
+}
+
+//nolint:funlen // Won't decompose.
+func TestUsersList(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "4")
+
+	_, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		UserID:      idsMap["username214"],
+		UserRole:    storage.UserRoleTypeAdmin,
+		ServiceName: "service114",
+	})
+	require.NoError(t, err)
+
+	// Search.
+	users, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+		Search:     "ername_1_4",
+		SearchMode: storage.UserListSearchSubstring,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, users) // the underscore is not a wildcard.
+
+	users, err = storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+		Search:     "USERNAME",
+		SearchMode: storage.UserListSearchPrefix,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, users)
+
+	// Filter by the role.
+	users, err = storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+		ServiceName: "service114",
+		UserRole:    storage.UserRoleTypeAdmin,
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "username214", users[0].Username)
+
+	roles, err := storage.TableUsersRoles.GetByUserIDs(context.Background(), testDB.GetPool(),
+		[]string{idsMap["username114"], idsMap["username214"]})
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, idsMap["username214"], roles[0].UserID)
+
+	// Paginate.
+	filter := storage.UserListFilter{
+		Search:   "4",
+		SortBy:   storage.UserListSortCreatedTS,
+		SortDesc: true,
+		Limit:    2,
+	}
+
+	firstPage, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &filter)
+	require.NoError(t, err)
+	require.Len(t, firstPage, 2)
+
+	filter.After = &storage.UserListCursor{
+		SortValue: firstPage[1].CreatedTS.Format(time.RFC3339Nano),
+		ID:        firstPage[1].ID,
+	}
+
+	secondPage, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &filter)
+	require.NoError(t, err)
+	require.NotEmpty(t, secondPage)
+
+	for _, user := range secondPage {
+		assert.NotEqual(t, firstPage[0].ID, user.ID)
+		assert.NotEqual(t, firstPage[1].ID, user.ID)
+		assert.False(t, user.CreatedTS.After(firstPage[1].CreatedTS))
+	}
+}
+
+func TestUsersRolesServiceMembers(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "5")
+
+	for username, role := range map[string]string{
+		"username215": storage.UserRoleTypeUser,
+		"username115": storage.UserRoleTypeAdmin,
+	} {
+		_, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+			UserID:      idsMap[username],
+			UserRole:    role,
+			ServiceName: "service215",
+		})
+		require.NoError(t, err)
+	}
+
+	members, err := storage.TableUsersRoles.GetMembersByServiceName(context.Background(), testDB.GetPool(),
+		"service215")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "username115", members[0].Username)
+	assert.Equal(t, storage.UserRoleTypeAdmin, members[0].UserRole.UserRole)
+	assert.Equal(t, "username215", members[1].Username)
+	assert.Equal(t, storage.UserRoleTypeUser, members[1].UserRole.UserRole)
+
+	role, err := storage.TableUsersRoles.GetByUserIDAndServiceName(context.Background(), testDB.GetPool(),
+		idsMap["username215"], "service215")
+	require.NoError(t, err)
+	assert.Equal(t, members[1].UserRole, *role)
+
+	_, err = storage.TableUsersRoles.GetByUserIDAndServiceName(context.Background(), testDB.GetPool(),
+		idsMap["username315"], "service215")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestUsersUpdateStatus(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	added, err := storage.TableUsers.Add(context.Background(), testDB.GetPool(), &storage.AddUser{
+		Username: "statususer",
+		Password: "password",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storage.UserStatusTypeActive, added.Status)
+	assert.Empty(t, added.StatusReason)
+	assert.Nil(t, added.StatusChangedTS)
+
+	err = storage.TableUsers.UpdateStatusByID(context.Background(), testDB.GetPool(), &storage.UserStatus{
+		Status: storage.UserStatusTypeDisabled,
+		Reason: "left the company",
+	}, added.ID)
+	require.NoError(t, err)
+
+	dbUser, err := storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), added.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.UserStatusTypeDisabled, dbUser.Status)
+	assert.Equal(t, "left the company", dbUser.StatusReason)
+	require.NotNil(t, dbUser.StatusChangedTS)
+
+	err = storage.TableUsers.UpdateStatusByID(context.Background(), testDB.GetPool(), &storage.UserStatus{
+		Status: storage.UserStatusTypeActive,
+	}, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestUsersSoftDeleteAndRestore(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "6")
+
+	_, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		UserID:      idsMap["username116"],
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service116",
+	})
+	require.NoError(t, err)
+
+	err = storage.TableUsers.SoftDeleteByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.NoError(t, err)
+
+	err = storage.TableUsers.SoftDeleteByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	// Hidden from the lookups.
+	_, err = storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	_, err = storage.TableUsers.GetByUsername(context.Background(), testDB.GetPool(), "username116")
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	deleted, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+		Search:  "username116",
+		Deleted: true,
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.NotNil(t, deleted[0].DeletedTS)
+
+	// Purge doesn't touch the recently deleted users.
+	_, err = storage.TableUsers.PurgeDeleted(context.Background(), testDB.GetPool(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	err = storage.TableUsers.RestoreByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.NoError(t, err)
+
+	err = storage.TableUsers.RestoreByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	restored, err := storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedTS)
+
+	// The roles are kept.
+	roles, err := storage.TableUsersRoles.GetByUserID(context.Background(), testDB.GetPool(), idsMap["username116"])
+	require.NoError(t, err)
+	assert.Len(t, roles, 1)
+
+	// Purge the deleted user.
+	err = storage.TableUsers.SoftDeleteByID(context.Background(), testDB.GetPool(), idsMap["username216"])
+	require.NoError(t, err)
+
+	purgedRoles, err := storage.TableUsersRoles.DeleteByDeletedUsers(context.Background(), testDB.GetPool(),
+		time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, slices.ContainsFunc(purgedRoles, func(role storage.UserRole) bool {
+		return role.UserID == idsMap["username116"]
+	}))
+
+	purged, err := storage.TableUsers.PurgeDeleted(context.Background(), testDB.GetPool(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Positive(t, purged)
+
+	err = storage.TableUsers.RestoreByID(context.Background(), testDB.GetPool(), idsMap["username216"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestUsersInsertAndGetAll(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	setupForValidUserGroupsAddAndGet(t, "7")
+
+	deletedTS := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	user := storage.User{
+		CreatedTS: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		DeletedTS: &deletedTS,
+		AddUser: storage.AddUser{
+			Username: "username417",
+			Password: "password4",
+		},
+		ID:           "7c1f8a2e-3b4d-4e5f-8a9b-0c1d2e3f4a57",
+		Status:       storage.UserStatusTypeLocked,
+		StatusReason: "reason",
+	}
+
+	err := storage.TableUsers.Insert(context.Background(), testDB.GetPool(), &user)
+	require.NoError(t, err)
+
+	err = storage.TableUsers.Insert(context.Background(), testDB.GetPool(), &user)
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	err = storage.TableUsersRoles.Insert(context.Background(), testDB.GetPool(), &storage.UserRole{
+		CreatedTS: user.CreatedTS,
+		AddUserRole: storage.AddUserRole{
+			UserID:      user.ID,
+			UserRole:    storage.UserRoleTypeAdmin,
+			ServiceName: "service117",
+		},
+		ID: 1_000_007,
+	})
+	require.NoError(t, err)
+
+	// The sequence is moved past the inserted id.
+	require.NoError(t, storage.TableUsersRoles.ResetIDSequence(context.Background(), testDB.GetPool()))
+
+	added, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		UserID:      user.ID,
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service217",
+	})
+	require.NoError(t, err)
+	assert.Greater(t, added.ID, uint(1_000_007))
+
+	users, err := storage.TableUsers.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+
+	count, err := storage.TableUsers.CountAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, len(users))
+
+	idx := slices.IndexFunc(users, func(dbUser storage.User) bool { return dbUser.ID == user.ID })
+	require.GreaterOrEqual(t, idx, 0)
+	assert.Equal(t, user.Username, users[idx].Username)
+	assert.Equal(t, user.Status, users[idx].Status)
+	assert.True(t, user.CreatedTS.Equal(users[idx].CreatedTS))
+	require.NotNil(t, users[idx].DeletedTS)
+	assert.True(t, deletedTS.Equal(*users[idx].DeletedTS))
+
+	roles, err := storage.TableUsersRoles.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(roles, func(role storage.UserRole) bool { return role.ID == 1_000_007 }))
+}
+
+func TestUsersUpdateLastLogin(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "8")
+
+	dbUser, err := storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), idsMap["username118"])
+	require.NoError(t, err)
+	assert.Nil(t, dbUser.LastLoginTS)
+
+	err = storage.TableUsers.UpdateLastLoginByID(context.Background(), testDB.GetPool(), idsMap["username118"])
+	require.NoError(t, err)
+
+	dbUser, err = storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), idsMap["username118"])
+	require.NoError(t, err)
+	require.NotNil(t, dbUser.LastLoginTS)
+	assert.WithinDuration(t, time.Now(), *dbUser.LastLoginTS, time.Minute)
+
+	err = storage.TableUsers.UpdateLastLoginByID(context.Background(), testDB.GetPool(),
+		"00000000-0000-0000-0000-000000000000")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestUsersAttributes(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "9")
+
+	err := storage.TableAttributeDefinitions.Add(context.Background(), testDB.GetPool(), &storage.AttributeDefinition{
+		Name:      "locale9",
+		ValueType: storage.AttributeValueTypeString,
+		MaxLength: 10,
+	})
+	require.NoError(t, err)
+
+	err = storage.TableAttributeDefinitions.Add(context.Background(), testDB.GetPool(), &storage.AttributeDefinition{
+		Name:      "locale9",
+		ValueType: storage.AttributeValueTypeNumber,
+	})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	definitions, err := storage.TableAttributeDefinitions.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+	assert.Contains(t, definitions, storage.AttributeDefinition{
+		Name:      "locale9",
+		ValueType: storage.AttributeValueTypeString,
+		MaxLength: 10,
+	})
+
+	dbUser, err := storage.TableUsers.GetByID(context.Background(), testDB.GetPool(), idsMap["username119"])
+	require.NoError(t, err)
+	assert.Empty(t, dbUser.Attributes)
+
+	attributes, err := storage.TableUsers.MergeAttributesByID(context.Background(), testDB.GetPool(),
+		map[string]any{"locale9": "en", "floor9": float64(3)}, idsMap["username119"])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"locale9": "en", "floor9": float64(3)}, attributes)
+
+	// null removes the attribute.
+	attributes, err = storage.TableUsers.MergeAttributesByID(context.Background(), testDB.GetPool(),
+		map[string]any{"floor9": nil}, idsMap["username119"])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"locale9": "en"}, attributes)
+
+	dbUser, err = storage.TableUsers.GetByUsername(context.Background(), testDB.GetPool(), "username119")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"locale9": "en"}, dbUser.Attributes)
+
+	_, err = storage.TableUsers.MergeAttributesByID(context.Background(), testDB.GetPool(),
+		map[string]any{}, "00000000-0000-0000-0000-000000000000")
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	affected, err := storage.TableUsers.DeleteAttribute(context.Background(), testDB.GetPool(), "locale9")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	err = storage.TableAttributeDefinitions.Delete(context.Background(), testDB.GetPool(), "locale9")
+	require.NoError(t, err)
+
+	err = storage.TableAttributeDefinitions.Delete(context.Background(), testDB.GetPool(), "locale9")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+//nolint:funlen // Won't decompose.
+func TestGroups(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "10")
+
+	group, err := storage.TableGroups.Add(context.Background(), testDB.GetPool(), "group10")
+	require.NoError(t, err)
+	assert.Equal(t, "group10", group.Name)
+
+	_, err = storage.TableGroups.Add(context.Background(), testDB.GetPool(), "group10")
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	groupRole, err := storage.TableGroupsRoles.Add(context.Background(), testDB.GetPool(), &storage.AddGroupRole{
+		GroupName:   "group10",
+		UserRole:    storage.UserRoleTypeAdmin,
+		ServiceName: "service1110",
+	})
+	require.NoError(t, err)
+
+	_, err = storage.TableGroupsRoles.Add(context.Background(), testDB.GetPool(), &storage.AddGroupRole{
+		GroupName:   "group10",
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service1110",
+	})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	_, err = storage.TableGroupsRoles.Add(context.Background(), testDB.GetPool(), &storage.AddGroupRole{
+		GroupName:   "group10",
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "unknown10",
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	err = storage.TableGroupsUsers.Add(context.Background(), testDB.GetPool(), "group10", idsMap["username1110"])
+	require.NoError(t, err)
+
+	err = storage.TableGroupsUsers.Add(context.Background(), testDB.GetPool(), "group10", idsMap["username1110"])
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	members, err := storage.TableGroupsUsers.GetByGroupName(context.Background(), testDB.GetPool(), "group10")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "username1110", members[0].Username)
+
+	userGroupRoles, err := storage.TableGroupsRoles.GetByUserIDs(context.Background(), testDB.GetPool(),
+		[]string{idsMap["username1110"], idsMap["username2110"]})
+	require.NoError(t, err)
+	require.Len(t, userGroupRoles, 1)
+	assert.Equal(t, idsMap["username1110"], userGroupRoles[0].UserID)
+	assert.Equal(t, groupRole.ID, userGroupRoles[0].ID)
+
+	// The users list sees the group roles.
+	listed, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+		ServiceName: "service1110",
+		UserRole:    storage.UserRoleTypeAdmin,
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, idsMap["username1110"], listed[0].ID)
+
+	err = storage.TableGroupsRoles.DeleteByID(context.Background(), testDB.GetPool(), "another10", groupRole.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	err = storage.TableGroupsRoles.DeleteByID(context.Background(), testDB.GetPool(), "group10", groupRole.ID)
+	require.NoError(t, err)
+
+	err = storage.TableGroupsUsers.Delete(context.Background(), testDB.GetPool(), "group10", idsMap["username1110"])
+	require.NoError(t, err)
+
+	err = storage.TableGroupsUsers.Delete(context.Background(), testDB.GetPool(), "group10", idsMap["username1110"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	err = storage.TableGroups.Delete(context.Background(), testDB.GetPool(), "group10")
+	require.NoError(t, err)
+
+	_, err = storage.TableGroups.GetByName(context.Background(), testDB.GetPool(), "group10")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestServicesRoles(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "11")
+
+	dbRole, err := storage.TableServicesRoles.Add(context.Background(), testDB.GetPool(), &storage.ServiceRole{
+		ServiceName: "service1111",
+		Name:        "billing-viewer",
+		Description: "reads the invoices",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "reads the invoices", dbRole.Description)
+
+	_, err = storage.TableServicesRoles.Add(context.Background(), testDB.GetPool(), &storage.ServiceRole{
+		ServiceName: "service1111",
+		Name:        "billing-viewer",
+	})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	_, err = storage.TableServicesRoles.Add(context.Background(), testDB.GetPool(), &storage.ServiceRole{
+		ServiceName: "unknown11",
+		Name:        "billing-viewer",
+	})
+	require.ErrorIs(t, err, database.ErrForeignKeyViolation)
+
+	roles, err := storage.TableServicesRoles.GetByServiceName(context.Background(), testDB.GetPool(), "service1111")
+	require.NoError(t, err)
+	assert.Len(t, roles, len(storage.DefaultServiceRoles)+1)
+
+	// The role is only defined in the first service.
+	_, err = storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		UserID:      idsMap["username1111"],
+		UserRole:    "billing-viewer",
+		ServiceName: "service2111",
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	_, err = storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		UserID:      idsMap["username1111"],
+		UserRole:    "billing-viewer",
+		ServiceName: "service1111",
+	})
+	require.NoError(t, err)
+
+	count, err := storage.TableServicesRoles.CountGrants(context.Background(), testDB.GetPool(),
+		"service1111", "billing-viewer")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = storage.TableServicesRoles.CountGrants(context.Background(), testDB.GetPool(), "service1111", "")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 1)
+
+	count, err = storage.TableServicesRoles.CountScopes(context.Background(), testDB.GetPool(),
+		"service1111", "billing-viewer")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	tx, err := testDB.Begin(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, storage.TableServicesRoles.LockGrants(context.Background(), tx))
+	require.NoError(t, tx.Rollback(context.Background()))
+
+	// Deleting the role revokes it.
+	err = storage.TableServicesRoles.Delete(context.Background(), testDB.GetPool(), "service1111", "billing-viewer")
+	require.NoError(t, err)
+
+	_, err = storage.TableUsersRoles.GetByUserIDAndServiceName(context.Background(), testDB.GetPool(),
+		idsMap["username1111"], "service1111")
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	err = storage.TableServicesRoles.Delete(context.Background(), testDB.GetPool(), "service1111", "billing-viewer")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+//nolint:funlen // Won't decompose.
+func TestPermissions(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	_ = setupForValidUserGroupsAddAndGet(t, "12")
+
+	for _, name := range []string{"invoices:read", "invoices:write"} {
+		_, err := storage.TableServicesPermissions.Add(context.Background(), testDB.GetPool(),
+			&storage.ServicePermission{ServiceName: "service1112", Name: name})
+		require.NoError(t, err)
+	}
+
+	_, err := storage.TableServicesPermissions.Add(context.Background(), testDB.GetPool(),
+		&storage.ServicePermission{ServiceName: "service1112", Name: "invoices:read"})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	_, err = storage.TableServicesPermissions.Add(context.Background(), testDB.GetPool(),
+		&storage.ServicePermission{ServiceName: "unknown12", Name: "invoices:read"})
+	require.ErrorIs(t, err, database.ErrForeignKeyViolation)
+
+	for _, name := range []string{"invoices:read", "invoices:write"} {
+		_, err = storage.TableRolesPermissions.Add(context.Background(), testDB.GetPool(), &storage.RolePermission{
+			ServiceName: "service1112",
+			UserRole:    storage.UserRoleTypeAdmin,
+			Permission:  name,
+		})
+		require.NoError(t, err)
+	}
+
+	_, err = storage.TableRolesPermissions.Add(context.Background(), testDB.GetPool(), &storage.RolePermission{
+		ServiceName: "service1112",
+		UserRole:    storage.UserRoleTypeUser,
+		Permission:  "invoices:read",
+	})
+	require.NoError(t, err)
+
+	_, err = storage.TableRolesPermissions.Add(context.Background(), testDB.GetPool(), &storage.RolePermission{
+		ServiceName: "service1112",
+		UserRole:    "editor",
+		Permission:  "invoices:read",
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	_, err = storage.TableRolesPermissions.Add(context.Background(), testDB.GetPool(), &storage.RolePermission{
+		ServiceName: "service1112",
+		UserRole:    storage.UserRoleTypeUser,
+		Permission:  "invoices:delete",
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownPermission)
+
+	permissions, err := storage.TableRolesPermissions.GetByRoles(context.Background(), testDB.GetPool(),
+		[]storage.UserRole{
+			{AddUserRole: storage.AddUserRole{ServiceName: "service1112", UserRole: storage.UserRoleTypeUser}},
+			{AddUserRole: storage.AddUserRole{ServiceName: "service2112", UserRole: storage.UserRoleTypeAdmin}},
+		})
+	require.NoError(t, err)
+	require.Len(t, permissions, 1)
+	assert.Equal(t, "invoices:read", permissions[0].Permission)
+
+	// The renamed service keeps the catalogs and the mapping.
+	err = storage.TableServices.Update(context.Background(), testDB.GetPool(),
+		&storage.Service{Name: "service1112r"}, "service1112")
+	require.NoError(t, err)
+
+	permissions, err = storage.TableRolesPermissions.GetByRole(context.Background(), testDB.GetPool(),
+		"service1112r", storage.UserRoleTypeAdmin)
+	require.NoError(t, err)
+	assert.Len(t, permissions, 2)
+
+	// Deleting the permission revokes it from the roles.
+	err = storage.TableServicesPermissions.Delete(context.Background(), testDB.GetPool(), "service1112r",
+		"invoices:write")
+	require.NoError(t, err)
+
+	permissions, err = storage.TableRolesPermissions.GetByRole(context.Background(), testDB.GetPool(),
+		"service1112r", storage.UserRoleTypeAdmin)
+	require.NoError(t, err)
+	require.Len(t, permissions, 1)
+
+	err = storage.TableRolesPermissions.Delete(context.Background(), testDB.GetPool(), "service1112r",
+		storage.UserRoleTypeAdmin, "invoices:read")
+	require.NoError(t, err)
+
+	err = storage.TableRolesPermissions.Delete(context.Background(), testDB.GetPool(), "service1112r",
+		storage.UserRoleTypeAdmin, "invoices:read")
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestRolesHierarchy(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	_ = setupForValidUserGroupsAddAndGet(t, "13")
+
+	implications, err := storage.TableRolesHierarchy.GetByServiceName(context.Background(), testDB.GetPool(),
+		"service1113")
+	require.NoError(t, err)
+	assert.Len(t, implications, len(storage.DefaultRoleHierarchy))
+
+	_, err = storage.TableServicesRoles.Add(context.Background(), testDB.GetPool(),
+		&storage.ServiceRole{ServiceName: "service1113", Name: "editor"})
+	require.NoError(t, err)
+
+	_, err = storage.TableRolesHierarchy.Add(context.Background(), testDB.GetPool(), &storage.RoleImplication{
+		ServiceName: "service1113",
+		UserRole:    "editor",
+		ImpliedRole: storage.UserRoleTypeUser,
+	})
+	require.NoError(t, err)
+
+	_, err = storage.TableRolesHierarchy.Add(context.Background(), testDB.GetPool(), &storage.RoleImplication{
+		ServiceName: "service1113",
+		UserRole:    "editor",
+		ImpliedRole: storage.UserRoleTypeUser,
+	})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	_, err = storage.TableRolesHierarchy.Add(context.Background(), testDB.GetPool(), &storage.RoleImplication{
+		ServiceName: "service1113",
+		UserRole:    "editor",
+		ImpliedRole: "viewer",
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	implications, err = storage.TableRolesHierarchy.GetByServiceNames(context.Background(), testDB.GetPool(),
+		[]string{"service1113", "service2113"})
+	require.NoError(t, err)
+	assert.Len(t, implications, 2*len(storage.DefaultRoleHierarchy)+1)
+
+	// Deleting the role removes it from the hierarchy.
+	err = storage.TableServicesRoles.Delete(context.Background(), testDB.GetPool(), "service1113", "editor")
+	require.NoError(t, err)
+
+	err = storage.TableRolesHierarchy.Delete(context.Background(), testDB.GetPool(), "service1113",
+		"editor", storage.UserRoleTypeUser)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	err = storage.TableRolesHierarchy.Delete(context.Background(), testDB.GetPool(), "service1113",
+		storage.UserRoleTypeRoot, storage.UserRoleTypeAdmin)
+	require.NoError(t, err)
+}
+
+func TestUsersRolesValidity(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "14")
+	userID := idsMap["username1114"]
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	_, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		ValidFrom:   &past,
+		ExpiresAt:   &future,
+		UserID:      userID,
+		UserRole:    storage.UserRoleTypeAdmin,
+		ServiceName: "service1114",
+	})
+	require.NoError(t, err)
+
+	// a pending grant and an expired one.
+	_, err = storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		ValidFrom:   &future,
+		ExpiresAt:   nil,
+		UserID:      userID,
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service3114",
+	})
+	require.NoError(t, err)
+
+	expired, err := storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		ValidFrom:   nil,
+		ExpiresAt:   &past,
+		UserID:      userID,
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service2114",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, expired.ExpiresAt)
+
+	// expires before it becomes valid.
+	_, err = storage.TableUsersRoles.Add(context.Background(), testDB.GetPool(), &storage.AddUserRole{
+		ValidFrom:   &future,
+		ExpiresAt:   &past,
+		UserID:      idsMap["username2114"],
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: "service1114",
+	})
+	require.Error(t, err)
+
+	roles, err := storage.TableUsersRoles.GetByUserID(context.Background(), testDB.GetPool(), userID)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, "service1114", roles[0].ServiceName)
+	assert.NotNil(t, roles[0].ExpiresAt)
+
+	roles, err = storage.TableUsersRoles.GetByUserIDs(context.Background(), testDB.GetPool(), []string{userID})
+	require.NoError(t, err)
+	assert.Len(t, roles, 1)
+
+	// the scheduled and the expired grants are listed too.
+	roles, err = storage.TableUsersRoles.GetAllByUserID(context.Background(), testDB.GetPool(), userID)
+	require.NoError(t, err)
+	assert.Len(t, roles, 3)
+
+	// the users list only sees the grants valid right now.
+	for serviceName, listed := range map[string]bool{"service1114": true, "service2114": false, "service3114": false} {
+		users, err := storage.TableUsers.List(context.Background(), testDB.GetPool(), &storage.UserListFilter{
+			ServiceName: serviceName,
+			Limit:       10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, listed, slices.ContainsFunc(users, func(user storage.User) bool {
+			return user.ID == userID
+		}), serviceName)
+	}
+
+	inService, err := storage.TableUsersRoles.GetByServiceName(context.Background(), testDB.GetPool(), "service2114")
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(inService, func(role storage.UserRole) bool { return role.ID == expired.ID }))
+
+	purged, err := storage.TableUsersRoles.DeleteExpired(context.Background(), testDB.GetPool(), time.Now())
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(purged, func(role storage.UserRole) bool { return role.ID == expired.ID }))
+
+	_, err = storage.TableUsersRoles.GetByID(context.Background(), testDB.GetPool(), expired.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "15")
+	userID := idsMap["username1115"]
+
+	key, err := storage.TableAPIKeys.Add(context.Background(), testDB.GetPool(), &storage.APIKey{
+		UserID:  userID,
+		Name:    "ci",
+		Hint:    "gak_abcdef",
+		KeyHash: strings.Repeat("a", 64),
+		Roles:   []storage.APIKeyRole{{ServiceName: "service1115", UserRole: storage.UserRoleTypeUser}},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.ID)
+
+	// same name of the same user.
+	_, err = storage.TableAPIKeys.Add(context.Background(), testDB.GetPool(), &storage.APIKey{
+		UserID:  userID,
+		Name:    "ci",
+		KeyHash: strings.Repeat("b", 64),
+	})
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	_, err = storage.TableAPIKeys.Add(context.Background(), testDB.GetPool(), &storage.APIKey{
+		UserID:  userID,
+		Name:    "unknown",
+		KeyHash: strings.Repeat("c", 64),
+		Roles:   []storage.APIKeyRole{{ServiceName: "service1115", UserRole: "nonexistent"}},
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	found, err := storage.TableAPIKeys.GetByHash(context.Background(), testDB.GetPool(), strings.Repeat("a", 64))
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, key.Roles, found.Roles)
+	assert.Nil(t, found.LastUsedTS)
+
+	require.NoError(t, storage.TableAPIKeys.UpdateLastUsedByID(context.Background(), testDB.GetPool(), key.ID))
+
+	keys, err := storage.TableAPIKeys.GetByUserID(context.Background(), testDB.GetPool(), userID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedTS)
+
+	// restored as is.
+	restored := storage.APIKey{
+		CreatedTS:  time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond),
+		ExpiresAt:  nil,
+		LastUsedTS: nil,
+		ID:         "0b5e9c3a-15f1-4d5c-9a1e-7c2d8b4f6a15",
+		UserID:     idsMap["username2115"],
+		Name:       "restored",
+		Hint:       "gak_restor",
+		KeyHash:    strings.Repeat("d", 64),
+		Roles:      []storage.APIKeyRole{{ServiceName: "service1115", UserRole: storage.UserRoleTypeAdmin}},
+	}
+	require.NoError(t, storage.TableAPIKeys.Insert(context.Background(), testDB.GetPool(), &restored))
+
+	err = storage.TableAPIKeys.Insert(context.Background(), testDB.GetPool(), &restored)
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	keys, err = storage.TableAPIKeys.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+
+	keyIdx := slices.IndexFunc(keys, func(key storage.APIKey) bool { return key.ID == restored.ID })
+	require.GreaterOrEqual(t, keyIdx, 0)
+	assert.Equal(t, restored.Roles, keys[keyIdx].Roles)
+	assert.Equal(t, restored.KeyHash, keys[keyIdx].KeyHash)
+	assert.True(t, restored.CreatedTS.Equal(keys[keyIdx].CreatedTS))
+
+	// other user's key.
+	err = storage.TableAPIKeys.DeleteByID(context.Background(), testDB.GetPool(), idsMap["username2115"], key.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	require.NoError(t, storage.TableAPIKeys.DeleteByID(context.Background(), testDB.GetPool(), userID, key.ID))
+
+	_, err = storage.TableAPIKeys.GetByHash(context.Background(), testDB.GetPool(), strings.Repeat("a", 64))
+	require.ErrorIs(t, err, database.ErrNoRows)
+}
+
+func TestInvitations(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	idsMap := setupForValidUserGroupsAddAndGet(t, "16")
+	creatorID := idsMap["username1116"]
+
+	invitation, err := storage.TableInvitations.Add(context.Background(), testDB.GetPool(), &storage.Invitation{
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedBy: &creatorID,
+		Roles:     []storage.InvitationRole{{ServiceName: "service1116", UserRole: storage.UserRoleTypeUser}},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, invitation.ID)
+
+	_, err = storage.TableInvitations.Add(context.Background(), testDB.GetPool(), &storage.Invitation{
+		ExpiresAt: time.Now().Add(time.Hour),
+		Roles:     []storage.InvitationRole{{ServiceName: "service1116", UserRole: "nonexistent"}},
+	})
+	require.ErrorIs(t, err, storage.ErrUnknownRole)
+
+	expired, err := storage.TableInvitations.Add(context.Background(), testDB.GetPool(), &storage.Invitation{
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	found, err := storage.TableInvitations.GetByID(context.Background(), testDB.GetPool(), invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, invitation.Roles, found.Roles)
+	require.NotNil(t, found.CreatedBy)
+	assert.Equal(t, creatorID, *found.CreatedBy)
+	assert.Nil(t, found.RedeemedTS)
+
+	all, err := storage.TableInvitations.GetAll(context.Background(), testDB.GetPool())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(all), 2)
+
+	err = storage.TableInvitations.Redeem(context.Background(), testDB.GetPool(), expired.ID, idsMap["username2116"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	require.NoError(t, storage.TableInvitations.Redeem(context.Background(), testDB.GetPool(), invitation.ID,
+		idsMap["username2116"]))
+
+	// redeemed once, and kept.
+	err = storage.TableInvitations.Redeem(context.Background(), testDB.GetPool(), invitation.ID, idsMap["username3116"])
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	err = storage.TableInvitations.DeleteByID(context.Background(), testDB.GetPool(), invitation.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	require.NoError(t, storage.TableInvitations.DeleteByID(context.Background(), testDB.GetPool(), expired.ID))
+
+	_, err = storage.TableInvitations.GetByID(context.Background(), testDB.GetPool(), expired.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	// restored as is.
+	restored := storage.Invitation{
+		CreatedTS:  time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond),
+		ExpiresAt:  time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond),
+		RedeemedTS: nil,
+		CreatedBy:  &creatorID,
+		RedeemedBy: nil,
+		ID:         "4a7c1e2b-16f3-4b8d-9e0a-2c5d7f9b1e16",
+		Roles:      []storage.InvitationRole{{ServiceName: "service1116", UserRole: storage.UserRoleTypeAdmin}},
+	}
+	require.NoError(t, storage.TableInvitations.Insert(context.Background(), testDB.GetPool(), &restored))
+
+	err = storage.TableInvitations.Insert(context.Background(), testDB.GetPool(), &restored)
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	found, err = storage.TableInvitations.GetByID(context.Background(), testDB.GetPool(), restored.ID)
+	require.NoError(t, err)
+	assert.Equal(t, restored.Roles, found.Roles)
+	assert.True(t, restored.CreatedTS.Equal(found.CreatedTS))
+	assert.True(t, restored.ExpiresAt.Equal(found.ExpiresAt))
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	const actor = "auditor17"
+
+	for _, action := range []string{"action1", "action2", "action3"} {
+		require.NoError(t, storage.TableAuditLog.Add(context.Background(), testDB.GetPool(), &storage.AuditEvent{
+			Actor:   actor,
+			Action:  action,
+			Target:  "target17",
+			IP:      "127.0.0.1",
+			Outcome: storage.AuditOutcomeSuccess,
+		}))
+	}
+
+	err := storage.TableAuditLog.Add(context.Background(), testDB.GetPool(), &storage.AuditEvent{
+		Actor:   actor,
+		Action:  "action4",
+		Outcome: "unknown",
+	})
+	require.Error(t, err)
+
+	page, err := storage.TableAuditLog.List(context.Background(), testDB.GetPool(), &storage.AuditEventFilter{
+		Actor: actor,
+		Limit: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "action3", page[0].Action)
+	assert.Equal(t, "action2", page[1].Action)
+	assert.Nil(t, page[0].ActorID)
+
+	page, err = storage.TableAuditLog.List(context.Background(), testDB.GetPool(), &storage.AuditEventFilter{
+		Actor:    actor,
+		BeforeID: &page[1].ID,
+		Limit:    2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "action1", page[0].Action)
+
+	page, err = storage.TableAuditLog.List(context.Background(), testDB.GetPool(), &storage.AuditEventFilter{
+		Actor:  actor,
+		Action: "action2",
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	// append-only.
+	_, err = testDB.GetPool().Exec(context.Background(), `UPDATE "audit_log" SET "target" = '' WHERE "actor" = $1`,
+		actor)
+	require.Error(t, err)
+
+	_, err = testDB.GetPool().Exec(context.Background(), `DELETE FROM "audit_log" WHERE "actor" = $1`, actor)
+	require.Error(t, err)
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel() // Running all db tests in parallel.
+	checkDB(t)
+
+	ctx := context.Background()
+
+	all, err := storage.TableWebhooks.Add(ctx, testDB.GetPool(), &storage.Webhook{
+		URL:    "https://all.example.com/hook",
+		Secret: "secret1",
+		Events: []string{},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, all.ID)
+
+	created, err := storage.TableWebhooks.Add(ctx, testDB.GetPool(), &storage.Webhook{
+		URL:    "https://created.example.com/hook",
+		Secret: "secret2",
+		Events: []string{"test.created"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.TableWebhookDeliveries.Enqueue(ctx, testDB.GetPool(), "test.created", []byte(`{"a":1}`)))
+	require.NoError(t, storage.TableWebhookDeliveries.Enqueue(ctx, testDB.GetPool(), "test.deleted", []byte(`{"a":2}`)))
+
+	claimed, err := storage.TableWebhookDeliveries.Claim(ctx, testDB.GetPool(), 100, time.Minute)
+	require.NoError(t, err)
+
+	claimed = slices.DeleteFunc(claimed, func(delivery storage.WebhookDelivery) bool {
+		return delivery.WebhookID != all.ID && delivery.WebhookID != created.ID
+	})
+	require.Len(t, claimed, 3)
+
+	// the deleted event is the last one and only for the webhook of all events.
+	assert.Equal(t, "test.deleted", claimed[2].EventType)
+	assert.Equal(t, all.ID, claimed[2].WebhookID)
+	assert.Equal(t, all.URL, claimed[2].URL)
+	assert.Equal(t, all.Secret, claimed[2].Secret)
+	assert.JSONEq(t, `{"a":2}`, string(claimed[2].Payload))
+	assert.Equal(t, "test.created", claimed[0].EventType)
+	assert.Equal(t, "test.created", claimed[1].EventType)
+
+	// leased.
+	again, err := storage.TableWebhookDeliveries.Claim(ctx, testDB.GetPool(), 100, time.Minute)
+	require.NoError(t, err)
+
+	for _, delivery := range again {
+		assert.NotEqual(t, all.ID, delivery.WebhookID)
+		assert.NotEqual(t, created.ID, delivery.WebhookID)
+	}
+
+	require.NoError(t, storage.TableWebhookDeliveries.MarkDelivered(ctx, testDB.GetPool(), claimed[0].ID))
+
+	retryAt := time.Now().Add(time.Hour)
+	require.NoError(t, storage.TableWebhookDeliveries.MarkFailed(ctx, testDB.GetPool(), claimed[1].ID, "timeout",
+		&retryAt))
+	require.NoError(t, storage.TableWebhookDeliveries.MarkFailed(ctx, testDB.GetPool(), claimed[2].ID, "refused",
+		nil))
+
+	dead, err := storage.TableWebhookDeliveries.GetDeadByWebhookID(ctx, testDB.GetPool(), all.ID)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "refused", dead[0].LastError)
+	assert.Equal(t, 1, dead[0].Attempts)
+	assert.NotNil(t, dead[0].DeadTS)
+
+	// not a dead letter.
+	err = storage.TableWebhookDeliveries.RetryDead(ctx, testDB.GetPool(), claimed[1].WebhookID, claimed[1].ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	require.NoError(t, storage.TableWebhookDeliveries.RetryDead(ctx, testDB.GetPool(), all.ID, claimed[2].ID))
+
+	dead, err = storage.TableWebhookDeliveries.GetDeadByWebhookID(ctx, testDB.GetPool(), all.ID)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+
+	require.NoError(t, storage.TableWebhooks.DeleteByID(ctx, testDB.GetPool(), all.ID))
+	require.NoError(t, storage.TableWebhooks.DeleteByID(ctx, testDB.GetPool(), created.ID))
+
+	err = storage.TableWebhooks.DeleteByID(ctx, testDB.GetPool(), created.ID)
+	require.ErrorIs(t, err, database.ErrNoRows)
+
+	// restored as is.
+	restored := storage.Webhook{
+		CreatedTS: time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond),
+		CreatedBy: nil,
+		ID:        "9d2f4b6a-18e5-4c7a-8b1d-3e6f0a2c4d18",
+		URL:       "https://restored.example.com/hook",
+		Secret:    "secret3",
+		Events:    []string{"test.restored"},
+	}
+	require.NoError(t, storage.TableWebhooks.Insert(ctx, testDB.GetPool(), &restored))
+
+	err = storage.TableWebhooks.Insert(ctx, testDB.GetPool(), &restored)
+	require.ErrorIs(t, err, database.ErrUniqueKeyViolation)
+
+	webhooks, err := storage.TableWebhooks.GetAll(ctx, testDB.GetPool())
+	require.NoError(t, err)
+
+	webhookIdx := slices.IndexFunc(webhooks, func(webhook storage.Webhook) bool { return webhook.ID == restored.ID })
+	require.GreaterOrEqual(t, webhookIdx, 0)
+	assert.Equal(t, restored.Secret, webhooks[webhookIdx].Secret)
+	assert.Equal(t, restored.Events, webhooks[webhookIdx].Events)
+	assert.True(t, restored.CreatedTS.Equal(webhooks[webhookIdx].CreatedTS))
+
+	require.NoError(t, storage.TableWebhooks.DeleteByID(ctx, testDB.GetPool(), restored.ID))
+}
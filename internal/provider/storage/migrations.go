@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed sql/*.sql
+var embeddedMigrations embed.FS
+
+// Migrations are the embedded sql migrations of the tables.
+func Migrations() fs.FS {
+	migrations, _ := fs.Sub(embeddedMigrations, "sql") // only fails on an invalid path.
+
+	return migrations
+}
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE "users_roles"
+  DROP CONSTRAINT "fk_users_roles_service_name";
+
+ALTER TABLE "users_roles"
+  ADD CONSTRAINT "fk_users_roles_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+COMMIT;
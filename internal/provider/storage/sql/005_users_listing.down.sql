@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX "idx_users_roles_service_name_user_role";
+
+DROP INDEX "idx_users_created_ts_id";
+
+DROP INDEX "idx_users_username_trgm";
+
+ALTER TABLE "users"
+  DROP COLUMN "created_ts";
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+-- the users existing before the migration get the migration timestamp.
+ALTER TABLE "users"
+  ADD COLUMN "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- serves both prefix and substring username search.
+CREATE INDEX "idx_users_username_trgm"
+  ON "users" USING GIN ("username" gin_trgm_ops);
+
+CREATE INDEX "idx_users_created_ts_id"
+  ON "users" ("created_ts", "id");
+
+CREATE INDEX "idx_users_roles_service_name_user_role"
+  ON "users_roles" ("service_name", "user_role");
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE "users"
+  DROP COLUMN "status_changed_ts";
+
+ALTER TABLE "users"
+  DROP COLUMN "status_reason";
+
+ALTER TABLE "users"
+  DROP COLUMN "status";
+
+DROP TYPE user_status_type;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE TYPE user_status_type AS ENUM (
+  'active',
+  'disabled',
+  'locked'
+);
+
+ALTER TABLE "users"
+  ADD COLUMN "status" user_status_type NOT NULL DEFAULT 'active';
+
+ALTER TABLE "users"
+  ADD COLUMN "status_reason" VARCHAR(200) NOT NULL DEFAULT '';
+
+-- NULL - the status was never changed.
+ALTER TABLE "users"
+  ADD COLUMN "status_changed_ts" TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX "idx_users_deleted_ts";
+
+ALTER TABLE "users"
+  DROP COLUMN "deleted_ts";
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- NULL - the user is not deleted.
+ALTER TABLE "users"
+  ADD COLUMN "deleted_ts" TIMESTAMPTZ;
+
+CREATE INDEX "idx_users_deleted_ts"
+  ON "users" ("deleted_ts")
+  WHERE "deleted_ts" IS NOT NULL;
+
+COMMIT;
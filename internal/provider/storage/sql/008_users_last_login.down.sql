@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE "users"
+  DROP COLUMN "last_login_ts";
+
+COMMIT;
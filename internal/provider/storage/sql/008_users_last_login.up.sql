@@ -0,0 +1,7 @@
+BEGIN;
+
+-- NULL - the user has never logged in.
+ALTER TABLE "users"
+  ADD COLUMN "last_login_ts" TIMESTAMPTZ;
+
+COMMIT;
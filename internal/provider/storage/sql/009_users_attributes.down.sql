@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE "users"
+  DROP COLUMN "attributes";
+
+DROP TABLE "attribute_definitions";
+
+DROP TYPE attribute_value_type;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE TYPE attribute_value_type AS ENUM (
+  'string',
+  'number',
+  'boolean'
+);
+
+-- The schema of the users' attributes, configured by the admins.
+CREATE TABLE "attribute_definitions" (
+  "name" VARCHAR(40) PRIMARY KEY,
+  "value_type" attribute_value_type NOT NULL,
+  "max_length" INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE "users"
+  ADD COLUMN "attributes" JSONB NOT NULL DEFAULT '{}';
+
+COMMIT;
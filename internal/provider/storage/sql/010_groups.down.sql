@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE "groups_users";
+
+DROP TABLE "groups_roles";
+
+DROP TABLE "groups";
+
+COMMIT;
@@ -0,0 +1,50 @@
+BEGIN;
+
+CREATE TABLE "groups" (
+  "name" VARCHAR(40) PRIMARY KEY,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The roles granted to every member of the group.
+CREATE TABLE "groups_roles" (
+  "id" SERIAL PRIMARY KEY,
+  "group_name" VARCHAR(40) NOT NULL,
+  "user_role" user_role_type NOT NULL,
+  "service_name" VARCHAR(100) NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT "fk_groups_roles_group_name"
+    FOREIGN KEY ("group_name") REFERENCES "groups"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+
+  CONSTRAINT "fk_groups_roles_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+
+  CONSTRAINT "uk_groups_roles_group_name_service_name"
+    UNIQUE ("group_name", "service_name")
+);
+
+CREATE TABLE "groups_users" (
+  "group_name" VARCHAR(40) NOT NULL,
+  "user_id" UUID NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY ("group_name", "user_id"),
+
+  CONSTRAINT "fk_groups_users_group_name"
+    FOREIGN KEY ("group_name") REFERENCES "groups"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+
+  CONSTRAINT "fk_groups_users_user_id"
+    FOREIGN KEY ("user_id") REFERENCES "users"("id")
+    ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_groups_users_user_id"
+  ON "groups_users" ("user_id");
+
+COMMIT;
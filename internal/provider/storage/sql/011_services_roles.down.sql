@@ -0,0 +1,42 @@
+BEGIN;
+
+CREATE TYPE user_role_type AS ENUM (
+  'root',
+  'admin',
+  'user'
+);
+
+-- The custom roles can't be represented by the enum and are revoked.
+DELETE FROM "users_roles"
+WHERE "user_role" NOT IN ('root', 'admin', 'user');
+
+DELETE FROM "groups_roles"
+WHERE "user_role" NOT IN ('root', 'admin', 'user');
+
+ALTER TABLE "users_roles"
+  DROP CONSTRAINT "fk_users_roles_service_role";
+
+ALTER TABLE "users_roles"
+  ALTER COLUMN "user_role" TYPE user_role_type USING "user_role"::user_role_type;
+
+ALTER TABLE "users_roles"
+  ADD CONSTRAINT "fk_users_roles_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+ALTER TABLE "groups_roles"
+  DROP CONSTRAINT "fk_groups_roles_service_role";
+
+ALTER TABLE "groups_roles"
+  ALTER COLUMN "user_role" TYPE user_role_type USING "user_role"::user_role_type;
+
+ALTER TABLE "groups_roles"
+  ADD CONSTRAINT "fk_groups_roles_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+DROP TABLE "services_roles";
+
+COMMIT;
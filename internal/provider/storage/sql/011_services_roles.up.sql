@@ -0,0 +1,55 @@
+BEGIN;
+
+-- The roles catalog of the services, replaces the fixed user_role_type.
+CREATE TABLE "services_roles" (
+  "service_name" VARCHAR(100) NOT NULL,
+  "name" VARCHAR(40) NOT NULL,
+  "description" VARCHAR(200) NOT NULL DEFAULT '',
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY ("service_name", "name"),
+
+  CONSTRAINT "fk_services_roles_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+);
+
+-- The existing services keep the formerly fixed roles, so the granted roles stay valid.
+INSERT INTO "services_roles"
+  ("service_name",
+  "name")
+SELECT
+  "services"."name",
+  "roles"."name"
+FROM "services"
+CROSS JOIN (VALUES ('root'), ('admin'), ('user')) AS "roles"("name");
+
+ALTER TABLE "users_roles"
+  ALTER COLUMN "user_role" TYPE VARCHAR(40) USING "user_role"::TEXT;
+
+-- The roles now reference the catalog, which in turn references the service.
+ALTER TABLE "users_roles"
+  DROP CONSTRAINT "fk_users_roles_service_name";
+
+ALTER TABLE "users_roles"
+  ADD CONSTRAINT "fk_users_roles_service_role"
+    FOREIGN KEY ("service_name", "user_role") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+ALTER TABLE "groups_roles"
+  ALTER COLUMN "user_role" TYPE VARCHAR(40) USING "user_role"::TEXT;
+
+ALTER TABLE "groups_roles"
+  DROP CONSTRAINT "fk_groups_roles_service_name";
+
+ALTER TABLE "groups_roles"
+  ADD CONSTRAINT "fk_groups_roles_service_role"
+    FOREIGN KEY ("service_name", "user_role") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+DROP TYPE user_role_type;
+
+COMMIT;
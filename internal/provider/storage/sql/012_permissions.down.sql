@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE "services_roles_permissions";
+
+DROP TABLE "services_permissions";
+
+COMMIT;
@@ -0,0 +1,65 @@
+BEGIN;
+
+-- The permissions catalog of the services.
+CREATE TABLE "services_permissions" (
+  "service_name" VARCHAR(100) NOT NULL,
+  "name" VARCHAR(100) NOT NULL,
+  "description" VARCHAR(200) NOT NULL DEFAULT '',
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY ("service_name", "name"),
+
+  CONSTRAINT "fk_services_permissions_service_name"
+    FOREIGN KEY ("service_name") REFERENCES "services"("name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+);
+
+-- The permissions granted by the roles. A service rename reaches the table
+-- through both of the catalogs, so the checks are deferred until both are renamed.
+CREATE TABLE "services_roles_permissions" (
+  "service_name" VARCHAR(100) NOT NULL,
+  "role_name" VARCHAR(40) NOT NULL,
+  "permission_name" VARCHAR(100) NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY ("service_name", "role_name", "permission_name"),
+
+  CONSTRAINT "fk_services_roles_permissions_role"
+    FOREIGN KEY ("service_name", "role_name") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+    DEFERRABLE INITIALLY DEFERRED,
+
+  CONSTRAINT "fk_services_roles_permissions_permission"
+    FOREIGN KEY ("service_name", "permission_name") REFERENCES "services_permissions"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+    DEFERRABLE INITIALLY DEFERRED
+);
+
+-- The permissions checked on the go-auth /manage routes.
+INSERT INTO "services_permissions"
+  ("service_name",
+  "name",
+  "description")
+SELECT
+  "services"."name",
+  "permissions"."name",
+  "permissions"."description"
+FROM "services"
+CROSS JOIN (VALUES
+  ('users:read', 'look up the users, their roles and attributes'),
+  ('users:write', 'create, import, change and delete the users'),
+  ('roles:write', 'grant and revoke the roles of the users and the groups'),
+  ('services:read', 'look up the services'),
+  ('services:write', 'manage the services, their roles and permissions'),
+  ('groups:read', 'look up the groups and their members'),
+  ('groups:write', 'manage the groups and their members'),
+  ('attributes:read', 'look up the attributes schema'),
+  ('attributes:write', 'manage the attributes schema'),
+  ('state:export', 'dump the full state')
+) AS "permissions"("name", "description")
+WHERE "services"."name" = 'go-auth';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE "services_roles_hierarchy";
+
+COMMIT;
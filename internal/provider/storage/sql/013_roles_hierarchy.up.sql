@@ -0,0 +1,45 @@
+BEGIN;
+
+-- The roles implied by a role of the service, a user with the role has the implied ones too.
+-- A service rename reaches the table through both of the roles, so the checks are deferred.
+CREATE TABLE "services_roles_hierarchy" (
+  "service_name" VARCHAR(100) NOT NULL,
+  "role_name" VARCHAR(40) NOT NULL,
+  "implied_role_name" VARCHAR(40) NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY ("service_name", "role_name", "implied_role_name"),
+
+  CONSTRAINT "ck_services_roles_hierarchy_not_self"
+    CHECK ("role_name" <> "implied_role_name"),
+
+  CONSTRAINT "fk_services_roles_hierarchy_role"
+    FOREIGN KEY ("service_name", "role_name") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+    DEFERRABLE INITIALLY DEFERRED,
+
+  CONSTRAINT "fk_services_roles_hierarchy_implied_role"
+    FOREIGN KEY ("service_name", "implied_role_name") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+    DEFERRABLE INITIALLY DEFERRED
+);
+
+-- The default hierarchy: root implies admin, admin implies user.
+INSERT INTO "services_roles_hierarchy"
+  ("service_name",
+  "role_name",
+  "implied_role_name")
+SELECT
+  "roles"."service_name",
+  "roles"."name",
+  "defaults"."implied_role_name"
+FROM "services_roles" AS "roles"
+JOIN (VALUES ('root', 'admin'), ('admin', 'user')) AS "defaults"("role_name", "implied_role_name")
+  ON "defaults"."role_name" = "roles"."name"
+JOIN "services_roles" AS "implied"
+  ON "implied"."service_name" = "roles"."service_name"
+  AND "implied"."name" = "defaults"."implied_role_name";
+
+COMMIT;
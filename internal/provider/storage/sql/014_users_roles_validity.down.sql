@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX "idx_users_roles_expires_at";
+
+ALTER TABLE "users_roles"
+  DROP CONSTRAINT "ck_users_roles_validity",
+  DROP COLUMN "valid_from",
+  DROP COLUMN "expires_at";
+
+COMMIT;
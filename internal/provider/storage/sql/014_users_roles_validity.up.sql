@@ -0,0 +1,13 @@
+BEGIN;
+
+-- NULL - the grant is valid since it was made, and never expires.
+ALTER TABLE "users_roles"
+  ADD COLUMN "valid_from" TIMESTAMPTZ,
+  ADD COLUMN "expires_at" TIMESTAMPTZ,
+  ADD CONSTRAINT "ck_users_roles_validity"
+    CHECK ("valid_from" IS NULL OR "expires_at" IS NULL OR "valid_from" < "expires_at");
+
+CREATE INDEX "idx_users_roles_expires_at" ON "users_roles" ("expires_at")
+  WHERE "expires_at" IS NOT NULL;
+
+COMMIT;
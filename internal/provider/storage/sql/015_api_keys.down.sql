@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE "api_keys_roles";
+
+DROP TABLE "api_keys";
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+-- The users' API keys. Only the sha256 of a key is kept, the hint is its beginning shown in the lists.
+CREATE TABLE "api_keys" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" UUID NOT NULL,
+  "name" VARCHAR(40) NOT NULL,
+  "hint" VARCHAR(20) NOT NULL,
+  "key_hash" CHAR(64) NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "expires_at" TIMESTAMPTZ,
+  "last_used_ts" TIMESTAMPTZ,
+
+  CONSTRAINT "fk_api_keys_user_id"
+    FOREIGN KEY ("user_id") REFERENCES "users"("id")
+    ON DELETE CASCADE,
+
+  CONSTRAINT "uk_api_keys_user_id_name"
+    UNIQUE ("user_id", "name"),
+
+  CONSTRAINT "uk_api_keys_key_hash"
+    UNIQUE ("key_hash")
+);
+
+-- The roles the key is scoped to. The key only carries the ones its owner still has.
+CREATE TABLE "api_keys_roles" (
+  "api_key_id" UUID NOT NULL,
+  "service_name" VARCHAR(100) NOT NULL,
+  "user_role" VARCHAR(40) NOT NULL,
+
+  PRIMARY KEY ("api_key_id", "service_name", "user_role"),
+
+  CONSTRAINT "fk_api_keys_roles_api_key_id"
+    FOREIGN KEY ("api_key_id") REFERENCES "api_keys"("id")
+    ON DELETE CASCADE,
+
+  CONSTRAINT "fk_api_keys_roles_service_role"
+    FOREIGN KEY ("service_name", "user_role") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+);
+
+COMMIT;
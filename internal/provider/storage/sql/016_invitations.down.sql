@@ -0,0 +1,11 @@
+BEGIN;
+
+DELETE FROM "services_permissions"
+WHERE "service_name" = 'go-auth'
+  AND "name" IN ('invitations:read', 'invitations:write');
+
+DROP TABLE "invitations_roles";
+
+DROP TABLE "invitations";
+
+COMMIT;
@@ -0,0 +1,54 @@
+BEGIN;
+
+-- The invitations to sign up. The invite token only names the invitation, so removing one revokes its token.
+CREATE TABLE "invitations" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "created_by" UUID,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "expires_at" TIMESTAMPTZ NOT NULL,
+  "redeemed_by" UUID,
+  "redeemed_ts" TIMESTAMPTZ,
+
+  CONSTRAINT "fk_invitations_created_by"
+    FOREIGN KEY ("created_by") REFERENCES "users"("id")
+    ON DELETE SET NULL,
+
+  CONSTRAINT "fk_invitations_redeemed_by"
+    FOREIGN KEY ("redeemed_by") REFERENCES "users"("id")
+    ON DELETE SET NULL
+);
+
+-- The roles granted to the invitee on redeem.
+CREATE TABLE "invitations_roles" (
+  "invitation_id" UUID NOT NULL,
+  "service_name" VARCHAR(100) NOT NULL,
+  "user_role" VARCHAR(40) NOT NULL,
+
+  PRIMARY KEY ("invitation_id", "service_name", "user_role"),
+
+  CONSTRAINT "fk_invitations_roles_invitation_id"
+    FOREIGN KEY ("invitation_id") REFERENCES "invitations"("id")
+    ON DELETE CASCADE,
+
+  CONSTRAINT "fk_invitations_roles_service_role"
+    FOREIGN KEY ("service_name", "user_role") REFERENCES "services_roles"("service_name", "name")
+    ON DELETE CASCADE
+    ON UPDATE CASCADE
+);
+
+INSERT INTO "services_permissions"
+  ("service_name",
+  "name",
+  "description")
+SELECT
+  "services"."name",
+  "permissions"."name",
+  "permissions"."description"
+FROM "services"
+CROSS JOIN (VALUES
+  ('invitations:read', 'look up the invitations'),
+  ('invitations:write', 'invite the users with pre-assigned roles and revoke the invitations')
+) AS "permissions"("name", "description")
+WHERE "services"."name" = 'go-auth';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE "audit_log";
+
+DROP FUNCTION "audit_log_append_only";
+
+COMMIT;
@@ -0,0 +1,42 @@
+BEGIN;
+
+-- The append-only audit log. The actor and the target are kept as text, so the events outlive
+-- the users and the services they name.
+CREATE TABLE "audit_log" (
+  "id" BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "actor_id" UUID,
+  "actor" VARCHAR(100) NOT NULL DEFAULT '',
+  "action" VARCHAR(200) NOT NULL,
+  "target" VARCHAR(500) NOT NULL DEFAULT '',
+  "ip" VARCHAR(100) NOT NULL DEFAULT '',
+  "user_agent" VARCHAR(500) NOT NULL DEFAULT '',
+  "outcome" VARCHAR(20) NOT NULL,
+  "details" VARCHAR(1000) NOT NULL DEFAULT '',
+
+  CONSTRAINT "ck_audit_log_outcome"
+    CHECK ("outcome" IN ('success', 'failure', 'denied'))
+);
+
+CREATE INDEX "idx_audit_log_created_ts" ON "audit_log" ("created_ts");
+
+CREATE INDEX "idx_audit_log_actor" ON "audit_log" ("actor");
+
+CREATE INDEX "idx_audit_log_action" ON "audit_log" ("action");
+
+-- The audit log is never changed, only appended to.
+CREATE FUNCTION "audit_log_append_only"() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'the audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "trg_audit_log_append_only"
+  BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION "audit_log_append_only"();
+
+CREATE TRIGGER "trg_audit_log_no_truncate"
+  BEFORE TRUNCATE ON "audit_log"
+  FOR EACH STATEMENT EXECUTE FUNCTION "audit_log_append_only"();
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+DELETE FROM "services_permissions"
+WHERE "service_name" = 'go-auth'
+  AND "name" IN ('webhooks:read', 'webhooks:write');
+
+DROP VIEW "webhook_dead_letters";
+
+DROP TABLE "webhook_deliveries";
+
+DROP TABLE "webhooks";
+
+COMMIT;
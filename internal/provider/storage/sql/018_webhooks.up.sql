@@ -0,0 +1,71 @@
+BEGIN;
+
+-- The webhook subscriptions. The secret signs the deliveries, so it is kept as is.
+-- No events subscribe to every event.
+CREATE TABLE "webhooks" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "url" VARCHAR(2000) NOT NULL,
+  "secret" VARCHAR(100) NOT NULL,
+  "events" VARCHAR(100)[] NOT NULL DEFAULT '{}',
+  "created_by" UUID,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT "fk_webhooks_created_by"
+    FOREIGN KEY ("created_by") REFERENCES "users"("id")
+    ON DELETE SET NULL
+);
+
+-- The outbox of the webhooks. The deliveries are added in the transaction of the change,
+-- and are pending until delivered, or dead after the last failed attempt.
+CREATE TABLE "webhook_deliveries" (
+  "id" BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  "webhook_id" UUID NOT NULL,
+  "event_type" VARCHAR(100) NOT NULL,
+  "payload" JSONB NOT NULL,
+  "created_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "attempts" INTEGER NOT NULL DEFAULT 0,
+  "next_attempt_ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "last_error" VARCHAR(1000) NOT NULL DEFAULT '',
+  "delivered_ts" TIMESTAMPTZ,
+  "dead_ts" TIMESTAMPTZ,
+
+  CONSTRAINT "fk_webhook_deliveries_webhook_id"
+    FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id")
+    ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_webhook_deliveries_pending" ON "webhook_deliveries" ("next_attempt_ts")
+  WHERE "delivered_ts" IS NULL AND "dead_ts" IS NULL;
+
+CREATE INDEX "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
+
+-- The deliveries given up on.
+CREATE VIEW "webhook_dead_letters" AS
+SELECT
+  "id",
+  "webhook_id",
+  "event_type",
+  "payload",
+  "created_ts",
+  "attempts",
+  "last_error",
+  "dead_ts"
+FROM "webhook_deliveries"
+WHERE "dead_ts" IS NOT NULL;
+
+INSERT INTO "services_permissions"
+  ("service_name",
+  "name",
+  "description")
+SELECT
+  "services"."name",
+  "permissions"."name",
+  "permissions"."description"
+FROM "services"
+CROSS JOIN (VALUES
+  ('webhooks:read', 'look up the webhooks and their dead letters'),
+  ('webhooks:write', 'subscribe the webhooks to the events, remove them and retry their dead letters')
+) AS "permissions"("name", "description")
+WHERE "services"."name" = 'go-auth';
+
+COMMIT;
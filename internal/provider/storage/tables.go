@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/eldarbr/go-auth/pkg/database"
@@ -12,31 +13,149 @@ func init() {
 	TableUsers = implTableUsers{}
 	TableServices = implTableServices{}
 	TableUsersRoles = implTableUsersRoles{}
+	TableAttributeDefinitions = implTableAttributeDefinitions{}
+	TableGroups = implTableGroups{}
+	TableGroupsRoles = implTableGroupsRoles{}
+	TableGroupsUsers = implTableGroupsUsers{}
+	TableServicesRoles = implTableServicesRoles{}
+	TableServicesPermissions = implTableServicesPermissions{}
+	TableRolesPermissions = implTableRolesPermissions{}
+	TableRolesHierarchy = implTableRolesHierarchy{}
+	TableAPIKeys = implTableAPIKeys{}
+	TableInvitations = implTableInvitations{}
+	TableAuditLog = implTableAuditLog{}
+	TableWebhooks = implTableWebhooks{}
+	TableWebhookDeliveries = implTableWebhookDeliveries{}
 }
 
 type UserRoleType = string
 
+// The roles every service is created with. Other roles are defined per service in its roles catalog.
 const (
 	UserRoleTypeRoot  UserRoleType = "root"
 	UserRoleTypeAdmin UserRoleType = "admin"
 	UserRoleTypeUser  UserRoleType = "user"
 )
 
+var DefaultServiceRoles = []UserRoleType{UserRoleTypeRoot, UserRoleTypeAdmin, UserRoleTypeUser}
+
+// DefaultRoleHierarchy is the hierarchy every service is created with: root implies admin, admin implies user.
+var DefaultRoleHierarchy = []RoleImplication{
+	{UserRole: UserRoleTypeRoot, ImpliedRole: UserRoleTypeAdmin}, //nolint:exhaustruct // the defaults.
+	{UserRole: UserRoleTypeAdmin, ImpliedRole: UserRoleTypeUser}, //nolint:exhaustruct // the defaults.
+}
+
+var (
+	// ErrUnknownRole is returned when a granted role is not defined in the service's roles catalog.
+	ErrUnknownRole = errors.New("the role is not defined in the service")
+	// ErrUnknownPermission is returned when a granted permission is not defined in the service's
+	// permissions catalog.
+	ErrUnknownPermission = errors.New("the permission is not defined in the service")
+)
+
+type UserStatusType = string
+
+const (
+	UserStatusTypeActive   UserStatusType = "active"
+	UserStatusTypeDisabled UserStatusType = "disabled"
+	UserStatusTypeLocked   UserStatusType = "locked"
+)
+
 type AddUser struct {
 	Username string
 	Password string
 }
 
+type UserStatus struct {
+	Status UserStatusType
+	Reason string
+}
+
 type User struct {
+	CreatedTS       time.Time
+	StatusChangedTS *time.Time
+	DeletedTS       *time.Time
+	LastLoginTS     *time.Time
+	Attributes      map[string]any
 	AddUser
-	ID string
+	ID           string
+	Status       UserStatusType
+	StatusReason string
+}
+
+const (
+	UserListSortUsername  = "username"
+	UserListSortCreatedTS = "created_ts"
+)
+
+const (
+	UserListSearchPrefix    = "prefix"
+	UserListSearchSubstring = "substring"
+)
+
+// UserListCursor points at the last user of the previous page.
+// SortValue holds the value of the sorting column in text form.
+type UserListCursor struct {
+	SortValue string
+	ID        string
+}
+
+// UserListFilter describes a page of the users list.
+// Empty fields do not filter. Deleted switches the list to the soft deleted users.
+type UserListFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	After         *UserListCursor
+	ServiceName   string
+	UserRole      UserRoleType
+	Search        string
+	SearchMode    string
+	SortBy        string
+	Limit         int
+	SortDesc      bool
+	Deleted       bool
 }
 
 type Service struct {
 	Name string
 }
 
+// ServiceRole is a role defined in the service's roles catalog.
+type ServiceRole struct {
+	CreatedTS   time.Time
+	ServiceName string
+	Name        UserRoleType
+	Description string
+}
+
+// RoleImplication tells that a user with the role of the service has the implied role too.
+type RoleImplication struct {
+	CreatedTS   time.Time
+	ServiceName string
+	UserRole    UserRoleType
+	ImpliedRole UserRoleType
+}
+
+// ServicePermission is a permission defined in the service's permissions catalog.
+type ServicePermission struct {
+	CreatedTS   time.Time
+	ServiceName string
+	Name        string
+	Description string
+}
+
+// RolePermission is a permission granted by the role of the service.
+type RolePermission struct {
+	CreatedTS   time.Time
+	ServiceName string
+	UserRole    UserRoleType
+	Permission  string
+}
+
+// AddUserRole is a role grant. A nil ValidFrom makes it valid at once, a nil ExpiresAt - forever.
 type AddUserRole struct {
+	ValidFrom   *time.Time
+	ExpiresAt   *time.Time
 	UserID      string
 	UserRole    UserRoleType
 	ServiceName string
@@ -48,15 +167,174 @@ type UserRole struct {
 	ID uint
 }
 
-type GroupUser struct {
+// ServiceMember is a role granted in a service, along with the user's username.
+type ServiceMember struct {
+	Username string
+	UserRole
+}
+
+// APIKey is a user's API key, scoped to the Roles. Only the hash of the key is kept.
+type APIKey struct {
+	CreatedTS  time.Time
+	ExpiresAt  *time.Time
+	LastUsedTS *time.Time
+	ID         string
+	UserID     string
+	Name       string
+	Hint       string
+	KeyHash    string
+	Roles      []APIKeyRole
+}
+
+type APIKeyRole struct {
+	ServiceName string
+	UserRole    UserRoleType
+}
+
+// Invitation lets the invitee sign up with the Roles granted. It is pending until redeemed or expired.
+type Invitation struct {
+	CreatedTS  time.Time
+	ExpiresAt  time.Time
+	RedeemedTS *time.Time
+	CreatedBy  *string
+	RedeemedBy *string
+	ID         string
+	Roles      []InvitationRole
+}
+
+type InvitationRole struct {
+	ServiceName string
+	UserRole    UserRoleType
+}
+
+// The outcomes of the audited events. Denied is a refused authorization, failure is any other failure.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeDenied  = "denied"
+)
+
+// AuditEvent is an entry of the append-only audit log. The Actor is the username of who made the event,
+// empty if unknown, the ActorID is set if the actor is a known user.
+type AuditEvent struct {
+	CreatedTS time.Time
+	ActorID   *string
+	Actor     string
+	Action    string
+	Target    string
+	IP        string
+	UserAgent string
+	Outcome   string
+	Details   string
+	ID        int64
+}
+
+// Webhook is a subscription to the events, no Events subscribe to every event.
+// The Secret signs the deliveries.
+type Webhook struct {
+	CreatedTS time.Time
+	CreatedBy *string
+	ID        string
+	URL       string
+	Secret    string
+	Events    []string
+}
+
+// WebhookDelivery is an event in the outbox of a webhook. It is pending until delivered,
+// or dead after the last failed attempt. The URL and the Secret of the webhook are set by Claim only.
+type WebhookDelivery struct {
+	CreatedTS     time.Time
+	NextAttemptTS time.Time
+	DeliveredTS   *time.Time
+	DeadTS        *time.Time
+	WebhookID     string
+	EventType     string
+	LastError     string
+	URL           string
+	Secret        string
+	Payload       []byte
+	ID            int64
+	Attempts      int
+}
+
+// AuditEventFilter describes a page of the audit log, the newest events first.
+// Empty fields do not filter. BeforeID is the keyset cursor, the ID of the last event of the previous page.
+type AuditEventFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	BeforeID      *int64
+	Actor         string
+	Action        string
+	Target        string
+	Outcome       string
+	IP            string
+	Limit         int
+}
+
+type AttributeValueType = string
+
+const (
+	AttributeValueTypeString  AttributeValueType = "string"
+	AttributeValueTypeNumber  AttributeValueType = "number"
+	AttributeValueTypeBoolean AttributeValueType = "boolean"
+)
+
+// AttributeDefinition describes a user attribute. MaxLength only limits the string values.
+type AttributeDefinition struct {
+	Name      string
+	ValueType AttributeValueType
+	MaxLength int
+}
+
+type Group struct {
+	CreatedTS time.Time
+	Name      string
+}
+
+type AddGroupRole struct {
+	GroupName   string
+	UserRole    UserRoleType
+	ServiceName string
+}
+
+// GroupRole is a role granted to every member of the group.
+type GroupRole struct {
+	CreatedTS time.Time
+	AddGroupRole
+	ID uint
+}
+
+// UserGroupRole is a role the user has as a member of the group.
+type UserGroupRole struct {
+	UserID string
+	GroupRole
+}
+
+// GroupMember is a membership of the user in the group, along with the user's username.
+type GroupMember struct {
+	CreatedTS time.Time
 	GroupName string
+	UserID    string
 	Username  string
 }
 
 var TableUsers interface {
 	Add(ctx context.Context, database database.Querier, user *AddUser) (*User, error)
+	Insert(ctx context.Context, database database.Querier, user *User) error
 	UpdateByUsername(ctx context.Context, database database.Querier, user *AddUser, username string) error
 	GetByUsername(ctx context.Context, database database.Querier, username string) (*User, error)
+	GetByID(ctx context.Context, database database.Querier, userID string) (*User, error)
+	List(ctx context.Context, database database.Querier, filter *UserListFilter) ([]User, error)
+	GetAll(ctx context.Context, database database.Querier) ([]User, error)
+	CountAll(ctx context.Context, database database.Querier) (int, error)
+	UpdateStatusByID(ctx context.Context, database database.Querier, status *UserStatus, userID string) error
+	UpdateLastLoginByID(ctx context.Context, database database.Querier, userID string) error
+	MergeAttributesByID(ctx context.Context, database database.Querier, attributes map[string]any,
+		userID string) (map[string]any, error)
+	DeleteAttribute(ctx context.Context, database database.Querier, name string) (int64, error)
+	SoftDeleteByID(ctx context.Context, database database.Querier, userID string) error
+	RestoreByID(ctx context.Context, database database.Querier, userID string) error
+	PurgeDeleted(ctx context.Context, database database.Querier, deletedBefore time.Time) (int64, error)
 	DeleteByUsername(ctx context.Context, database database.Querier, username string) error
 }
 
@@ -64,14 +342,130 @@ var TableServices interface {
 	Add(ctx context.Context, database database.Querier, service *Service) error
 	Update(ctx context.Context, database database.Querier, service *Service, serviceName string) error
 	GetByServiceName(ctx context.Context, database database.Querier, serviceName string) (*Service, error)
+	GetAll(ctx context.Context, database database.Querier) ([]Service, error)
 	Delete(ctx context.Context, database database.Querier, serviceName string) error
 }
 
+var TableServicesRoles interface {
+	Add(ctx context.Context, database database.Querier, role *ServiceRole) (*ServiceRole, error)
+	AddDefaults(ctx context.Context, database database.Querier, serviceName string) error
+	GetByServiceName(ctx context.Context, database database.Querier, serviceName string) ([]ServiceRole, error)
+	GetAll(ctx context.Context, database database.Querier) ([]ServiceRole, error)
+	CountGrants(ctx context.Context, database database.Querier, serviceName, roleName string) (int, error)
+	CountScopes(ctx context.Context, database database.Querier, serviceName, roleName string) (int, error)
+	LockGrants(ctx context.Context, database database.Querier) error
+	Delete(ctx context.Context, database database.Querier, serviceName, roleName string) error
+}
+
+var TableRolesHierarchy interface {
+	Add(ctx context.Context, database database.Querier, implication *RoleImplication) (*RoleImplication, error)
+	GetByServiceName(ctx context.Context, database database.Querier, serviceName string) ([]RoleImplication, error)
+	GetByServiceNames(ctx context.Context, database database.Querier, serviceNames []string) ([]RoleImplication, error)
+	GetAll(ctx context.Context, database database.Querier) ([]RoleImplication, error)
+	Delete(ctx context.Context, database database.Querier, serviceName, roleName, impliedRoleName string) error
+}
+
+var TableServicesPermissions interface {
+	Add(ctx context.Context, database database.Querier, permission *ServicePermission) (*ServicePermission, error)
+	GetByServiceName(ctx context.Context, database database.Querier, serviceName string) ([]ServicePermission, error)
+	GetAll(ctx context.Context, database database.Querier) ([]ServicePermission, error)
+	Delete(ctx context.Context, database database.Querier, serviceName, name string) error
+}
+
+var TableRolesPermissions interface {
+	Add(ctx context.Context, database database.Querier, rolePermission *RolePermission) (*RolePermission, error)
+	GetByRole(ctx context.Context, database database.Querier, serviceName, roleName string) ([]RolePermission, error)
+	GetByRoles(ctx context.Context, database database.Querier, roles []UserRole) ([]RolePermission, error)
+	GetAll(ctx context.Context, database database.Querier) ([]RolePermission, error)
+	Delete(ctx context.Context, database database.Querier, serviceName, roleName, permission string) error
+}
+
 var TableUsersRoles interface {
 	Add(ctx context.Context, database database.Querier, useRole *AddUserRole) (*UserRole, error)
 	Insert(ctx context.Context, database database.Querier, useRole *UserRole) error
 	UpdateByID(ctx context.Context, database database.Querier, useRole *UserRole, dbEntryID uint) error
 	GetByUserID(ctx context.Context, database database.Querier, userID string) ([]UserRole, error)
+	GetAllByUserID(ctx context.Context, database database.Querier, userID string) ([]UserRole, error)
+	GetByUserIDs(ctx context.Context, database database.Querier, userIDs []string) ([]UserRole, error)
+	GetAll(ctx context.Context, database database.Querier) ([]UserRole, error)
 	GetByID(ctx context.Context, database database.Querier, dbEntryID uint) (*UserRole, error)
+	GetByUserIDAndServiceName(ctx context.Context, database database.Querier, userID,
+		serviceName string) (*UserRole, error)
+	GetMembersByServiceName(ctx context.Context, database database.Querier, serviceName string) ([]ServiceMember, error)
+	GetByServiceName(ctx context.Context, database database.Querier, serviceName string) ([]UserRole, error)
+	CountByServiceName(ctx context.Context, database database.Querier, serviceName string) (int, error)
 	DeleteByID(ctx context.Context, database database.Querier, dbEntryID uint) error
+	DeleteExpired(ctx context.Context, database database.Querier, expiredBefore time.Time) ([]UserRole, error)
+	DeleteByDeletedUsers(ctx context.Context, database database.Querier, deletedBefore time.Time) ([]UserRole, error)
+	ResetIDSequence(ctx context.Context, database database.Querier) error
+}
+
+var TableAttributeDefinitions interface {
+	Add(ctx context.Context, database database.Querier, definition *AttributeDefinition) error
+	GetAll(ctx context.Context, database database.Querier) ([]AttributeDefinition, error)
+	Delete(ctx context.Context, database database.Querier, name string) error
+}
+
+var TableGroups interface {
+	Add(ctx context.Context, database database.Querier, groupName string) (*Group, error)
+	GetAll(ctx context.Context, database database.Querier) ([]Group, error)
+	GetByName(ctx context.Context, database database.Querier, groupName string) (*Group, error)
+	Delete(ctx context.Context, database database.Querier, groupName string) error
+}
+
+var TableGroupsRoles interface {
+	Add(ctx context.Context, database database.Querier, groupRole *AddGroupRole) (*GroupRole, error)
+	GetAll(ctx context.Context, database database.Querier) ([]GroupRole, error)
+	GetByGroupName(ctx context.Context, database database.Querier, groupName string) ([]GroupRole, error)
+	GetByUserIDs(ctx context.Context, database database.Querier, userIDs []string) ([]UserGroupRole, error)
+	DeleteByID(ctx context.Context, database database.Querier, groupName string, dbEntryID uint) error
+}
+
+var TableGroupsUsers interface {
+	Add(ctx context.Context, database database.Querier, groupName, userID string) error
+	GetAll(ctx context.Context, database database.Querier) ([]GroupMember, error)
+	GetByGroupName(ctx context.Context, database database.Querier, groupName string) ([]GroupMember, error)
+	Delete(ctx context.Context, database database.Querier, groupName, userID string) error
+}
+
+var TableAPIKeys interface {
+	Add(ctx context.Context, database database.Querier, key *APIKey) (*APIKey, error)
+	Insert(ctx context.Context, database database.Querier, key *APIKey) error
+	GetByHash(ctx context.Context, database database.Querier, keyHash string) (*APIKey, error)
+	GetByUserID(ctx context.Context, database database.Querier, userID string) ([]APIKey, error)
+	GetAll(ctx context.Context, database database.Querier) ([]APIKey, error)
+	UpdateLastUsedByID(ctx context.Context, database database.Querier, keyID string) error
+	DeleteByID(ctx context.Context, database database.Querier, userID, keyID string) error
+}
+
+var TableInvitations interface {
+	Add(ctx context.Context, database database.Querier, invitation *Invitation) (*Invitation, error)
+	Insert(ctx context.Context, database database.Querier, invitation *Invitation) error
+	GetByID(ctx context.Context, database database.Querier, invitationID string) (*Invitation, error)
+	GetAll(ctx context.Context, database database.Querier) ([]Invitation, error)
+	Redeem(ctx context.Context, database database.Querier, invitationID, userID string) error
+	DeleteByID(ctx context.Context, database database.Querier, invitationID string) error
+}
+
+var TableWebhooks interface {
+	Add(ctx context.Context, database database.Querier, webhook *Webhook) (*Webhook, error)
+	Insert(ctx context.Context, database database.Querier, webhook *Webhook) error
+	GetAll(ctx context.Context, database database.Querier) ([]Webhook, error)
+	DeleteByID(ctx context.Context, database database.Querier, webhookID string) error
+}
+
+var TableWebhookDeliveries interface {
+	Enqueue(ctx context.Context, database database.Querier, eventType string, payload []byte) error
+	Claim(ctx context.Context, database database.Querier, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, database database.Querier, deliveryID int64) error
+	MarkFailed(ctx context.Context, database database.Querier, deliveryID int64, lastError string,
+		retryAt *time.Time) error
+	GetDeadByWebhookID(ctx context.Context, database database.Querier, webhookID string) ([]WebhookDelivery, error)
+	RetryDead(ctx context.Context, database database.Querier, webhookID string, deliveryID int64) error
+	PurgeDelivered(ctx context.Context, database database.Querier, deliveredBefore time.Time) (int64, error)
+}
+
+var TableAuditLog interface {
+	Add(ctx context.Context, database database.Querier, event *AuditEvent) error
+	List(ctx context.Context, database database.Querier, filter *AuditEventFilter) ([]AuditEvent, error)
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/eldarbr/go-auth/pkg/database"
 	"github.com/jackc/pgx/v5"
@@ -16,6 +19,40 @@ type implTableServices struct{}
 
 type implTableUsersRoles struct{}
 
+type implTableAttributeDefinitions struct{}
+
+type implTableGroups struct{}
+
+type implTableGroupsRoles struct{}
+
+type implTableGroupsUsers struct{}
+
+type implTableServicesRoles struct{}
+
+type implTableServicesPermissions struct{}
+
+type implTableRolesPermissions struct{}
+
+type implTableRolesHierarchy struct{}
+
+type implTableAPIKeys struct{}
+
+type implTableInvitations struct{}
+
+type implTableAuditLog struct{}
+
+type implTableWebhooks struct{}
+
+type implTableWebhookDeliveries struct{}
+
+// isUnknownRoleErr tests if the error is a violation of a reference to the services' roles catalog.
+func isUnknownRoleErr(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "fk_users_roles_service_role") ||
+		strings.Contains(err.Error(), "fk_groups_roles_service_role") ||
+		strings.Contains(err.Error(), "fk_api_keys_roles_service_role") ||
+		strings.Contains(err.Error(), "fk_invitations_roles_service_role"))
+}
+
 func (s implTableUsers) Add(ctx context.Context, querier database.Querier, user *AddUser) (*User, error) {
 	if querier == nil {
 		return nil, database.ErrDBNotInitilized
@@ -34,13 +71,22 @@ VALUES
 RETURNING
   "username",
   "password",
-  "id"
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes"
 	`
 
 	var dst User
 
 	queryResult := querier.QueryRow(ctx, query, user.Username, user.Password)
-	err := queryResult.Scan(&dst.Username, &dst.Password, &dst.ID)
+	err := queryResult.Scan(&dst.Username, &dst.Password, &dst.ID, &dst.CreatedTS, &dst.Status,
+		&dst.StatusReason, &dst.StatusChangedTS, &dst.DeletedTS, &dst.LastLoginTS,
+		&dst.Attributes)
 
 	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 		return nil, database.ErrUniqueKeyViolation
@@ -57,6 +103,50 @@ RETURNING
 	return &dst, nil
 }
 
+// Insert adds the user as is, including the generated fields. Used to restore a dump.
+func (s implTableUsers) Insert(ctx context.Context, querier database.Querier, user *User) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if user == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "users"
+  ("username",
+  "password",
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes")
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	attributes := user.Attributes
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	_, err := querier.Exec(ctx, query, user.Username, user.Password, user.ID, user.CreatedTS, user.Status,
+		user.StatusReason, user.StatusChangedTS, user.DeletedTS, user.LastLoginTS, attributes)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableUsers.Insert failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
 func (s implTableUsers) UpdateByUsername(ctx context.Context, querier database.Querier,
 	user *AddUser, username string) error {
 	if querier == nil {
@@ -73,6 +163,7 @@ SET
   "username" = $1,
   "password" = $2
 WHERE "username" = $3
+  AND "deleted_ts" IS NULL
 	`
 
 	result, err := querier.Exec(ctx, query, user.Username, user.Password, username)
@@ -100,15 +191,25 @@ func (s implTableUsers) GetByUsername(ctx context.Context, querier database.Quer
 SELECT
   "username",
   "password",
-  "id"
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes"
 FROM "users"
 WHERE "username" = $1
+  AND "deleted_ts" IS NULL
 	`
 
 	var dst User
 
 	queryResult := querier.QueryRow(ctx, query, username)
-	err := queryResult.Scan(&dst.Username, &dst.Password, &dst.ID)
+	err := queryResult.Scan(&dst.Username, &dst.Password, &dst.ID, &dst.CreatedTS, &dst.Status,
+		&dst.StatusReason, &dst.StatusChangedTS, &dst.DeletedTS, &dst.LastLoginTS,
+		&dst.Attributes)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, database.ErrNoRows
@@ -121,253 +222,288 @@ WHERE "username" = $1
 	return &dst, nil
 }
 
-func (s implTableUsers) DeleteByUsername(ctx context.Context, querier database.Querier, username string) error {
+func (s implTableUsers) GetByID(ctx context.Context, querier database.Querier, userID string) (*User, error) {
 	if querier == nil {
-		return database.ErrDBNotInitilized
+		return nil, database.ErrDBNotInitilized
 	}
 
 	query := `
-DELETE FROM "users"
-WHERE "username" = $1
+SELECT
+  "username",
+  "password",
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes"
+FROM "users"
+WHERE "id" = $1
+  AND "deleted_ts" IS NULL
 	`
 
-	result, err := querier.Exec(ctx, query, username)
-	if err != nil {
-		return fmt.Errorf("TableUsers.Delete failed on DELETE: %w", err)
+	var dst User
+
+	queryResult := querier.QueryRow(ctx, query, userID)
+	err := queryResult.Scan(&dst.Username, &dst.Password, &dst.ID, &dst.CreatedTS, &dst.Status,
+		&dst.StatusReason, &dst.StatusChangedTS, &dst.DeletedTS, &dst.LastLoginTS,
+		&dst.Attributes)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
 	}
 
-	if result.RowsAffected() == 0 {
-		return database.ErrNoRows
+	if err != nil {
+		return nil, fmt.Errorf("TableUsers.GetByID failed on SELECT: %w", err)
 	}
 
-	return nil
+	return &dst, nil
 }
 
-func (s implTableServices) Add(ctx context.Context, querier database.Querier, service *Service) error {
+// List returns a page of users matching the filter.
+// The page is sorted by the SortBy column with the user id as a tie-breaker,
+// so the last user of a page can serve as a keyset cursor for the next one.
+func (s implTableUsers) List(ctx context.Context, querier database.Querier, filter *UserListFilter) ([]User, error) {
 	if querier == nil {
-		return database.ErrDBNotInitilized
+		return nil, database.ErrDBNotInitilized
 	}
 
-	if service == nil {
-		return database.ErrNilArgument
+	if filter == nil {
+		return nil, database.ErrNilArgument
 	}
 
-	query := `
-INSERT INTO "services"
-  ("name")
-VALUES
-  ($1)
-	`
+	var (
+		conditions []string
+		args       []any
+	)
 
-	_, err := querier.Exec(ctx, query, service.Name)
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return database.ErrUniqueKeyViolation
-	}
+	addArg := func(arg any) string {
+		args = append(args, arg)
 
-	if err != nil {
-		return fmt.Errorf("TableServices.Add failed on INSERT: %w", err)
+		return "$" + strconv.Itoa(len(args))
 	}
 
-	return nil
-}
-
-func (s implTableServices) Update(ctx context.Context, querier database.Querier, service *Service,
-	serviceName string) error {
-	if querier == nil {
-		return database.ErrDBNotInitilized
+	sortColumn, sortCast := `"username"`, "VARCHAR"
+	if filter.SortBy == UserListSortCreatedTS {
+		sortColumn, sortCast = `"created_ts"`, "TIMESTAMPTZ"
 	}
 
-	if service == nil {
-		return database.ErrNilArgument
+	sortOrder, cursorOperator := "ASC", ">"
+	if filter.SortDesc {
+		sortOrder, cursorOperator = "DESC", "<"
 	}
 
-	query := `
-UPDATE "services"
-SET
-  "name" = $1
-WHERE "name" = $2
-	`
+	if filter.Deleted {
+		conditions = append(conditions, `"deleted_ts" IS NOT NULL`)
+	} else {
+		conditions = append(conditions, `"deleted_ts" IS NULL`)
+	}
 
-	result, err := querier.Exec(ctx, query, service.Name, serviceName)
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return database.ErrUniqueKeyViolation
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, `"created_ts" >= `+addArg(*filter.CreatedAfter))
 	}
 
-	if err != nil {
-		return fmt.Errorf("TableServices.Update failed on UPDATE: %w", err)
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, `"created_ts" < `+addArg(*filter.CreatedBefore))
 	}
 
-	if result.RowsAffected() == 0 {
-		return database.ErrNoRows
+	// the role is either granted directly, by a grant valid right now, or through a group.
+	if filter.ServiceName != "" || filter.UserRole != "" {
+		directCondition := `EXISTS (SELECT 1 FROM "users_roles" WHERE "users_roles"."user_id" = "users"."id"
+    AND ("users_roles"."valid_from" IS NULL OR "users_roles"."valid_from" <= NOW())
+    AND ("users_roles"."expires_at" IS NULL OR "users_roles"."expires_at" > NOW())`
+		groupCondition := `EXISTS (SELECT 1 FROM "groups_users" JOIN "groups_roles"
+    ON "groups_roles"."group_name" = "groups_users"."group_name"
+    WHERE "groups_users"."user_id" = "users"."id"`
+
+		if filter.ServiceName != "" {
+			arg := addArg(filter.ServiceName)
+			directCondition += ` AND "users_roles"."service_name" = ` + arg
+			groupCondition += ` AND "groups_roles"."service_name" = ` + arg
+		}
+
+		if filter.UserRole != "" {
+			arg := addArg(filter.UserRole)
+			directCondition += ` AND "users_roles"."user_role" = ` + arg
+			groupCondition += ` AND "groups_roles"."user_role" = ` + arg
+		}
+
+		conditions = append(conditions, "("+directCondition+") OR "+groupCondition+"))")
 	}
 
-	return nil
-}
+	if filter.Search != "" {
+		pattern := escapeLikePattern(filter.Search) + "%"
+		if filter.SearchMode != UserListSearchPrefix {
+			pattern = "%" + pattern
+		}
 
-func (s implTableServices) GetByServiceName(ctx context.Context, querier database.Querier,
-	serviceName string) (*Service, error,
-) {
-	if querier == nil {
-		return nil, database.ErrDBNotInitilized
+		conditions = append(conditions, `"username" ILIKE `+addArg(pattern))
+	}
+
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf(`(%s, "id") %s (%s::%s, %s::UUID)`, sortColumn, cursorOperator,
+			addArg(filter.After.SortValue), sortCast, addArg(filter.After.ID)))
 	}
 
 	query := `
 SELECT
-  "name"
-FROM "services"
-WHERE "name" = $1
-	`
+  "username",
+  "password",
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes"
+FROM "users"
+`
 
-	var dst Service
+	query += "WHERE " + strings.Join(conditions, "\n  AND ") + "\n"
 
-	queryResult := querier.QueryRow(ctx, query, serviceName)
-	err := queryResult.Scan(&dst.Name)
+	query += fmt.Sprintf("ORDER BY %s %s, \"id\" %s\nLIMIT %s", sortColumn, sortOrder, sortOrder, addArg(filter.Limit))
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, database.ErrNoRows
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsers.List failed on SELECT: %w", err)
 	}
 
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (User, error) {
+		var nextDst User
+		err := row.Scan(&nextDst.Username, &nextDst.Password, &nextDst.ID, &nextDst.CreatedTS,
+			&nextDst.Status, &nextDst.StatusReason, &nextDst.StatusChangedTS, &nextDst.DeletedTS,
+			&nextDst.LastLoginTS, &nextDst.Attributes)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
 	if err != nil {
-		return nil, fmt.Errorf("TableServices.GetByServiceName failed on SELECT: %w", err)
+		return nil, fmt.Errorf("TableUsers.List failed on Scan: %w", err)
 	}
 
-	return &dst, nil
+	return dst, nil
 }
 
-func (s implTableServices) Delete(ctx context.Context, querier database.Querier,
-	serviceName string) error {
+// escapeLikePattern escapes the LIKE wildcards, so the string is matched literally.
+func escapeLikePattern(str string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(str)
+}
+
+// GetAll returns all the users, the soft deleted ones included.
+func (s implTableUsers) GetAll(ctx context.Context, querier database.Querier) ([]User, error) {
 	if querier == nil {
-		return database.ErrDBNotInitilized
+		return nil, database.ErrDBNotInitilized
 	}
 
 	query := `
-DELETE FROM "services"
-WHERE "name" = $1
+SELECT
+  "username",
+  "password",
+  "id",
+  "created_ts",
+  "status",
+  "status_reason",
+  "status_changed_ts",
+  "deleted_ts",
+  "last_login_ts",
+  "attributes"
+FROM "users"
+ORDER BY "created_ts", "id"
 	`
 
-	result, err := querier.Exec(ctx, query, serviceName)
+	queryResult, err := querier.Query(ctx, query)
 	if err != nil {
-		return fmt.Errorf("TableServices.Delete failed on DELETE: %w", err)
+		return nil, fmt.Errorf("TableUsers.GetAll failed on SELECT: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return database.ErrNoRows
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (User, error) {
+		var nextDst User
+		err := row.Scan(&nextDst.Username, &nextDst.Password, &nextDst.ID, &nextDst.CreatedTS, &nextDst.Status,
+			&nextDst.StatusReason, &nextDst.StatusChangedTS, &nextDst.DeletedTS,
+			&nextDst.LastLoginTS, &nextDst.Attributes)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsers.GetAll failed on Scan: %w", err)
 	}
 
-	return nil
+	return dst, nil
 }
 
-// TODO: check if the user_role is adequate.
-func (s implTableUsersRoles) Add(ctx context.Context, querier database.Querier,
-	userRole *AddUserRole) (*UserRole, error,
-) {
+// CountAll counts all the users, the soft deleted ones included.
+func (s implTableUsers) CountAll(ctx context.Context, querier database.Querier) (int, error) {
 	if querier == nil {
-		return nil, database.ErrDBNotInitilized
-	}
-
-	if userRole == nil {
-		return nil, database.ErrNilArgument
+		return 0, database.ErrDBNotInitilized
 	}
 
 	query := `
-INSERT INTO "users_roles"
-  ("user_id",
-  "user_role",
-  "service_name")
-VALUES
-  ($1, $2, $3)
-RETURNING
-  "id",
-  "user_id",
-  "user_role",
-  "service_name",
-  "created_ts"
+SELECT
+  COUNT(*)
+FROM "users"
 	`
 
-	var dst UserRole
-
-	queryResult := querier.QueryRow(ctx, query, userRole.UserID, userRole.UserRole, userRole.ServiceName)
-	err := queryResult.Scan(&dst.ID, &dst.UserID, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS)
-
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return nil, database.ErrUniqueKeyViolation
-	}
-
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, database.ErrNoRows
-	}
+	var dst int
 
+	err := querier.QueryRow(ctx, query).Scan(&dst)
 	if err != nil {
-		return nil, fmt.Errorf("TableUsersGroups.Add failed on INSERT: %w", err)
+		return 0, fmt.Errorf("TableUsers.CountAll failed on SELECT: %w", err)
 	}
 
-	return &dst, nil
+	return dst, nil
 }
 
-// TODO: check if the user_role is adequate.
-func (s implTableUsersRoles) Insert(ctx context.Context, querier database.Querier, userRole *UserRole) error {
+func (s implTableUsers) UpdateStatusByID(ctx context.Context, querier database.Querier,
+	status *UserStatus, userID string) error {
 	if querier == nil {
 		return database.ErrDBNotInitilized
 	}
 
-	if userRole == nil {
+	if status == nil {
 		return database.ErrNilArgument
 	}
 
 	query := `
-INSERT INTO "users_roles"
-  ("id",
-  "user_id",
-  "user_role",
-  "service_name",
-  "created_ts")
-VALUES
-  ($1, $2, $3, $4, $5)
+UPDATE "users"
+SET
+  "status" = $1,
+  "status_reason" = $2,
+  "status_changed_ts" = NOW()
+WHERE "id" = $3
+  AND "deleted_ts" IS NULL
 	`
 
-	_, err := querier.Exec(ctx, query, userRole.ID, userRole.UserID, userRole.UserRole,
-		userRole.ServiceName, userRole.CreatedTS)
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return database.ErrUniqueKeyViolation
+	result, err := querier.Exec(ctx, query, status.Status, status.Reason, userID)
+	if err != nil {
+		return fmt.Errorf("TableUsers.UpdateStatusByID failed on UPDATE: %w", err)
 	}
 
-	if err != nil {
-		return fmt.Errorf("TableUsersGroups.Insert failed on INSERT: %w", err)
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
 	}
 
 	return nil
 }
 
-// TODO: check if the user_role is adequate.
-func (s implTableUsersRoles) UpdateByID(ctx context.Context, querier database.Querier, userRole *UserRole,
-	dbEntryID uint) error {
+// UpdateLastLoginByID sets the last login time of the user to now.
+func (s implTableUsers) UpdateLastLoginByID(ctx context.Context, querier database.Querier, userID string) error {
 	if querier == nil {
 		return database.ErrDBNotInitilized
 	}
 
-	if userRole == nil {
-		return database.ErrNilArgument
-	}
-
 	query := `
-UPDATE "users_roles"
+UPDATE "users"
 SET
-  "id" = $1,
-  "user_id" = $2,
-  "user_role" = $3,
-  "service_name" = $4,
-  "created_ts" = $5
-WHERE "id" = $6
+  "last_login_ts" = NOW()
+WHERE "id" = $1
+  AND "deleted_ts" IS NULL
 	`
 
-	result, err := querier.Exec(ctx, query, userRole.ID, userRole.UserID, userRole.UserRole,
-		userRole.ServiceName, userRole.CreatedTS, dbEntryID)
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return database.ErrUniqueKeyViolation
-	}
-
+	result, err := querier.Exec(ctx, query, userID)
 	if err != nil {
-		return fmt.Errorf("TableUsersGroups.UpdateByID failed on UPDATE: %w", err)
+		return fmt.Errorf("TableUsers.UpdateLastLoginByID failed on UPDATE: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
@@ -377,48 +513,2852 @@ WHERE "id" = $6
 	return nil
 }
 
-func (s implTableUsersRoles) GetByUserID(ctx context.Context, querier database.Querier,
-	userID string) ([]UserRole, error,
-) {
+// MergeAttributesByID merges the attributes into the user's ones, a nil value removes the attribute.
+// Returns the resulting attributes.
+func (s implTableUsers) MergeAttributesByID(ctx context.Context, querier database.Querier,
+	attributes map[string]any, userID string) (map[string]any, error) {
 	if querier == nil {
 		return nil, database.ErrDBNotInitilized
 	}
 
+	if attributes == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	// the values are scalars, so stripping the nulls only removes the attributes set to null.
 	query := `
-SELECT
-  "id",
-  "user_id",
-  "user_role",
-  "service_name",
-  "created_ts"
-FROM "users_roles"
-WHERE "user_id" = $1
+UPDATE "users"
+SET
+  "attributes" = jsonb_strip_nulls("attributes" || $1::JSONB)
+WHERE "id" = $2
+  AND "deleted_ts" IS NULL
+RETURNING
+  "attributes"
 	`
 
-	var (
+	var dst map[string]any
+
+	err := querier.QueryRow(ctx, query, attributes, userID).Scan(&dst)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableUsers.MergeAttributesByID failed on UPDATE: %w", err)
+	}
+
+	return dst, nil
+}
+
+// DeleteAttribute removes the attribute from all the users.
+func (s implTableUsers) DeleteAttribute(ctx context.Context, querier database.Querier, name string) (int64, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "users"
+SET
+  "attributes" = "attributes" - $1::TEXT
+WHERE "attributes" ? $1::TEXT
+	`
+
+	result, err := querier.Exec(ctx, query, name)
+	if err != nil {
+		return 0, fmt.Errorf("TableUsers.DeleteAttribute failed on UPDATE: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// SoftDeleteByID marks the user deleted. The deleted user is hidden from the lookups,
+// but keeps the roles until restored or purged.
+func (s implTableUsers) SoftDeleteByID(ctx context.Context, querier database.Querier, userID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "users"
+SET
+  "deleted_ts" = NOW()
+WHERE "id" = $1
+  AND "deleted_ts" IS NULL
+	`
+
+	result, err := querier.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("TableUsers.SoftDeleteByID failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableUsers) RestoreByID(ctx context.Context, querier database.Querier, userID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "users"
+SET
+  "deleted_ts" = NULL
+WHERE "id" = $1
+  AND "deleted_ts" IS NOT NULL
+	`
+
+	result, err := querier.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("TableUsers.RestoreByID failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// PurgeDeleted removes for good the users deleted before the deletedBefore.
+func (s implTableUsers) PurgeDeleted(ctx context.Context, querier database.Querier,
+	deletedBefore time.Time) (int64, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "users"
+WHERE "deleted_ts" < $1
+	`
+
+	result, err := querier.Exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("TableUsers.PurgeDeleted failed on DELETE: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (s implTableUsers) DeleteByUsername(ctx context.Context, querier database.Querier, username string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "users"
+WHERE "username" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, username)
+	if err != nil {
+		return fmt.Errorf("TableUsers.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableServices) Add(ctx context.Context, querier database.Querier, service *Service) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if service == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "services"
+  ("name")
+VALUES
+  ($1)
+	`
+
+	_, err := querier.Exec(ctx, query, service.Name)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableServices.Add failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
+func (s implTableServices) Update(ctx context.Context, querier database.Querier, service *Service,
+	serviceName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if service == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+UPDATE "services"
+SET
+  "name" = $1
+WHERE "name" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, service.Name, serviceName)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableServices.Update failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableServices) GetByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) (*Service, error,
+) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "name"
+FROM "services"
+WHERE "name" = $1
+	`
+
+	var dst Service
+
+	queryResult := querier.QueryRow(ctx, query, serviceName)
+	err := queryResult.Scan(&dst.Name)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableServices.GetByServiceName failed on SELECT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableServices) GetAll(ctx context.Context, querier database.Querier) ([]Service, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "name"
+FROM "services"
+ORDER BY "name"
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableServices.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (Service, error) {
+		var nextDst Service
+		err := row.Scan(&nextDst.Name)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableServices.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableServices) Delete(ctx context.Context, querier database.Querier,
+	serviceName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "services"
+WHERE "name" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, serviceName)
+	if err != nil {
+		return fmt.Errorf("TableServices.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableAttributeDefinitions) Add(ctx context.Context, querier database.Querier,
+	definition *AttributeDefinition) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if definition == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "attribute_definitions"
+  ("name",
+  "value_type",
+  "max_length")
+VALUES
+  ($1, $2, $3)
+	`
+
+	_, err := querier.Exec(ctx, query, definition.Name, definition.ValueType, definition.MaxLength)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableAttributeDefinitions.Add failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
+func (s implTableAttributeDefinitions) GetAll(ctx context.Context,
+	querier database.Querier) ([]AttributeDefinition, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "name",
+  "value_type",
+  "max_length"
+FROM "attribute_definitions"
+ORDER BY "name"
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableAttributeDefinitions.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (AttributeDefinition, error) {
+		var nextDst AttributeDefinition
+		err := row.Scan(&nextDst.Name, &nextDst.ValueType, &nextDst.MaxLength)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableAttributeDefinitions.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableAttributeDefinitions) Delete(ctx context.Context, querier database.Querier, name string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "attribute_definitions"
+WHERE "name" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, name)
+	if err != nil {
+		return fmt.Errorf("TableAttributeDefinitions.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add grants the role. The role must be defined in the service's roles catalog, ErrUnknownRole otherwise.
+func (s implTableUsersRoles) Add(ctx context.Context, querier database.Querier,
+	userRole *AddUserRole) (*UserRole, error,
+) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if userRole == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "users_roles"
+  ("user_id",
+  "user_role",
+  "service_name",
+  "valid_from",
+  "expires_at")
+VALUES
+  ($1, $2, $3, $4, $5)
+RETURNING
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+	`
+
+	var dst UserRole
+
+	queryResult := querier.QueryRow(ctx, query, userRole.UserID, userRole.UserRole, userRole.ServiceName,
+		userRole.ValidFrom, userRole.ExpiresAt)
+	err := queryResult.Scan(&dst.ID, &dst.UserID, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS, &dst.ValidFrom,
+		&dst.ExpiresAt)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return nil, ErrUnknownRole
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+// Insert inserts the role entry as is. The role must be defined in the service's roles catalog.
+func (s implTableUsersRoles) Insert(ctx context.Context, querier database.Querier, userRole *UserRole) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if userRole == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "users_roles"
+  ("id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at")
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := querier.Exec(ctx, query, userRole.ID, userRole.UserID, userRole.UserRole,
+		userRole.ServiceName, userRole.CreatedTS, userRole.ValidFrom, userRole.ExpiresAt)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return ErrUnknownRole
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableUsersGroups.Insert failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateByID overwrites the role entry. The role must be defined in the service's roles catalog.
+func (s implTableUsersRoles) UpdateByID(ctx context.Context, querier database.Querier, userRole *UserRole,
+	dbEntryID uint) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if userRole == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+UPDATE "users_roles"
+SET
+  "id" = $1,
+  "user_id" = $2,
+  "user_role" = $3,
+  "service_name" = $4,
+  "created_ts" = $5,
+  "valid_from" = $6,
+  "expires_at" = $7
+WHERE "id" = $8
+	`
+
+	result, err := querier.Exec(ctx, query, userRole.ID, userRole.UserID, userRole.UserRole,
+		userRole.ServiceName, userRole.CreatedTS, userRole.ValidFrom, userRole.ExpiresAt, dbEntryID)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return ErrUnknownRole
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableUsersGroups.UpdateByID failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's grants active right now.
+func (s implTableUsersRoles) GetByUserID(ctx context.Context, querier database.Querier,
+	userID string) ([]UserRole, error,
+) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "user_id" = $1
+  AND ("valid_from" IS NULL OR "valid_from" <= NOW())
+  AND ("expires_at" IS NULL OR "expires_at" > NOW())
+	`
+
+	var (
+		dst []UserRole
+		err error
+	)
+
+	queryResult, err := querier.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByUserID failed on SELECT: %w", err)
+	}
+
+	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByUserID failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// GetAllByUserID returns every role granted to the user, the expired and the not yet valid ones too.
+func (s implTableUsersRoles) GetAllByUserID(ctx context.Context, querier database.Querier,
+	userID string) ([]UserRole, error,
+) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "user_id" = $1
+	`
+
+	var (
+		dst []UserRole
+		err error
+	)
+
+	queryResult, err := querier.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetAllByUserID failed on SELECT: %w", err)
+	}
+
+	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetAllByUserID failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// GetByUserIDs returns the users' grants active right now.
+func (s implTableUsersRoles) GetByUserIDs(ctx context.Context, querier database.Querier,
+	userIDs []string) ([]UserRole, error,
+) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "user_id" = ANY($1::UUID[])
+  AND ("valid_from" IS NULL OR "valid_from" <= NOW())
+  AND ("expires_at" IS NULL OR "expires_at" > NOW())
+	`
+
+	queryResult, err := querier.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByUserIDs failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err := row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByUserIDs failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// GetAll returns every grant, including the not yet valid and the expired ones.
+func (s implTableUsersRoles) GetAll(ctx context.Context, querier database.Querier) ([]UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+ORDER BY "id"
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err := row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableUsersRoles) GetByID(ctx context.Context, querier database.Querier, dbEntryID uint) (*UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "id" = $1
+	`
+
+	var dst UserRole
+
+	queryResult := querier.QueryRow(ctx, query, dbEntryID)
+	err := queryResult.Scan(&dst.ID, &dst.UserID, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS, &dst.ValidFrom,
+		&dst.ExpiresAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableServices.GetByID failed on SELECT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableUsersRoles) GetByUserIDAndServiceName(ctx context.Context, querier database.Querier,
+	userID, serviceName string) (*UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "user_id" = $1
+  AND "service_name" = $2
+	`
+
+	var dst UserRole
+
+	queryResult := querier.QueryRow(ctx, query, userID, serviceName)
+	err := queryResult.Scan(&dst.ID, &dst.UserID, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS, &dst.ValidFrom,
+		&dst.ExpiresAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByUserIDAndServiceName failed on SELECT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableUsersRoles) GetMembersByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) ([]ServiceMember, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "users_roles"."id",
+  "users_roles"."user_id",
+  "users_roles"."user_role",
+  "users_roles"."service_name",
+  "users_roles"."created_ts",
+  "users_roles"."valid_from",
+  "users_roles"."expires_at",
+  "users"."username"
+FROM "users_roles"
+JOIN "users" ON "users"."id" = "users_roles"."user_id"
+WHERE "users_roles"."service_name" = $1
+  AND "users"."deleted_ts" IS NULL
+ORDER BY "users"."username"
+	`
+
+	queryResult, err := querier.Query(ctx, query, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetMembersByServiceName failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (ServiceMember, error) {
+		var nextDst ServiceMember
+		err := row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole.UserRole, &nextDst.ServiceName,
+			&nextDst.CreatedTS, &nextDst.ValidFrom, &nextDst.ExpiresAt, &nextDst.Username)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetMembersByServiceName failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// GetByServiceName returns every role granted in the service, the expired and the not yet valid ones too.
+func (s implTableUsersRoles) GetByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) ([]UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "user_id",
+  "user_role",
+  "service_name",
+  "created_ts",
+  "valid_from",
+  "expires_at"
+FROM "users_roles"
+WHERE "service_name" = $1
+	`
+
+	var (
+		dst []UserRole
+		err error
+	)
+
+	queryResult, err := querier.Query(ctx, query, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByServiceName failed on SELECT: %w", err)
+	}
+
+	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.GetByServiceName failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableUsersRoles) CountByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) (int, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  COUNT(*)
+FROM "users_roles"
+WHERE "service_name" = $1
+	`
+
+	var dst int
+
+	err := querier.QueryRow(ctx, query, serviceName).Scan(&dst)
+	if err != nil {
+		return 0, fmt.Errorf("TableUsersGroups.CountByServiceName failed on SELECT: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableUsersRoles) DeleteByID(ctx context.Context, querier database.Querier, dbEntryID uint) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "users_roles"
+WHERE "id" = $1
+		`
+
+	result, err := querier.Exec(ctx, query, dbEntryID)
+	if err != nil {
+		return fmt.Errorf("TableUsersGroups.DeleteByID failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the grants expired before the moment and returns them.
+func (s implTableUsersRoles) DeleteExpired(ctx context.Context, querier database.Querier,
+	expiredBefore time.Time) ([]UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "users_roles"
+WHERE "expires_at" <= $1
+RETURNING
+  "users_roles"."id",
+  "users_roles"."user_id",
+  "users_roles"."user_role",
+  "users_roles"."service_name",
+  "users_roles"."created_ts",
+  "users_roles"."valid_from",
+  "users_roles"."expires_at"
+	`
+
+	var (
+		dst []UserRole
+		err error
+	)
+
+	queryResult, err := querier.Query(ctx, query, expiredBefore)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.DeleteExpired failed on DELETE: %w", err)
+	}
+
+	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.DeleteExpired failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// DeleteByDeletedUsers deletes the roles of the users soft deleted before the time and returns them.
+func (s implTableUsersRoles) DeleteByDeletedUsers(ctx context.Context, querier database.Querier,
+	deletedBefore time.Time) ([]UserRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "users_roles" USING "users"
+WHERE "users"."id" = "users_roles"."user_id"
+  AND "users"."deleted_ts" < $1
+RETURNING
+  "users_roles"."id",
+  "users_roles"."user_id",
+  "users_roles"."user_role",
+  "users_roles"."service_name",
+  "users_roles"."created_ts",
+  "users_roles"."valid_from",
+  "users_roles"."expires_at"
+	`
+
+	var (
 		dst []UserRole
 		err error
 	)
 
-	queryResult, err := querier.Query(ctx, query, userID)
+	queryResult, err := querier.Query(ctx, query, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.DeleteByDeletedUsers failed on DELETE: %w", err)
+	}
+
+	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
+		var nextDst UserRole
+		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS,
+			&nextDst.ValidFrom, &nextDst.ExpiresAt)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableUsersGroups.DeleteByDeletedUsers failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// ResetIDSequence moves the id sequence past the biggest id, needed after the explicit ids were inserted.
+func (s implTableUsersRoles) ResetIDSequence(ctx context.Context, querier database.Querier) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT setval(
+  pg_get_serial_sequence('"users_roles"', 'id'),
+  COALESCE(MAX("id"), 0) + 1,
+  false)
+FROM "users_roles"
+	`
+
+	_, err := querier.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("TableUsersGroups.ResetIDSequence failed on SELECT: %w", err)
+	}
+
+	return nil
+}
+
+func (s implTableGroups) Add(ctx context.Context, querier database.Querier, groupName string) (*Group, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+INSERT INTO "groups"
+  ("name")
+VALUES
+  ($1)
+RETURNING
+  "name",
+  "created_ts"
+	`
+
+	var dst Group
+
+	err := querier.QueryRow(ctx, query, groupName).Scan(&dst.Name, &dst.CreatedTS)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableGroups.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableGroups) GetAll(ctx context.Context, querier database.Querier) ([]Group, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "name",
+  "created_ts"
+FROM "groups"
+ORDER BY "name"
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableGroups.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (Group, error) {
+		var nextDst Group
+		err := row.Scan(&nextDst.Name, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableGroups.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableGroups) GetByName(ctx context.Context, querier database.Querier, groupName string) (*Group, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "name",
+  "created_ts"
+FROM "groups"
+WHERE "name" = $1
+	`
+
+	var dst Group
+
+	err := querier.QueryRow(ctx, query, groupName).Scan(&dst.Name, &dst.CreatedTS)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, database.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableGroups.GetByName failed on SELECT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableGroups) Delete(ctx context.Context, querier database.Querier, groupName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "groups"
+WHERE "name" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, groupName)
+	if err != nil {
+		return fmt.Errorf("TableGroups.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableGroupsRoles) Add(ctx context.Context, querier database.Querier,
+	groupRole *AddGroupRole) (*GroupRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if groupRole == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "groups_roles"
+  ("group_name",
+  "user_role",
+  "service_name")
+VALUES
+  ($1, $2, $3)
+RETURNING
+  "id",
+  "group_name",
+  "user_role",
+  "service_name",
+  "created_ts"
+	`
+
+	var dst GroupRole
+
+	queryResult := querier.QueryRow(ctx, query, groupRole.GroupName, groupRole.UserRole, groupRole.ServiceName)
+	err := queryResult.Scan(&dst.ID, &dst.GroupName, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return nil, ErrUnknownRole
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableGroupsRoles) GetAll(ctx context.Context, querier database.Querier) ([]GroupRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "group_name",
+  "user_role",
+  "service_name",
+  "created_ts"
+FROM "groups_roles"
+ORDER BY "id"
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (GroupRole, error) {
+		var nextDst GroupRole
+		err := row.Scan(&nextDst.ID, &nextDst.GroupName, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableGroupsRoles) GetByGroupName(ctx context.Context, querier database.Querier,
+	groupName string) ([]GroupRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "group_name",
+  "user_role",
+  "service_name",
+  "created_ts"
+FROM "groups_roles"
+WHERE "group_name" = $1
+ORDER BY "service_name"
+	`
+
+	queryResult, err := querier.Query(ctx, query, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetByGroupName failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (GroupRole, error) {
+		var nextDst GroupRole
+		err := row.Scan(&nextDst.ID, &nextDst.GroupName, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetByGroupName failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// GetByUserIDs returns the roles the users have through their groups.
+func (s implTableGroupsRoles) GetByUserIDs(ctx context.Context, querier database.Querier,
+	userIDs []string) ([]UserGroupRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "groups_users"."user_id",
+  "groups_roles"."id",
+  "groups_roles"."group_name",
+  "groups_roles"."user_role",
+  "groups_roles"."service_name",
+  "groups_roles"."created_ts"
+FROM "groups_users"
+JOIN "groups_roles"
+  ON "groups_roles"."group_name" = "groups_users"."group_name"
+WHERE "groups_users"."user_id" = ANY($1::UUID[])
+ORDER BY "groups_roles"."group_name", "groups_roles"."service_name"
+	`
+
+	queryResult, err := querier.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetByUserIDs failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserGroupRole, error) {
+		var nextDst UserGroupRole
+		err := row.Scan(&nextDst.UserID, &nextDst.ID, &nextDst.GroupName, &nextDst.UserRole, &nextDst.ServiceName,
+			&nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableGroupsRoles.GetByUserIDs failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableGroupsRoles) DeleteByID(ctx context.Context, querier database.Querier, groupName string,
+	dbEntryID uint) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "groups_roles"
+WHERE "id" = $1
+  AND "group_name" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, dbEntryID, groupName)
+	if err != nil {
+		return fmt.Errorf("TableGroupsRoles.DeleteByID failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableGroupsUsers) Add(ctx context.Context, querier database.Querier, groupName, userID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+INSERT INTO "groups_users"
+  ("group_name",
+  "user_id")
+VALUES
+  ($1, $2)
+	`
+
+	_, err := querier.Exec(ctx, query, groupName, userID)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableGroupsUsers.Add failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
+// GetAll returns all the memberships, the soft deleted users' ones included.
+func (s implTableGroupsUsers) GetAll(ctx context.Context, querier database.Querier) ([]GroupMember, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "groups_users"."group_name",
+  "groups_users"."user_id",
+  "users"."username",
+  "groups_users"."created_ts"
+FROM "groups_users"
+JOIN "users"
+  ON "users"."id" = "groups_users"."user_id"
+ORDER BY "groups_users"."group_name", "users"."username"
+	`
+
+	return s.collectMembers(ctx, querier, "TableGroupsUsers.GetAll", query)
+}
+
+// GetByGroupName returns the group's members, the soft deleted users are omitted.
+func (s implTableGroupsUsers) GetByGroupName(ctx context.Context, querier database.Querier,
+	groupName string) ([]GroupMember, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "groups_users"."group_name",
+  "groups_users"."user_id",
+  "users"."username",
+  "groups_users"."created_ts"
+FROM "groups_users"
+JOIN "users"
+  ON "users"."id" = "groups_users"."user_id"
+WHERE "groups_users"."group_name" = $1
+  AND "users"."deleted_ts" IS NULL
+ORDER BY "users"."username"
+	`
+
+	return s.collectMembers(ctx, querier, "TableGroupsUsers.GetByGroupName", query, groupName)
+}
+
+func (s implTableGroupsUsers) collectMembers(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]GroupMember, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (GroupMember, error) {
+		var nextDst GroupMember
+		err := row.Scan(&nextDst.GroupName, &nextDst.UserID, &nextDst.Username, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableGroupsUsers) Delete(ctx context.Context, querier database.Querier, groupName, userID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "groups_users"
+WHERE "group_name" = $1
+  AND "user_id" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, groupName, userID)
+	if err != nil {
+		return fmt.Errorf("TableGroupsUsers.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableServicesRoles) Add(ctx context.Context, querier database.Querier,
+	role *ServiceRole) (*ServiceRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if role == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "services_roles"
+  ("service_name",
+  "name",
+  "description")
+VALUES
+  ($1, $2, $3)
+RETURNING
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+	`
+
+	var dst ServiceRole
+
+	queryResult := querier.QueryRow(ctx, query, role.ServiceName, role.Name, role.Description)
+	err := queryResult.Scan(&dst.ServiceName, &dst.Name, &dst.Description, &dst.CreatedTS)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesRoles.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+// AddDefaults defines the DefaultServiceRoles and the DefaultRoleHierarchy in the service,
+// the already defined ones are skipped. Expected to be run in a transaction.
+func (s implTableServicesRoles) AddDefaults(ctx context.Context, querier database.Querier, serviceName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+INSERT INTO "services_roles"
+  ("service_name",
+  "name")
+SELECT
+  $1,
+  UNNEST($2::VARCHAR[])
+ON CONFLICT DO NOTHING
+	`
+
+	_, err := querier.Exec(ctx, query, serviceName, DefaultServiceRoles)
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableServicesRoles.AddDefaults failed on INSERT: %w", err)
+	}
+
+	roleNames := make([]string, 0, len(DefaultRoleHierarchy))
+	impliedRoleNames := make([]string, 0, len(DefaultRoleHierarchy))
+
+	for _, implication := range DefaultRoleHierarchy {
+		roleNames = append(roleNames, implication.UserRole)
+		impliedRoleNames = append(impliedRoleNames, implication.ImpliedRole)
+	}
+
+	query = `
+INSERT INTO "services_roles_hierarchy"
+  ("service_name",
+  "role_name",
+  "implied_role_name")
+SELECT
+  $1,
+  "defaults"."role_name",
+  "defaults"."implied_role_name"
+FROM UNNEST($2::VARCHAR[], $3::VARCHAR[]) AS "defaults"("role_name", "implied_role_name")
+ON CONFLICT DO NOTHING
+	`
+
+	_, err = querier.Exec(ctx, query, serviceName, roleNames, impliedRoleNames)
+	if err != nil {
+		return fmt.Errorf("TableServicesRoles.AddDefaults failed on INSERT hierarchy: %w", err)
+	}
+
+	return nil
+}
+
+func (s implTableServicesRoles) GetByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) ([]ServiceRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+FROM "services_roles"
+WHERE "service_name" = $1
+ORDER BY "name"
+	`
+
+	return collectServiceRoles(ctx, querier, "GetByServiceName", query, serviceName)
+}
+
+func (s implTableServicesRoles) GetAll(ctx context.Context, querier database.Querier) ([]ServiceRole, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+FROM "services_roles"
+ORDER BY "service_name", "name"
+	`
+
+	return collectServiceRoles(ctx, querier, "GetAll", query)
+}
+
+func collectServiceRoles(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]ServiceRole, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesRoles.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (ServiceRole, error) {
+		var nextDst ServiceRole
+		err := row.Scan(&nextDst.ServiceName, &nextDst.Name, &nextDst.Description, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesRoles.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+// CountGrants counts the users' and the groups' entries that grant the role, an empty roleName stands for
+// every role of the service.
+func (s implTableServicesRoles) CountGrants(ctx context.Context, querier database.Querier,
+	serviceName, roleName string) (int, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  (SELECT COUNT(*) FROM "users_roles" WHERE "service_name" = $1 AND ($2 = '' OR "user_role" = $2)) +
+  (SELECT COUNT(*) FROM "groups_roles" WHERE "service_name" = $1 AND ($2 = '' OR "user_role" = $2))
+	`
+
+	var dst int
+
+	err := querier.QueryRow(ctx, query, serviceName, roleName).Scan(&dst)
+	if err != nil {
+		return 0, fmt.Errorf("TableServicesRoles.CountGrants failed on SELECT: %w", err)
+	}
+
+	return dst, nil
+}
+
+// CountScopes counts the API keys' and the invitations' entries scoped to the role, an empty roleName stands for
+// every role of the service.
+func (s implTableServicesRoles) CountScopes(ctx context.Context, querier database.Querier,
+	serviceName, roleName string) (int, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  (SELECT COUNT(*) FROM "api_keys_roles" WHERE "service_name" = $1 AND ($2 = '' OR "user_role" = $2)) +
+  (SELECT COUNT(*) FROM "invitations_roles" WHERE "service_name" = $1 AND ($2 = '' OR "user_role" = $2))
+	`
+
+	var dst int
+
+	err := querier.QueryRow(ctx, query, serviceName, roleName).Scan(&dst)
+	if err != nil {
+		return 0, fmt.Errorf("TableServicesRoles.CountScopes failed on SELECT: %w", err)
+	}
+
+	return dst, nil
+}
+
+// LockGrants blocks the role grants to the users and the groups until the end of the transaction,
+// so that the counted grants stay as they are. The querier must be a transaction.
+func (s implTableServicesRoles) LockGrants(ctx context.Context, querier database.Querier) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+LOCK TABLE "users_roles", "groups_roles" IN SHARE ROW EXCLUSIVE MODE
+	`
+
+	_, err := querier.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("TableServicesRoles.LockGrants failed on LOCK: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the role from the catalog, the entries granting it are deleted too.
+func (s implTableServicesRoles) Delete(ctx context.Context, querier database.Querier,
+	serviceName, roleName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "services_roles"
+WHERE "service_name" = $1
+  AND "name" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, serviceName, roleName)
+	if err != nil {
+		return fmt.Errorf("TableServicesRoles.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s implTableServicesPermissions) Add(ctx context.Context, querier database.Querier,
+	permission *ServicePermission) (*ServicePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if permission == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "services_permissions"
+  ("service_name",
+  "name",
+  "description")
+VALUES
+  ($1, $2, $3)
+RETURNING
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+	`
+
+	var dst ServicePermission
+
+	queryResult := querier.QueryRow(ctx, query, permission.ServiceName, permission.Name, permission.Description)
+	err := queryResult.Scan(&dst.ServiceName, &dst.Name, &dst.Description, &dst.CreatedTS)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesPermissions.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableServicesPermissions) GetByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) ([]ServicePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+FROM "services_permissions"
+WHERE "service_name" = $1
+ORDER BY "name"
+	`
+
+	return collectServicePermissions(ctx, querier, "GetByServiceName", query, serviceName)
+}
+
+func (s implTableServicesPermissions) GetAll(ctx context.Context,
+	querier database.Querier) ([]ServicePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "name",
+  "description",
+  "created_ts"
+FROM "services_permissions"
+ORDER BY "service_name", "name"
+	`
+
+	return collectServicePermissions(ctx, querier, "GetAll", query)
+}
+
+func collectServicePermissions(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]ServicePermission, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesPermissions.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (ServicePermission, error) {
+		var nextDst ServicePermission
+		err := row.Scan(&nextDst.ServiceName, &nextDst.Name, &nextDst.Description, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableServicesPermissions.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+// Delete removes the permission from the catalog, the roles stop granting it.
+func (s implTableServicesPermissions) Delete(ctx context.Context, querier database.Querier,
+	serviceName, name string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "services_permissions"
+WHERE "service_name" = $1
+  AND "name" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, serviceName, name)
+	if err != nil {
+		return fmt.Errorf("TableServicesPermissions.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add grants the permission to the role. Both must be defined in the service's catalogs,
+// ErrUnknownRole or ErrUnknownPermission otherwise.
+func (s implTableRolesPermissions) Add(ctx context.Context, querier database.Querier,
+	rolePermission *RolePermission) (*RolePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if rolePermission == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "services_roles_permissions"
+  ("service_name",
+  "role_name",
+  "permission_name")
+VALUES
+  ($1, $2, $3)
+RETURNING
+  "service_name",
+  "role_name",
+  "permission_name",
+  "created_ts"
+	`
+
+	var dst RolePermission
+
+	queryResult := querier.QueryRow(ctx, query, rolePermission.ServiceName, rolePermission.UserRole,
+		rolePermission.Permission)
+	err := queryResult.Scan(&dst.ServiceName, &dst.UserRole, &dst.Permission, &dst.CreatedTS)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "fk_services_roles_permissions_role") {
+		return nil, ErrUnknownRole
+	}
+
+	if err != nil && strings.Contains(err.Error(), "fk_services_roles_permissions_permission") {
+		return nil, ErrUnknownPermission
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesPermissions.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableRolesPermissions) GetByRole(ctx context.Context, querier database.Querier,
+	serviceName, roleName string) ([]RolePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "permission_name",
+  "created_ts"
+FROM "services_roles_permissions"
+WHERE "service_name" = $1
+  AND "role_name" = $2
+ORDER BY "permission_name"
+	`
+
+	return collectRolePermissions(ctx, querier, "GetByRole", query, serviceName, roleName)
+}
+
+// GetByRoles returns the permissions granted by any of the roles, a permission may be listed several times.
+func (s implTableRolesPermissions) GetByRoles(ctx context.Context, querier database.Querier,
+	roles []UserRole) ([]RolePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	serviceNames := make([]string, 0, len(roles))
+	roleNames := make([]string, 0, len(roles))
+
+	for _, role := range roles {
+		serviceNames = append(serviceNames, role.ServiceName)
+		roleNames = append(roleNames, role.UserRole)
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "permission_name",
+  "created_ts"
+FROM "services_roles_permissions"
+WHERE ("service_name", "role_name") IN (
+  SELECT * FROM UNNEST($1::VARCHAR[], $2::VARCHAR[])
+)
+ORDER BY "service_name", "permission_name"
+	`
+
+	return collectRolePermissions(ctx, querier, "GetByRoles", query, serviceNames, roleNames)
+}
+
+func (s implTableRolesPermissions) GetAll(ctx context.Context, querier database.Querier) ([]RolePermission, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "permission_name",
+  "created_ts"
+FROM "services_roles_permissions"
+ORDER BY "service_name", "role_name", "permission_name"
+	`
+
+	return collectRolePermissions(ctx, querier, "GetAll", query)
+}
+
+func collectRolePermissions(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]RolePermission, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesPermissions.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (RolePermission, error) {
+		var nextDst RolePermission
+		err := row.Scan(&nextDst.ServiceName, &nextDst.UserRole, &nextDst.Permission, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesPermissions.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableRolesPermissions) Delete(ctx context.Context, querier database.Querier,
+	serviceName, roleName, permission string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "services_roles_permissions"
+WHERE "service_name" = $1
+  AND "role_name" = $2
+  AND "permission_name" = $3
+	`
+
+	result, err := querier.Exec(ctx, query, serviceName, roleName, permission)
+	if err != nil {
+		return fmt.Errorf("TableRolesPermissions.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add makes the role imply the other one. Both must be defined in the service's roles catalog,
+// ErrUnknownRole otherwise.
+func (s implTableRolesHierarchy) Add(ctx context.Context, querier database.Querier,
+	implication *RoleImplication) (*RoleImplication, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if implication == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "services_roles_hierarchy"
+  ("service_name",
+  "role_name",
+  "implied_role_name")
+VALUES
+  ($1, $2, $3)
+RETURNING
+  "service_name",
+  "role_name",
+  "implied_role_name",
+  "created_ts"
+	`
+
+	var dst RoleImplication
+
+	queryResult := querier.QueryRow(ctx, query, implication.ServiceName, implication.UserRole,
+		implication.ImpliedRole)
+	err := queryResult.Scan(&dst.ServiceName, &dst.UserRole, &dst.ImpliedRole, &dst.CreatedTS)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, ErrUnknownRole
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesHierarchy.Add failed on INSERT: %w", err)
+	}
+
+	return &dst, nil
+}
+
+func (s implTableRolesHierarchy) GetByServiceName(ctx context.Context, querier database.Querier,
+	serviceName string) ([]RoleImplication, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "implied_role_name",
+  "created_ts"
+FROM "services_roles_hierarchy"
+WHERE "service_name" = $1
+ORDER BY "role_name", "implied_role_name"
+	`
+
+	return collectRoleImplications(ctx, querier, "GetByServiceName", query, serviceName)
+}
+
+func (s implTableRolesHierarchy) GetByServiceNames(ctx context.Context, querier database.Querier,
+	serviceNames []string) ([]RoleImplication, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "implied_role_name",
+  "created_ts"
+FROM "services_roles_hierarchy"
+WHERE "service_name" = ANY($1::VARCHAR[])
+ORDER BY "service_name", "role_name", "implied_role_name"
+	`
+
+	return collectRoleImplications(ctx, querier, "GetByServiceNames", query, serviceNames)
+}
+
+func (s implTableRolesHierarchy) GetAll(ctx context.Context, querier database.Querier) ([]RoleImplication, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "service_name",
+  "role_name",
+  "implied_role_name",
+  "created_ts"
+FROM "services_roles_hierarchy"
+ORDER BY "service_name", "role_name", "implied_role_name"
+	`
+
+	return collectRoleImplications(ctx, querier, "GetAll", query)
+}
+
+func collectRoleImplications(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]RoleImplication, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesHierarchy.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (RoleImplication, error) {
+		var nextDst RoleImplication
+		err := row.Scan(&nextDst.ServiceName, &nextDst.UserRole, &nextDst.ImpliedRole, &nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableRolesHierarchy.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableRolesHierarchy) Delete(ctx context.Context, querier database.Querier,
+	serviceName, roleName, impliedRoleName string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "services_roles_hierarchy"
+WHERE "service_name" = $1
+  AND "role_name" = $2
+  AND "implied_role_name" = $3
+	`
+
+	result, err := querier.Exec(ctx, query, serviceName, roleName, impliedRoleName)
+	if err != nil {
+		return fmt.Errorf("TableRolesHierarchy.Delete failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add saves the key along with its roles, which must be defined in the services' roles catalogs,
+// ErrUnknownRole otherwise. Expected to be run in a transaction.
+func (s implTableAPIKeys) Add(ctx context.Context, querier database.Querier, key *APIKey) (*APIKey, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if key == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "api_keys"
+  ("user_id",
+  "name",
+  "hint",
+  "key_hash",
+  "expires_at")
+VALUES
+  ($1, $2, $3, $4, $5)
+RETURNING
+  "id",
+  "created_ts"
+	`
+
+	dst := *key
+	dst.Roles = slices.Clone(key.Roles)
+
+	err := querier.QueryRow(ctx, query, key.UserID, key.Name, key.Hint, key.KeyHash, key.ExpiresAt).
+		Scan(&dst.ID, &dst.CreatedTS)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return nil, database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableAPIKeys.Add failed on INSERT: %w", err)
+	}
+
+	if err = addAPIKeyRoles(ctx, querier, "Add", dst.ID, key.Roles); err != nil {
+		return nil, err
+	}
+
+	return &dst, nil
+}
+
+// Insert adds the key along with its roles as is, including the generated fields. Used to restore a dump.
+// Expected to be run in a transaction.
+func (s implTableAPIKeys) Insert(ctx context.Context, querier database.Querier, key *APIKey) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if key == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "api_keys"
+  ("id",
+  "user_id",
+  "name",
+  "hint",
+  "key_hash",
+  "created_ts",
+  "expires_at",
+  "last_used_ts")
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := querier.Exec(ctx, query, key.ID, key.UserID, key.Name, key.Hint, key.KeyHash, key.CreatedTS,
+		key.ExpiresAt, key.LastUsedTS)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableAPIKeys.Insert failed on INSERT: %w", err)
+	}
+
+	return addAPIKeyRoles(ctx, querier, "Insert", key.ID, key.Roles)
+}
+
+func addAPIKeyRoles(ctx context.Context, querier database.Querier, method, keyID string, roles []APIKeyRole) error {
+	serviceNames := make([]string, 0, len(roles))
+	roleNames := make([]string, 0, len(roles))
+
+	for _, role := range roles {
+		serviceNames = append(serviceNames, role.ServiceName)
+		roleNames = append(roleNames, role.UserRole)
+	}
+
+	query := `
+INSERT INTO "api_keys_roles"
+  ("api_key_id",
+  "service_name",
+  "user_role")
+SELECT
+  $1,
+  "roles"."service_name",
+  "roles"."user_role"
+FROM UNNEST($2::VARCHAR[], $3::VARCHAR[]) AS "roles"("service_name", "user_role")
+	`
+
+	_, err := querier.Exec(ctx, query, keyID, serviceNames, roleNames)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return ErrUnknownRole
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableAPIKeys.%s failed on INSERT roles: %w", method, err)
+	}
+
+	return nil
+}
+
+// GetByHash returns the key with the hash, expired or not.
+func (s implTableAPIKeys) GetByHash(ctx context.Context, querier database.Querier, keyHash string) (*APIKey, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := selectAPIKeysQuery + `
+WHERE "api_keys"."key_hash" = $1
+GROUP BY "api_keys"."id"
+	`
+
+	dst, err := collectAPIKeys(ctx, querier, "GetByHash", query, keyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dst) == 0 {
+		return nil, database.ErrNoRows
+	}
+
+	return &dst[0], nil
+}
+
+// GetByUserID returns the user's keys ordered by the name, the expired ones included.
+func (s implTableAPIKeys) GetByUserID(ctx context.Context, querier database.Querier,
+	userID string) ([]APIKey, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := selectAPIKeysQuery + `
+WHERE "api_keys"."user_id" = $1
+GROUP BY "api_keys"."id"
+ORDER BY "api_keys"."name"
+	`
+
+	return collectAPIKeys(ctx, querier, "GetByUserID", query, userID)
+}
+
+// GetAll returns the keys of all the users ordered by the user and the name, the expired ones included.
+func (s implTableAPIKeys) GetAll(ctx context.Context, querier database.Querier) ([]APIKey, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := selectAPIKeysQuery + `
+GROUP BY "api_keys"."id"
+ORDER BY "api_keys"."user_id", "api_keys"."name"
+	`
+
+	return collectAPIKeys(ctx, querier, "GetAll", query)
+}
+
+// selectAPIKeysQuery selects the keys along with their roles, expects a GROUP BY "api_keys"."id".
+const selectAPIKeysQuery = `
+SELECT
+  "api_keys"."id",
+  "api_keys"."user_id",
+  "api_keys"."name",
+  "api_keys"."hint",
+  "api_keys"."key_hash",
+  "api_keys"."created_ts",
+  "api_keys"."expires_at",
+  "api_keys"."last_used_ts",
+  COALESCE(ARRAY_AGG("api_keys_roles"."service_name" ORDER BY "api_keys_roles"."service_name",
+    "api_keys_roles"."user_role") FILTER (WHERE "api_keys_roles"."service_name" IS NOT NULL), '{}'),
+  COALESCE(ARRAY_AGG("api_keys_roles"."user_role" ORDER BY "api_keys_roles"."service_name",
+    "api_keys_roles"."user_role") FILTER (WHERE "api_keys_roles"."service_name" IS NOT NULL), '{}')
+FROM "api_keys"
+LEFT JOIN "api_keys_roles" ON "api_keys_roles"."api_key_id" = "api_keys"."id"`
+
+func collectAPIKeys(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]APIKey, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableAPIKeys.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (APIKey, error) {
+		var (
+			nextDst      APIKey
+			serviceNames []string
+			roleNames    []string
+		)
+
+		err := row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.Name, &nextDst.Hint, &nextDst.KeyHash,
+			&nextDst.CreatedTS, &nextDst.ExpiresAt, &nextDst.LastUsedTS, &serviceNames, &roleNames)
+
+		nextDst.Roles = make([]APIKeyRole, 0, len(serviceNames))
+		for i := range min(len(serviceNames), len(roleNames)) {
+			nextDst.Roles = append(nextDst.Roles, APIKeyRole{ServiceName: serviceNames[i], UserRole: roleNames[i]})
+		}
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableAPIKeys.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableAPIKeys) UpdateLastUsedByID(ctx context.Context, querier database.Querier, keyID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "api_keys"
+SET "last_used_ts" = NOW()
+WHERE "id" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, keyID)
+	if err != nil {
+		return fmt.Errorf("TableAPIKeys.UpdateLastUsedByID failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteByID revokes the user's key.
+func (s implTableAPIKeys) DeleteByID(ctx context.Context, querier database.Querier, userID, keyID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "api_keys"
+WHERE "id" = $1
+  AND "user_id" = $2
+	`
+
+	result, err := querier.Exec(ctx, query, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("TableAPIKeys.DeleteByID failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add saves the invitation along with its roles, which must be defined in the services' roles catalogs,
+// ErrUnknownRole otherwise. Expected to be run in a transaction.
+func (s implTableInvitations) Add(ctx context.Context, querier database.Querier,
+	invitation *Invitation) (*Invitation, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if invitation == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "invitations"
+  ("created_by",
+  "expires_at")
+VALUES
+  ($1, $2)
+RETURNING
+  "id",
+  "created_ts"
+	`
+
+	dst := *invitation
+	dst.Roles = slices.Clone(invitation.Roles)
+
+	err := querier.QueryRow(ctx, query, invitation.CreatedBy, invitation.ExpiresAt).Scan(&dst.ID, &dst.CreatedTS)
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return nil, database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("TableInvitations.Add failed on INSERT: %w", err)
+	}
+
+	if err = addInvitationRoles(ctx, querier, "Add", dst.ID, invitation.Roles); err != nil {
+		return nil, err
+	}
+
+	return &dst, nil
+}
+
+// Insert adds the invitation along with its roles as is, including the generated fields. Used to restore a dump.
+// Expected to be run in a transaction.
+func (s implTableInvitations) Insert(ctx context.Context, querier database.Querier, invitation *Invitation) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if invitation == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "invitations"
+  ("id",
+  "created_by",
+  "created_ts",
+  "expires_at",
+  "redeemed_by",
+  "redeemed_ts")
+VALUES
+  ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := querier.Exec(ctx, query, invitation.ID, invitation.CreatedBy, invitation.CreatedTS,
+		invitation.ExpiresAt, invitation.RedeemedBy, invitation.RedeemedTS)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableInvitations.Insert failed on INSERT: %w", err)
+	}
+
+	return addInvitationRoles(ctx, querier, "Insert", invitation.ID, invitation.Roles)
+}
+
+func addInvitationRoles(ctx context.Context, querier database.Querier, method, invitationID string,
+	roles []InvitationRole) error {
+	serviceNames := make([]string, 0, len(roles))
+	roleNames := make([]string, 0, len(roles))
+
+	for _, role := range roles {
+		serviceNames = append(serviceNames, role.ServiceName)
+		roleNames = append(roleNames, role.UserRole)
+	}
+
+	query := `
+INSERT INTO "invitations_roles"
+  ("invitation_id",
+  "service_name",
+  "user_role")
+SELECT
+  $1,
+  "roles"."service_name",
+  "roles"."user_role"
+FROM UNNEST($2::VARCHAR[], $3::VARCHAR[]) AS "roles"("service_name", "user_role")
+	`
+
+	_, err := querier.Exec(ctx, query, invitationID, serviceNames, roleNames)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if isUnknownRoleErr(err) {
+		return ErrUnknownRole
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableInvitations.%s failed on INSERT roles: %w", method, err)
+	}
+
+	return nil
+}
+
+// GetByID returns the invitation, redeemed, expired or pending.
+func (s implTableInvitations) GetByID(ctx context.Context, querier database.Querier,
+	invitationID string) (*Invitation, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := selectInvitationsQuery + `
+WHERE "invitations"."id" = $1
+GROUP BY "invitations"."id"
+	`
+
+	dst, err := collectInvitations(ctx, querier, "GetByID", query, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dst) == 0 {
+		return nil, database.ErrNoRows
+	}
+
+	return &dst[0], nil
+}
+
+// GetAll returns the invitations, the latest first.
+func (s implTableInvitations) GetAll(ctx context.Context, querier database.Querier) ([]Invitation, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := selectInvitationsQuery + `
+GROUP BY "invitations"."id"
+ORDER BY "invitations"."created_ts" DESC
+	`
+
+	return collectInvitations(ctx, querier, "GetAll", query)
+}
+
+// selectInvitationsQuery selects the invitations along with their roles,
+// expects a GROUP BY "invitations"."id".
+const selectInvitationsQuery = `
+SELECT
+  "invitations"."id",
+  "invitations"."created_by",
+  "invitations"."created_ts",
+  "invitations"."expires_at",
+  "invitations"."redeemed_by",
+  "invitations"."redeemed_ts",
+  COALESCE(ARRAY_AGG("invitations_roles"."service_name" ORDER BY "invitations_roles"."service_name",
+    "invitations_roles"."user_role") FILTER (WHERE "invitations_roles"."service_name" IS NOT NULL), '{}'),
+  COALESCE(ARRAY_AGG("invitations_roles"."user_role" ORDER BY "invitations_roles"."service_name",
+    "invitations_roles"."user_role") FILTER (WHERE "invitations_roles"."service_name" IS NOT NULL), '{}')
+FROM "invitations"
+LEFT JOIN "invitations_roles" ON "invitations_roles"."invitation_id" = "invitations"."id"`
+
+func collectInvitations(ctx context.Context, querier database.Querier, method, query string,
+	args ...any) ([]Invitation, error) {
+	queryResult, err := querier.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("TableInvitations.%s failed on SELECT: %w", method, err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (Invitation, error) {
+		var (
+			nextDst      Invitation
+			serviceNames []string
+			roleNames    []string
+		)
+
+		err := row.Scan(&nextDst.ID, &nextDst.CreatedBy, &nextDst.CreatedTS, &nextDst.ExpiresAt,
+			&nextDst.RedeemedBy, &nextDst.RedeemedTS, &serviceNames, &roleNames)
+
+		nextDst.Roles = make([]InvitationRole, 0, len(serviceNames))
+		for i := range min(len(serviceNames), len(roleNames)) {
+			nextDst.Roles = append(nextDst.Roles, InvitationRole{ServiceName: serviceNames[i], UserRole: roleNames[i]})
+		}
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableInvitations.%s failed on Scan: %w", method, err)
+	}
+
+	return dst, nil
+}
+
+// Redeem marks the pending invitation as redeemed by the user, ErrNoRows if it is not pending.
+// A concurrent redeem of the same invitation waits for this one and gets ErrNoRows.
+func (s implTableInvitations) Redeem(ctx context.Context, querier database.Querier,
+	invitationID, userID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "invitations"
+SET "redeemed_by" = $2,
+  "redeemed_ts" = NOW()
+WHERE "id" = $1
+  AND "redeemed_ts" IS NULL
+  AND "expires_at" > NOW()
+	`
+
+	result, err := querier.Exec(ctx, query, invitationID, userID)
+	if err != nil {
+		return fmt.Errorf("TableInvitations.Redeem failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteByID revokes the pending invitation, the redeemed ones are kept.
+func (s implTableInvitations) DeleteByID(ctx context.Context, querier database.Querier, invitationID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "invitations"
+WHERE "id" = $1
+  AND "redeemed_ts" IS NULL
+	`
+
+	result, err := querier.Exec(ctx, query, invitationID)
+	if err != nil {
+		return fmt.Errorf("TableInvitations.DeleteByID failed on DELETE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// Add appends the event to the audit log, the ID and the CreatedTS are set by the database.
+func (s implTableAuditLog) Add(ctx context.Context, querier database.Querier, event *AuditEvent) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if event == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "audit_log"
+  ("actor_id",
+  "actor",
+  "action",
+  "target",
+  "ip",
+  "user_agent",
+  "outcome",
+  "details")
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := querier.Exec(ctx, query, event.ActorID, event.Actor, event.Action, event.Target, event.IP,
+		event.UserAgent, event.Outcome, event.Details)
+	if err != nil {
+		return fmt.Errorf("TableAuditLog.Add failed: %w", err)
+	}
+
+	return nil
+}
+
+// List returns a page of the events matching the filter, the newest first.
+func (s implTableAuditLog) List(ctx context.Context, querier database.Querier,
+	filter *AuditEventFilter) ([]AuditEvent, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if filter == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	var (
+		conditions = []string{"TRUE"}
+		args       []any
+	)
+
+	addArg := func(arg any) string {
+		args = append(args, arg)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, match := range [][2]string{
+		{`"actor"`, filter.Actor},
+		{`"action"`, filter.Action},
+		{`"target"`, filter.Target},
+		{`"outcome"`, filter.Outcome},
+		{`"ip"`, filter.IP},
+	} {
+		if match[1] != "" {
+			conditions = append(conditions, match[0]+" = "+addArg(match[1]))
+		}
+	}
+
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, `"created_ts" >= `+addArg(*filter.CreatedAfter))
+	}
+
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, `"created_ts" < `+addArg(*filter.CreatedBefore))
+	}
+
+	if filter.BeforeID != nil {
+		conditions = append(conditions, `"id" < `+addArg(*filter.BeforeID))
+	}
+
+	query := `
+SELECT
+  "id",
+  "created_ts",
+  "actor_id",
+  "actor",
+  "action",
+  "target",
+  "ip",
+  "user_agent",
+  "outcome",
+  "details"
+FROM "audit_log"
+WHERE ` + strings.Join(conditions, "\n  AND ") + `
+ORDER BY "id" DESC
+LIMIT ` + addArg(filter.Limit)
+
+	queryResult, err := querier.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("TableUsersGroups.GetByUserID failed on SELECT: %w", err)
+		return nil, fmt.Errorf("TableAuditLog.List failed on SELECT: %w", err)
 	}
 
-	dst, err = pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (UserRole, error) {
-		var nextDst UserRole
-		err = row.Scan(&nextDst.ID, &nextDst.UserID, &nextDst.UserRole, &nextDst.ServiceName, &nextDst.CreatedTS)
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (AuditEvent, error) {
+		var nextDst AuditEvent
+
+		err := row.Scan(&nextDst.ID, &nextDst.CreatedTS, &nextDst.ActorID, &nextDst.Actor, &nextDst.Action,
+			&nextDst.Target, &nextDst.IP, &nextDst.UserAgent, &nextDst.Outcome, &nextDst.Details)
 
 		return nextDst, err //nolint:wrapcheck // not an actual return
 	})
 	if err != nil {
-		return nil, fmt.Errorf("TableUsersGroups.GetByUserID failed on Scan: %w", err)
+		return nil, fmt.Errorf("TableAuditLog.List failed on Scan: %w", err)
 	}
 
 	return dst, nil
 }
 
-func (s implTableUsersRoles) GetByID(ctx context.Context, querier database.Querier, dbEntryID uint) (*UserRole, error) {
+// Add subscribes the webhook, the ID and the CreatedTS are set by the database.
+func (s implTableWebhooks) Add(ctx context.Context, querier database.Querier, webhook *Webhook) (*Webhook, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	if webhook == nil {
+		return nil, database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "webhooks"
+  ("url",
+  "secret",
+  "events",
+  "created_by")
+VALUES ($1, $2, $3, $4)
+RETURNING
+  "id",
+  "created_ts"
+	`
+
+	dst := *webhook
+
+	err := querier.QueryRow(ctx, query, webhook.URL, webhook.Secret, webhook.Events, webhook.CreatedBy).
+		Scan(&dst.ID, &dst.CreatedTS)
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhooks.Add failed: %w", err)
+	}
+
+	return &dst, nil
+}
+
+// Insert subscribes the webhook as is, including the generated fields. Used to restore a dump.
+func (s implTableWebhooks) Insert(ctx context.Context, querier database.Querier, webhook *Webhook) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	if webhook == nil {
+		return database.ErrNilArgument
+	}
+
+	query := `
+INSERT INTO "webhooks"
+  ("id",
+  "url",
+  "secret",
+  "events",
+  "created_by",
+  "created_ts")
+VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	_, err := querier.Exec(ctx, query, webhook.ID, webhook.URL, webhook.Secret, events, webhook.CreatedBy,
+		webhook.CreatedTS)
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return database.ErrUniqueKeyViolation
+	}
+
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return database.ErrForeignKeyViolation
+	}
+
+	if err != nil {
+		return fmt.Errorf("TableWebhooks.Insert failed on INSERT: %w", err)
+	}
+
+	return nil
+}
+
+// GetAll returns the webhooks, the latest first.
+func (s implTableWebhooks) GetAll(ctx context.Context, querier database.Querier) ([]Webhook, error) {
 	if querier == nil {
 		return nil, database.ErrDBNotInitilized
 	}
@@ -426,43 +3366,266 @@ func (s implTableUsersRoles) GetByID(ctx context.Context, querier database.Queri
 	query := `
 SELECT
   "id",
-  "user_id",
-  "user_role",
-  "service_name",
+  "url",
+  "secret",
+  "events",
+  "created_by",
   "created_ts"
-FROM "users_roles"
+FROM "webhooks"
+ORDER BY "created_ts" DESC
+	`
+
+	queryResult, err := querier.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhooks.GetAll failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (Webhook, error) {
+		var nextDst Webhook
+
+		err := row.Scan(&nextDst.ID, &nextDst.URL, &nextDst.Secret, &nextDst.Events, &nextDst.CreatedBy,
+			&nextDst.CreatedTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhooks.GetAll failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// DeleteByID removes the webhook along with its outbox.
+func (s implTableWebhooks) DeleteByID(ctx context.Context, querier database.Querier, webhookID string) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "webhooks"
 WHERE "id" = $1
 	`
 
-	var dst UserRole
+	result, err := querier.Exec(ctx, query, webhookID)
+	if err != nil {
+		return fmt.Errorf("TableWebhooks.DeleteByID failed on DELETE: %w", err)
+	}
 
-	queryResult := querier.QueryRow(ctx, query, dbEntryID)
-	err := queryResult.Scan(&dst.ID, &dst.UserID, &dst.UserRole, &dst.ServiceName, &dst.CreatedTS)
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, database.ErrNoRows
+	return nil
+}
+
+// Enqueue adds the event to the outbox of every webhook subscribed to the event type.
+// Enqueue within the transaction of the change, so the event is only delivered if the change is committed.
+func (s implTableWebhookDeliveries) Enqueue(ctx context.Context, querier database.Querier, eventType string,
+	payload []byte) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
 	}
 
+	query := `
+INSERT INTO "webhook_deliveries"
+  ("webhook_id",
+  "event_type",
+  "payload")
+SELECT
+  "id",
+  $1::VARCHAR,
+  $2::JSONB
+FROM "webhooks"
+WHERE CARDINALITY("events") = 0
+  OR $1::VARCHAR = ANY("events")
+	`
+
+	_, err := querier.Exec(ctx, query, eventType, string(payload))
 	if err != nil {
-		return nil, fmt.Errorf("TableServices.GetByID failed on SELECT: %w", err)
+		return fmt.Errorf("TableWebhookDeliveries.Enqueue failed: %w", err)
 	}
 
-	return &dst, nil
+	return nil
 }
 
-func (s implTableUsersRoles) DeleteByID(ctx context.Context, querier database.Querier, dbEntryID uint) error {
+// Claim takes the due pending deliveries, the oldest first, and leases them: the deliveries are not due again
+// until the lease is over, so they are retried if their attempt is never marked. The claimed deliveries are
+// skipped by the concurrent claims.
+func (s implTableWebhookDeliveries) Claim(ctx context.Context, querier database.Querier, limit int,
+	lease time.Duration) ([]WebhookDelivery, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+WITH "claimed" AS (
+  UPDATE "webhook_deliveries"
+  SET "next_attempt_ts" = NOW() + $2::INTERVAL
+  WHERE "id" IN (
+    SELECT "id"
+    FROM "webhook_deliveries"
+    WHERE "delivered_ts" IS NULL
+      AND "dead_ts" IS NULL
+      AND "next_attempt_ts" <= NOW()
+    ORDER BY "id"
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED)
+  RETURNING *
+)
+SELECT
+  "claimed"."id",
+  "claimed"."webhook_id",
+  "claimed"."event_type",
+  "claimed"."payload",
+  "claimed"."created_ts",
+  "claimed"."attempts",
+  "claimed"."next_attempt_ts",
+  "claimed"."last_error",
+  "webhooks"."url",
+  "webhooks"."secret"
+FROM "claimed"
+JOIN "webhooks" ON "webhooks"."id" = "claimed"."webhook_id"
+ORDER BY "claimed"."id"
+	`
+
+	queryResult, err := querier.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhookDeliveries.Claim failed on UPDATE: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (WebhookDelivery, error) {
+		var nextDst WebhookDelivery
+
+		err := row.Scan(&nextDst.ID, &nextDst.WebhookID, &nextDst.EventType, &nextDst.Payload, &nextDst.CreatedTS,
+			&nextDst.Attempts, &nextDst.NextAttemptTS, &nextDst.LastError, &nextDst.URL, &nextDst.Secret)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhookDeliveries.Claim failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (s implTableWebhookDeliveries) MarkDelivered(ctx context.Context, querier database.Querier,
+	deliveryID int64) error {
 	if querier == nil {
 		return database.ErrDBNotInitilized
 	}
 
 	query := `
-DELETE FROM "users_roles"
+UPDATE "webhook_deliveries"
+SET
+  "attempts" = "attempts" + 1,
+  "delivered_ts" = NOW()
 WHERE "id" = $1
-		`
+	`
 
-	result, err := querier.Exec(ctx, query, dbEntryID)
+	result, err := querier.Exec(ctx, query, deliveryID)
 	if err != nil {
-		return fmt.Errorf("TableUsersGroups.DeleteByID failed on DELETE: %w", err)
+		return fmt.Errorf("TableWebhookDeliveries.MarkDelivered failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// MarkFailed counts the failed attempt, the delivery is retried at retryAt, or is dead if retryAt is nil.
+func (s implTableWebhookDeliveries) MarkFailed(ctx context.Context, querier database.Querier, deliveryID int64,
+	lastError string, retryAt *time.Time) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "webhook_deliveries"
+SET
+  "attempts" = "attempts" + 1,
+  "last_error" = LEFT($2, 1000),
+  "next_attempt_ts" = COALESCE($3, "next_attempt_ts"),
+  "dead_ts" = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN NOW() END
+WHERE "id" = $1
+	`
+
+	result, err := querier.Exec(ctx, query, deliveryID, lastError, retryAt)
+	if err != nil {
+		return fmt.Errorf("TableWebhookDeliveries.MarkFailed failed on UPDATE: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return database.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetDeadByWebhookID returns the dead letters of the webhook, the latest first.
+func (s implTableWebhookDeliveries) GetDeadByWebhookID(ctx context.Context, querier database.Querier,
+	webhookID string) ([]WebhookDelivery, error) {
+	if querier == nil {
+		return nil, database.ErrDBNotInitilized
+	}
+
+	query := `
+SELECT
+  "id",
+  "webhook_id",
+  "event_type",
+  "payload",
+  "created_ts",
+  "attempts",
+  "last_error",
+  "dead_ts"
+FROM "webhook_dead_letters"
+WHERE "webhook_id" = $1
+ORDER BY "id" DESC
+	`
+
+	queryResult, err := querier.Query(ctx, query, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhookDeliveries.GetDeadByWebhookID failed on SELECT: %w", err)
+	}
+
+	dst, err := pgx.CollectRows(queryResult, func(row pgx.CollectableRow) (WebhookDelivery, error) {
+		var nextDst WebhookDelivery
+
+		err := row.Scan(&nextDst.ID, &nextDst.WebhookID, &nextDst.EventType, &nextDst.Payload, &nextDst.CreatedTS,
+			&nextDst.Attempts, &nextDst.LastError, &nextDst.DeadTS)
+
+		return nextDst, err //nolint:wrapcheck // not an actual return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TableWebhookDeliveries.GetDeadByWebhookID failed on Scan: %w", err)
+	}
+
+	return dst, nil
+}
+
+// RetryDead makes the dead letter of the webhook pending again, with the attempts counted anew.
+func (s implTableWebhookDeliveries) RetryDead(ctx context.Context, querier database.Querier, webhookID string,
+	deliveryID int64) error {
+	if querier == nil {
+		return database.ErrDBNotInitilized
+	}
+
+	query := `
+UPDATE "webhook_deliveries"
+SET
+  "attempts" = 0,
+  "next_attempt_ts" = NOW(),
+  "dead_ts" = NULL
+WHERE "id" = $1
+  AND "webhook_id" = $2
+  AND "dead_ts" IS NOT NULL
+	`
+
+	result, err := querier.Exec(ctx, query, deliveryID, webhookID)
+	if err != nil {
+		return fmt.Errorf("TableWebhookDeliveries.RetryDead failed on UPDATE: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
@@ -471,3 +3634,23 @@ WHERE "id" = $1
 
 	return nil
 }
+
+// PurgeDelivered removes the deliveries delivered before the moment, the dead letters are kept.
+func (s implTableWebhookDeliveries) PurgeDelivered(ctx context.Context, querier database.Querier,
+	deliveredBefore time.Time) (int64, error) {
+	if querier == nil {
+		return 0, database.ErrDBNotInitilized
+	}
+
+	query := `
+DELETE FROM "webhook_deliveries"
+WHERE "delivered_ts" < $1
+	`
+
+	result, err := querier.Exec(ctx, query, deliveredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("TableWebhookDeliveries.PurgeDelivered failed on DELETE: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,618 @@
+// Package backup dumps the full go-auth state to the model.StateDump and restores it.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported state dump version")
+	ErrNotEmpty           = errors.New("the database is not empty")
+)
+
+// Export reads the state in a single read only transaction, so the dump is consistent.
+// The keys are not a part of the database, they are left for the caller.
+func Export(ctx context.Context, dbInstance *database.Database) (*model.StateDump, error) {
+	tx, err := dbInstance.BeginTx(ctx, pgx.TxOptions{ //nolint:exhaustruct // the defaults.
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // read only.
+
+	services, err := storage.TableServices.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	serviceRoles, err := storage.TableServicesRoles.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	permissions, err := storage.TableServicesPermissions.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	rolePermissions, err := storage.TableRolesPermissions.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	implications, err := storage.TableRolesHierarchy.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	definitions, err := storage.TableAttributeDefinitions.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	users, err := storage.TableUsers.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	roles, err := storage.TableUsersRoles.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	apiKeys, err := storage.TableAPIKeys.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	groups, err := exportGroups(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := storage.TableInvitations.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	webhooks, err := storage.TableWebhooks.GetAll(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	dump := prepareDump(services, serviceRoles, users, roles)
+	addAPIKeys(dump.Users, apiKeys)
+	addPermissions(dump.Services, permissions, rolePermissions)
+	addHierarchy(dump.Services, implications)
+	dump.Attributes = model.PrepareAttributeDefinitions(definitions)
+	dump.Groups = groups
+	dump.Invitations = prepareInvitations(invitations)
+	dump.Webhooks = prepareWebhooks(webhooks)
+
+	return dump, nil
+}
+
+// Restore writes the dump to an empty database in a single transaction.
+func Restore(ctx context.Context, dbInstance *database.Database, dump *model.StateDump) error {
+	if dump == nil {
+		return database.ErrNilArgument
+	}
+
+	if dump.Version != model.StateDumpVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, dump.Version)
+	}
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	if err = checkEmpty(ctx, tx); err != nil {
+		return err
+	}
+
+	for _, service := range dump.Services {
+		if err = restoreService(ctx, tx, &service); err != nil {
+			return err
+		}
+	}
+
+	for _, attribute := range dump.Attributes {
+		definition := attribute.Definition()
+
+		err = storage.TableAttributeDefinitions.Add(ctx, tx, &definition)
+		if err != nil {
+			return fmt.Errorf("backup.Restore attribute %s: %w", attribute.Name, err)
+		}
+	}
+
+	for _, user := range dump.Users {
+		if err = restoreUser(ctx, tx, &user); err != nil {
+			return err
+		}
+	}
+
+	for _, group := range dump.Groups {
+		if err = restoreGroup(ctx, tx, &group); err != nil {
+			return err
+		}
+	}
+
+	for _, invitation := range dump.Invitations {
+		if err = restoreInvitation(ctx, tx, &invitation); err != nil {
+			return err
+		}
+	}
+
+	for _, webhook := range dump.Webhooks {
+		err = storage.TableWebhooks.Insert(ctx, tx, &storage.Webhook{
+			CreatedTS: webhook.CreatedTS,
+			CreatedBy: webhook.CreatedBy,
+			ID:        webhook.ID,
+			URL:       webhook.URL,
+			Secret:    webhook.Secret,
+			Events:    webhook.Events,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore webhook %s: %w", webhook.ID, err)
+		}
+	}
+
+	if err = storage.TableUsersRoles.ResetIDSequence(ctx, tx); err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("backup.Restore commit: %w", err)
+	}
+
+	return nil
+}
+
+func checkEmpty(ctx context.Context, querier database.Querier) error {
+	usersCount, err := storage.TableUsers.CountAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	services, err := storage.TableServices.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	definitions, err := storage.TableAttributeDefinitions.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	groups, err := storage.TableGroups.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	invitations, err := storage.TableInvitations.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	webhooks, err := storage.TableWebhooks.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("backup.Restore: %w", err)
+	}
+
+	if usersCount > 0 || len(services) > 0 || len(definitions) > 0 || len(groups) > 0 || len(invitations) > 0 ||
+		len(webhooks) > 0 {
+		return ErrNotEmpty
+	}
+
+	return nil
+}
+
+func restoreService(ctx context.Context, querier database.Querier, service *model.DumpService) error {
+	err := storage.TableServices.Add(ctx, querier, &storage.Service{Name: service.Name})
+	if err == nil && len(service.Roles) == 0 {
+		err = storage.TableServicesRoles.AddDefaults(ctx, querier, service.Name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("backup.Restore service %s: %w", service.Name, err)
+	}
+
+	for _, role := range service.Roles {
+		_, err = storage.TableServicesRoles.Add(ctx, querier, &storage.ServiceRole{
+			ServiceName: service.Name,
+			Name:        role.Name,
+			Description: role.Description,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore role %s of service %s: %w", role.Name, service.Name, err)
+		}
+	}
+
+	for _, permission := range service.Permissions {
+		_, err = storage.TableServicesPermissions.Add(ctx, querier, &storage.ServicePermission{
+			ServiceName: service.Name,
+			Name:        permission.Name,
+			Description: permission.Description,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore permission %s of service %s: %w", permission.Name, service.Name, err)
+		}
+	}
+
+	for roleName, permissions := range service.RolePermissions {
+		for _, permission := range permissions {
+			_, err = storage.TableRolesPermissions.Add(ctx, querier, &storage.RolePermission{
+				ServiceName: service.Name,
+				UserRole:    roleName,
+				Permission:  permission,
+			})
+			if err != nil {
+				return fmt.Errorf("backup.Restore permission %s of role %s of service %s: %w", permission,
+					roleName, service.Name, err)
+			}
+		}
+	}
+
+	// the services restored with the default roles got the default hierarchy already.
+	if len(service.Roles) == 0 {
+		return nil
+	}
+
+	for roleName, impliedRoles := range service.Hierarchy {
+		for _, impliedRole := range impliedRoles {
+			_, err = storage.TableRolesHierarchy.Add(ctx, querier, &storage.RoleImplication{
+				ServiceName: service.Name,
+				UserRole:    roleName,
+				ImpliedRole: impliedRole,
+			})
+			if err != nil {
+				return fmt.Errorf("backup.Restore implied role %s of role %s of service %s: %w", impliedRole,
+					roleName, service.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func restoreUser(ctx context.Context, querier database.Querier, user *model.DumpUser) error {
+	err := storage.TableUsers.Insert(ctx, querier, &storage.User{
+		CreatedTS:       user.CreatedTS,
+		StatusChangedTS: user.StatusChangedTS,
+		DeletedTS:       user.DeletedTS,
+		LastLoginTS:     user.LastLoginTS,
+		Attributes:      user.Attributes,
+		AddUser: storage.AddUser{
+			Username: user.Username,
+			Password: user.PasswordHash,
+		},
+		ID:           user.ID,
+		Status:       user.Status,
+		StatusReason: user.StatusReason,
+	})
+	if err != nil {
+		return fmt.Errorf("backup.Restore user %s: %w", user.Username, err)
+	}
+
+	for _, role := range user.Roles {
+		err = storage.TableUsersRoles.Insert(ctx, querier, &storage.UserRole{
+			CreatedTS: role.CreatedTS,
+			AddUserRole: storage.AddUserRole{
+				ValidFrom:   role.ValidFrom,
+				ExpiresAt:   role.ExpiresAt,
+				UserID:      user.ID,
+				UserRole:    role.UserRole,
+				ServiceName: role.ServiceName,
+			},
+			ID: role.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore role of %s in %s: %w", user.Username, role.ServiceName, err)
+		}
+	}
+
+	for _, key := range user.APIKeys {
+		keyRoles := make([]storage.APIKeyRole, 0, len(key.Roles))
+		for _, role := range key.Roles {
+			keyRoles = append(keyRoles, storage.APIKeyRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+		}
+
+		err = storage.TableAPIKeys.Insert(ctx, querier, &storage.APIKey{
+			CreatedTS:  key.CreatedTS,
+			ExpiresAt:  key.ExpiresAt,
+			LastUsedTS: key.LastUsedTS,
+			ID:         key.ID,
+			UserID:     user.ID,
+			Name:       key.Name,
+			Hint:       key.Hint,
+			KeyHash:    key.KeyHash,
+			Roles:      keyRoles,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore API key %s of %s: %w", key.Name, user.Username, err)
+		}
+	}
+
+	return nil
+}
+
+func restoreGroup(ctx context.Context, querier database.Querier, group *model.DumpGroup) error {
+	_, err := storage.TableGroups.Add(ctx, querier, group.Name)
+	if err != nil {
+		return fmt.Errorf("backup.Restore group %s: %w", group.Name, err)
+	}
+
+	for _, role := range group.Roles {
+		_, err = storage.TableGroupsRoles.Add(ctx, querier, &storage.AddGroupRole{
+			GroupName:   group.Name,
+			UserRole:    role.UserRole,
+			ServiceName: role.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("backup.Restore role of group %s in %s: %w", group.Name, role.ServiceName, err)
+		}
+	}
+
+	for _, userID := range group.Members {
+		if err = storage.TableGroupsUsers.Add(ctx, querier, group.Name, userID); err != nil {
+			return fmt.Errorf("backup.Restore member %s of group %s: %w", userID, group.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func restoreInvitation(ctx context.Context, querier database.Querier, invitation *model.DumpInvitation) error {
+	roles := make([]storage.InvitationRole, 0, len(invitation.Roles))
+	for _, role := range invitation.Roles {
+		roles = append(roles, storage.InvitationRole{ServiceName: role.ServiceName, UserRole: role.UserRole})
+	}
+
+	err := storage.TableInvitations.Insert(ctx, querier, &storage.Invitation{
+		CreatedTS:  invitation.CreatedTS,
+		ExpiresAt:  invitation.ExpiresAt,
+		RedeemedTS: invitation.RedeemedTS,
+		CreatedBy:  invitation.CreatedBy,
+		RedeemedBy: invitation.RedeemedBy,
+		ID:         invitation.ID,
+		Roles:      roles,
+	})
+	if err != nil {
+		return fmt.Errorf("backup.Restore invitation %s: %w", invitation.ID, err)
+	}
+
+	return nil
+}
+
+func prepareInvitations(invitations []storage.Invitation) []model.DumpInvitation {
+	dumpInvitations := make([]model.DumpInvitation, 0, len(invitations))
+
+	for _, invitation := range invitations {
+		roles := make([]model.UserRoleRequest, 0, len(invitation.Roles))
+		for _, role := range invitation.Roles {
+			roles = append(roles, model.UserRoleRequest{ServiceName: role.ServiceName, UserRole: role.UserRole})
+		}
+
+		dumpInvitations = append(dumpInvitations, model.DumpInvitation{
+			CreatedTS:  invitation.CreatedTS,
+			ExpiresAt:  invitation.ExpiresAt,
+			RedeemedTS: invitation.RedeemedTS,
+			CreatedBy:  invitation.CreatedBy,
+			RedeemedBy: invitation.RedeemedBy,
+			ID:         invitation.ID,
+			Roles:      roles,
+		})
+	}
+
+	return dumpInvitations
+}
+
+func prepareWebhooks(webhooks []storage.Webhook) []model.DumpWebhook {
+	dumpWebhooks := make([]model.DumpWebhook, 0, len(webhooks))
+
+	for _, webhook := range webhooks {
+		dumpWebhooks = append(dumpWebhooks, model.DumpWebhook{
+			CreatedTS: webhook.CreatedTS,
+			CreatedBy: webhook.CreatedBy,
+			ID:        webhook.ID,
+			URL:       webhook.URL,
+			Secret:    webhook.Secret,
+			Events:    webhook.Events,
+		})
+	}
+
+	return dumpWebhooks
+}
+
+func exportGroups(ctx context.Context, querier database.Querier) ([]model.DumpGroup, error) {
+	groups, err := storage.TableGroups.GetAll(ctx, querier)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	roles, err := storage.TableGroupsRoles.GetAll(ctx, querier)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	members, err := storage.TableGroupsUsers.GetAll(ctx, querier)
+	if err != nil {
+		return nil, fmt.Errorf("backup.Export: %w", err)
+	}
+
+	dumpGroups := make([]model.DumpGroup, 0, len(groups))
+	groupIdx := make(map[string]int, len(groups))
+
+	for i, group := range groups {
+		groupIdx[group.Name] = i
+		dumpGroups = append(dumpGroups, model.DumpGroup{
+			Name:    group.Name,
+			Roles:   []model.UserRoleRequest{},
+			Members: []string{},
+		})
+	}
+
+	for _, role := range roles {
+		dumpGroup := &dumpGroups[groupIdx[role.GroupName]]
+		dumpGroup.Roles = append(dumpGroup.Roles, model.UserRoleRequest{
+			ServiceName: role.ServiceName,
+			UserRole:    role.UserRole,
+		})
+	}
+
+	for _, member := range members {
+		dumpGroup := &dumpGroups[groupIdx[member.GroupName]]
+		dumpGroup.Members = append(dumpGroup.Members, member.UserID)
+	}
+
+	return dumpGroups, nil
+}
+
+// addPermissions fills the services' permissions catalogs and the permissions of their roles.
+func addPermissions(services []model.DumpService, permissions []storage.ServicePermission,
+	rolePermissions []storage.RolePermission) {
+	serviceIdx := make(map[string]int, len(services))
+	for i, service := range services {
+		serviceIdx[service.Name] = i
+	}
+
+	for _, permission := range permissions {
+		service := &services[serviceIdx[permission.ServiceName]]
+		service.Permissions = append(service.Permissions, model.ServicePermissionRequest{
+			Name:        permission.Name,
+			Description: permission.Description,
+		})
+	}
+
+	for _, rolePermission := range rolePermissions {
+		service := &services[serviceIdx[rolePermission.ServiceName]]
+		if service.RolePermissions == nil {
+			service.RolePermissions = make(map[string][]string)
+		}
+
+		service.RolePermissions[rolePermission.UserRole] = append(service.RolePermissions[rolePermission.UserRole],
+			rolePermission.Permission)
+	}
+}
+
+// addAPIKeys fills the users' API keys.
+func addAPIKeys(users []model.DumpUser, keys []storage.APIKey) {
+	userIdx := make(map[string]int, len(users))
+	for i, user := range users {
+		userIdx[user.ID] = i
+	}
+
+	for _, key := range keys {
+		keyRoles := make([]model.UserRoleRequest, 0, len(key.Roles))
+		for _, role := range key.Roles {
+			keyRoles = append(keyRoles, model.UserRoleRequest{ServiceName: role.ServiceName, UserRole: role.UserRole})
+		}
+
+		user := &users[userIdx[key.UserID]]
+		user.APIKeys = append(user.APIKeys, model.DumpAPIKey{
+			CreatedTS:  key.CreatedTS,
+			ExpiresAt:  key.ExpiresAt,
+			LastUsedTS: key.LastUsedTS,
+			ID:         key.ID,
+			Name:       key.Name,
+			Hint:       key.Hint,
+			KeyHash:    key.KeyHash,
+			Roles:      keyRoles,
+		})
+	}
+}
+
+// addHierarchy fills the roles hierarchy of the services.
+func addHierarchy(services []model.DumpService, implications []storage.RoleImplication) {
+	serviceIdx := make(map[string]int, len(services))
+	for i, service := range services {
+		serviceIdx[service.Name] = i
+	}
+
+	for _, implication := range implications {
+		service := &services[serviceIdx[implication.ServiceName]]
+		if service.Hierarchy == nil {
+			service.Hierarchy = make(map[string][]string)
+		}
+
+		service.Hierarchy[implication.UserRole] = append(service.Hierarchy[implication.UserRole],
+			implication.ImpliedRole)
+	}
+}
+
+func prepareDump(services []storage.Service, serviceRoles []storage.ServiceRole, users []storage.User,
+	roles []storage.UserRole) *model.StateDump {
+	dump := &model.StateDump{
+		CreatedTS:   time.Now().UTC(),
+		Keys:        nil,
+		Services:    make([]model.DumpService, 0, len(services)),
+		Attributes:  nil,
+		Users:       make([]model.DumpUser, 0, len(users)),
+		Groups:      nil,
+		Invitations: nil,
+		Webhooks:    nil,
+		Version:     model.StateDumpVersion,
+	}
+
+	catalogs := make(map[string][]model.ServiceRoleRequest, len(services))
+	for _, role := range serviceRoles {
+		catalogs[role.ServiceName] = append(catalogs[role.ServiceName], model.ServiceRoleRequest{
+			Name:        role.Name,
+			Description: role.Description,
+		})
+	}
+
+	for _, service := range services {
+		dump.Services = append(dump.Services, model.DumpService{Name: service.Name, Roles: catalogs[service.Name]})
+	}
+
+	rolesByUser := make(map[string][]model.DumpUserRole, len(users))
+	for _, role := range roles {
+		rolesByUser[role.UserID] = append(rolesByUser[role.UserID], model.DumpUserRole{
+			CreatedTS:   role.CreatedTS,
+			ValidFrom:   role.ValidFrom,
+			ExpiresAt:   role.ExpiresAt,
+			ServiceName: role.ServiceName,
+			UserRole:    role.UserRole,
+			ID:          role.ID,
+		})
+	}
+
+	for _, user := range users {
+		userRoles := rolesByUser[user.ID]
+		if userRoles == nil {
+			userRoles = []model.DumpUserRole{}
+		}
+
+		dump.Users = append(dump.Users, model.DumpUser{
+			CreatedTS:       user.CreatedTS,
+			StatusChangedTS: user.StatusChangedTS,
+			DeletedTS:       user.DeletedTS,
+			LastLoginTS:     user.LastLoginTS,
+			Attributes:      user.Attributes,
+			ID:              user.ID,
+			Username:        user.Username,
+			PasswordHash:    user.Password,
+			Status:          user.Status,
+			StatusReason:    user.StatusReason,
+			Roles:           userRoles,
+			APIKeys:         nil,
+		})
+	}
+
+	return dump
+}
@@ -0,0 +1,209 @@
+// Package bootstrap creates the first go-auth root of a fresh deployment.
+package bootstrap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+var (
+	ErrRootExists       = errors.New("the go-auth root already exists")
+	ErrBadCredentials   = errors.New("the credentials are not valid")
+	ErrWrongSetupToken  = errors.New("the setup token is not valid")
+	ErrSetupTokenIsUsed = errors.New("the setup token has been used")
+)
+
+const setupTokenBytes = 32
+
+// NeedsRoot tests if no user nor group is granted the go-auth root.
+func NeedsRoot(ctx context.Context, querier database.Querier) (bool, error) {
+	grants, err := storage.TableServicesRoles.CountGrants(ctx, querier, model.MyOwnServiceName,
+		storage.UserRoleTypeRoot)
+	if err != nil {
+		return false, fmt.Errorf("NeedsRoot: %w", err)
+	}
+
+	return grants == 0, nil
+}
+
+// CreateRoot creates the go-auth service along with its default roles and permissions catalog if missing,
+// and the user granted the go-auth root. ErrRootExists if there is a root already.
+// The grants are locked while the root is checked and created, two setups can't both create a root.
+func CreateRoot(ctx context.Context, dbInstance *database.Database, creds model.UserCreds) (*storage.User, error) {
+	if !creds.ValidFormat() {
+		return nil, ErrBadCredentials
+	}
+
+	hashedPassword, err := encrypt.PasswordEncrypt(creds.Password)
+	if err != nil {
+		return nil, fmt.Errorf("CreateRoot: %w", err)
+	}
+
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CreateRoot: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	// the concurrent setups and grants wait for the root to be created or refused.
+	err = storage.TableServicesRoles.LockGrants(ctx, tx)
+
+	var needsRoot bool
+
+	if err == nil {
+		needsRoot, err = NeedsRoot(ctx, tx)
+	}
+
+	if err == nil && !needsRoot {
+		return nil, ErrRootExists
+	}
+
+	if err == nil {
+		err = ensureOwnService(ctx, tx)
+	}
+
+	var dbUser *storage.User
+
+	if err == nil {
+		dbUser, err = storage.TableUsers.Add(ctx, tx, &storage.AddUser{
+			Username: creds.Username,
+			Password: hashedPassword,
+		})
+	}
+
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserCreated, model.PrepareWebhookUser(dbUser))
+	}
+
+	var dbRole *storage.UserRole
+
+	if err == nil {
+		dbRole, err = storage.TableUsersRoles.Add(ctx, tx, &storage.AddUserRole{ //nolint:exhaustruct // not time-bound.
+			UserID:      dbUser.ID,
+			ServiceName: model.MyOwnServiceName,
+			UserRole:    storage.UserRoleTypeRoot,
+		})
+	}
+
+	if err == nil {
+		err = webhook.Publish(ctx, tx, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateRoot: %w", err)
+	}
+
+	return dbUser, nil
+}
+
+// ensureOwnService adds the go-auth service, its default roles and the missing permissions of the catalog.
+func ensureOwnService(ctx context.Context, querier database.Querier) error {
+	_, err := storage.TableServices.GetByServiceName(ctx, querier, model.MyOwnServiceName)
+	if errors.Is(err, database.ErrNoRows) {
+		err = storage.TableServices.Add(ctx, querier, &storage.Service{Name: model.MyOwnServiceName})
+	}
+
+	if err == nil {
+		err = storage.TableServicesRoles.AddDefaults(ctx, querier, model.MyOwnServiceName)
+	}
+
+	var existing []storage.ServicePermission
+
+	if err == nil {
+		existing, err = storage.TableServicesPermissions.GetByServiceName(ctx, querier, model.MyOwnServiceName)
+	}
+
+	for i := 0; err == nil && i < len(model.ManagePermissions); i++ {
+		permission := model.ManagePermissions[i]
+
+		if slices.ContainsFunc(existing, func(entry storage.ServicePermission) bool {
+			return entry.Name == permission.Name
+		}) {
+			continue
+		}
+
+		dbPermission := storage.ServicePermission{ //nolint:exhaustruct // set by db.
+			ServiceName: model.MyOwnServiceName,
+			Name:        permission.Name,
+			Description: permission.Description,
+		}
+
+		_, err = storage.TableServicesPermissions.Add(ctx, querier, &dbPermission)
+	}
+
+	if err != nil {
+		return fmt.Errorf("ensureOwnService: %w", err)
+	}
+
+	return nil
+}
+
+// SetupToken is the one-time token that lets the first root sign up over the network.
+// It is used up by the first successful setup.
+type SetupToken struct {
+	mutex sync.Mutex
+	token string
+	used  bool
+}
+
+func NewSetupToken() (*SetupToken, error) {
+	secret := make([]byte, setupTokenBytes)
+
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("NewSetupToken: %w", err)
+	}
+
+	return &SetupToken{
+		mutex: sync.Mutex{},
+		token: base64.RawURLEncoding.EncodeToString(secret),
+		used:  false,
+	}, nil
+}
+
+func (setupToken *SetupToken) String() string {
+	return setupToken.token
+}
+
+// Use runs the setup if the token matches and has not been used. The token is used up if the setup succeeds.
+// The setups are run one at a time.
+func (setupToken *SetupToken) Use(token string, setup func() error) error {
+	if setupToken == nil {
+		return ErrWrongSetupToken
+	}
+
+	setupToken.mutex.Lock()
+	defer setupToken.mutex.Unlock()
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(setupToken.token)) != 1 {
+		return ErrWrongSetupToken
+	}
+
+	if setupToken.used {
+		return ErrSetupTokenIsUsed
+	}
+
+	if err := setup(); err != nil {
+		return err
+	}
+
+	setupToken.used = true
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package bootstrap_test
+
+import (
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/service/bootstrap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ = flag.String("t-db-uri", "", "perform sql tests on the `t-db-uri` database")
+
+var errSetupFailed = errors.New("setup failed")
+
+func TestSetupTokenUse(t *testing.T) {
+	t.Parallel()
+
+	setupToken, err := bootstrap.NewSetupToken()
+	require.NoError(t, err)
+	require.NotEmpty(t, setupToken.String())
+
+	runs := 0
+	setup := func() error {
+		runs++
+
+		return nil
+	}
+
+	require.ErrorIs(t, setupToken.Use("wrong", setup), bootstrap.ErrWrongSetupToken)
+
+	// a failed setup doesn't use the token up.
+	require.ErrorIs(t, setupToken.Use(setupToken.String(), func() error { return errSetupFailed }), errSetupFailed)
+
+	require.NoError(t, setupToken.Use(setupToken.String(), setup))
+	require.ErrorIs(t, setupToken.Use(setupToken.String(), setup), bootstrap.ErrSetupTokenIsUsed)
+	assert.Equal(t, 1, runs)
+
+	var nilToken *bootstrap.SetupToken
+
+	require.ErrorIs(t, nilToken.Use("", setup), bootstrap.ErrWrongSetupToken)
+}
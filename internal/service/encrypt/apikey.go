@@ -0,0 +1,44 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, it tells the keys from the JWTs.
+const APIKeyPrefix = "gak_"
+
+const (
+	apiKeySecretBytes = 32
+	apiKeyHintLen     = len(APIKeyPrefix) + 6
+)
+
+// GenerateAPIKey makes a new random API key. The hint is the key's beginning, safe to be shown later,
+// the hash is the only form the key is kept in.
+func GenerateAPIKey() (key, hint, hash string, err error) {
+	secret := make([]byte, apiKeySecretBytes)
+
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("GenerateAPIKey: %w", err)
+	}
+
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, key[:apiKeyHintLen], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex sha256 of the key. The keys are random, so no salt nor stretching is needed.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey tests if the token is an API key rather than a JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,83 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/myerrors"
+	"github.com/golang-jwt/jwt"
+)
+
+// challengeAudience marks the proof-of-work challenge tokens, they are never accepted as the access tokens.
+const challengeAudience = "go-auth-challenge"
+
+const challengeNonceBytes = 16
+
+// IssueChallengeToken signs a new random proof-of-work challenge, valid for the ttl.
+func (jwtService *JWTService) IssueChallengeToken(ttl time.Duration) (string, *time.Time, error) {
+	if jwtService == nil {
+		return "", nil, myerrors.ErrServiceNullPtr
+	}
+
+	nonce := make([]byte, challengeNonceBytes)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("jwtService.IssueChallengeToken: %w", err)
+	}
+
+	expiresAt := jwt.TimeFunc().Add(ttl)
+	claims := jwt.StandardClaims{ //nolint:exhaustruct // other fields are not used.
+		Audience:  challengeAudience,
+		Id:        hex.EncodeToString(nonce),
+		IssuedAt:  jwt.TimeFunc().Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	signedToken, err := jwtService.sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("jwtService.IssueChallengeToken signing failed: %w", err)
+	}
+
+	return signedToken, &expiresAt, nil
+}
+
+// ValidateChallengeToken tests if the challenge was issued by the service and has not expired.
+func (jwtService *JWTService) ValidateChallengeToken(tokenString string) error {
+	if jwtService == nil {
+		return myerrors.ErrServiceNullPtr
+	}
+
+	//nolint:exhaustruct // Only the type is what matters.
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, jwtService.keyFunc)
+	if err != nil {
+		return ErrParsingToken
+	}
+
+	claims, ok := token.Claims.(*jwt.StandardClaims)
+	if !ok || claims.Audience != challengeAudience {
+		return ErrWrongClaims
+	}
+
+	return nil
+}
+
+// SolvesChallenge tests if the sha256 of the challenge, the subject and the solution starts with
+// the difficulty zero bits. The subject binds the solution to a single use, like a sign up of a username.
+func SolvesChallenge(challenge, subject, solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + subject + ":" + solution))
+
+	zeros := 0
+
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return zeros >= difficulty
+}
@@ -0,0 +1,36 @@
+package encrypt_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolvesChallenge(t *testing.T) {
+	t.Parallel()
+
+	const difficulty = 12
+
+	solution := ""
+
+	for i := 0; solution == ""; i++ {
+		if encrypt.SolvesChallenge("challenge", "username", strconv.Itoa(i), difficulty) {
+			solution = strconv.Itoa(i)
+		}
+	}
+
+	assert.True(t, encrypt.SolvesChallenge("challenge", "username", solution, difficulty))
+	assert.True(t, encrypt.SolvesChallenge("challenge", "username", solution, difficulty-1))
+	assert.True(t, encrypt.SolvesChallenge("challenge", "username", "anything", 0))
+
+	// the solution is bound to the challenge and the subject.
+	assert.False(t, encrypt.SolvesChallenge("challenge", "other", solution, difficulty))
+	assert.False(t, encrypt.SolvesChallenge("other", "username", solution, difficulty))
+	assert.False(t, encrypt.SolvesChallenge("challenge", "username", solution, 256+1))
+
+	// sha256("challenge:username:0") starts with the byte 0000 0010.
+	assert.True(t, encrypt.SolvesChallenge("challenge", "username", "0", 6))
+	assert.False(t, encrypt.SolvesChallenge("challenge", "username", "0", 7))
+}
@@ -0,0 +1,52 @@
+package encrypt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/myerrors"
+	"github.com/golang-jwt/jwt"
+)
+
+// invitationAudience marks the invite tokens, they are never accepted as the access tokens.
+const invitationAudience = "go-auth-invitation"
+
+// IssueInvitationToken signs a token naming the invitation, valid until the invitation expires.
+func (jwtService *JWTService) IssueInvitationToken(invitationID string, expiresAt time.Time) (string, error) {
+	if jwtService == nil {
+		return "", myerrors.ErrServiceNullPtr
+	}
+
+	claims := jwt.StandardClaims{ //nolint:exhaustruct // other fields are not used.
+		Audience:  invitationAudience,
+		Id:        invitationID,
+		IssuedAt:  jwt.TimeFunc().Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+	signedToken, err := jwtService.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwtService.IssueInvitationToken signing failed: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// ValidateInvitationToken returns the ID of the invitation the valid invite token names.
+func (jwtService *JWTService) ValidateInvitationToken(tokenString string) (string, error) {
+	if jwtService == nil {
+		return "", myerrors.ErrServiceNullPtr
+	}
+
+	//nolint:exhaustruct // Only the type is what matters.
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, jwtService.keyFunc)
+	if err != nil {
+		return "", ErrParsingToken
+	}
+
+	claims, ok := token.Claims.(*jwt.StandardClaims)
+	if !ok || claims.Audience != invitationAudience || claims.Id == "" {
+		return "", ErrWrongClaims
+	}
+
+	return claims.Id, nil
+}
@@ -1,10 +1,11 @@
 package encrypt
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/eldarbr/go-auth/internal/service/myerrors"
@@ -16,10 +17,12 @@ var (
 	ErrWrongClaims  = errors.New("unknown claims type, cannot proceed")
 )
 
+// JWTService signs the tokens with an RSA or an ECDSA key, the signing method follows the key.
 type JWTService struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-	tokenTTL   time.Duration
+	signingMethod jwt.SigningMethod
+	privateKey    crypto.Signer
+	publicKey     crypto.PublicKey
+	tokenTTL      time.Duration
 }
 
 type ClaimUserRole struct {
@@ -27,10 +30,32 @@ type ClaimUserRole struct {
 	UserRole    string `json:"userRole"`
 }
 
+// ActorClaim is the RFC 8693 act claim, it names the user acting on behalf of the token's owner.
+type ActorClaim struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+}
+
+// AuthCustomClaims are the go-auth claims of a token.
+// The Permissions are the effective permissions of the user keyed by the service name.
+// The impersonation tokens carry the Actor, who was issued the token, and are marked with Impersonation.
 type AuthCustomClaims struct {
-	Username string          `json:"username"`
-	UserID   string          `json:"userId"`
-	Roles    []ClaimUserRole `json:"roles"`
+	Attributes    map[string]any      `json:"attributes,omitempty"`
+	Permissions   map[string][]string `json:"permissions,omitempty"`
+	Actor         *ActorClaim         `json:"act,omitempty"`
+	Username      string              `json:"username"`
+	UserID        string              `json:"userId"`
+	Roles         []ClaimUserRole     `json:"roles"`
+	Impersonation bool                `json:"impersonation,omitempty"`
+}
+
+// ValidatedClaims are the claims of a valid token along with the token's lifetime.
+// The APIKeyID is set if the claims were made for an API key, which may have a zero ExpiresAt.
+type ValidatedClaims struct {
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	APIKeyID  string
+	AuthCustomClaims
 }
 
 type myCompletelaims struct {
@@ -44,7 +69,7 @@ func NewJWTService(privatePath, publicPath string, tokenTTL time.Duration) (*JWT
 		return nil, fmt.Errorf("NewJWTService private key read failed: %w", err)
 	}
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyBytes)
+	privateKey, err := ParsePrivateKeyPEM(privateKeyBytes)
 	if err != nil {
 		return nil, fmt.Errorf("NewJWTService private key parse failed: %w", err)
 	}
@@ -54,24 +79,63 @@ func NewJWTService(privatePath, publicPath string, tokenTTL time.Duration) (*JWT
 		return nil, fmt.Errorf("NewJWTService public key read failed: %w", err)
 	}
 
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyBytes)
+	publicKey, err := ParsePublicKeyPEM(publicKeyBytes)
 	if err != nil {
 		return nil, fmt.Errorf("NewJWTService public key parse failed: %w", err)
 	}
 
+	if matching, ok := privateKey.Public().(interface{ Equal(x crypto.PublicKey) bool }); !ok ||
+		!matching.Equal(publicKey) {
+		return nil, fmt.Errorf("NewJWTService: %w", ErrKeysMismatch)
+	}
+
+	signingMethod, err := signingMethodFor(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("NewJWTService: %w", err)
+	}
+
 	return &JWTService{
-		privateKey: privateKey,
-		publicKey:  publicKey,
-		tokenTTL:   tokenTTL,
+		signingMethod: signingMethod,
+		privateKey:    privateKey,
+		publicKey:     publicKey,
+		tokenTTL:      tokenTTL,
 	}, nil
 }
 
+// PublicKey is the key the tokens are verified with.
+func (jwtService *JWTService) PublicKey() crypto.PublicKey {
+	return jwtService.publicKey
+}
+
+func (jwtService *JWTService) sign(claims jwt.Claims) (string, error) {
+	//nolint:wrapcheck // wrapped by the callers.
+	return jwt.NewWithClaims(jwtService.signingMethod, claims).SignedString(jwtService.privateKey)
+}
+
+// keyFunc only accepts the tokens signed with the service's signing method.
+func (jwtService *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwtService.signingMethod.Alg() {
+		return nil, ErrParsingToken
+	}
+
+	return jwtService.publicKey, nil
+}
+
 func (jwtService *JWTService) IssueToken(claims AuthCustomClaims) (string, *time.Time, error) {
+	return jwtService.IssueTokenNotAfter(claims, nil)
+}
+
+// IssueTokenNotAfter issues a token that expires no later than notAfter, a nil notAfter doesn't limit the TTL.
+func (jwtService *JWTService) IssueTokenNotAfter(claims AuthCustomClaims, notAfter *time.Time) (string, *time.Time,
+	error) {
 	if jwtService == nil {
 		return "", nil, myerrors.ErrServiceNullPtr
 	}
 
 	newTokenExpires := jwt.TimeFunc().Add(jwtService.tokenTTL)
+	if notAfter != nil && notAfter.Before(newTokenExpires) {
+		newTokenExpires = *notAfter
+	}
 
 	completeClaims := myCompletelaims{
 		AuthCustomClaims: claims,
@@ -80,9 +144,7 @@ func (jwtService *JWTService) IssueToken(claims AuthCustomClaims) (string, *time
 			ExpiresAt: newTokenExpires.Unix(),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS512, completeClaims)
-
-	signedToken, err := token.SignedString(jwtService.privateKey)
+	signedToken, err := jwtService.sign(completeClaims)
 	if err != nil {
 		return "", nil, fmt.Errorf("jwtService.IssueToken signing failed: %w", err)
 	}
@@ -90,7 +152,7 @@ func (jwtService *JWTService) IssueToken(claims AuthCustomClaims) (string, *time
 	return signedToken, &newTokenExpires, nil
 }
 
-func (jwtService *JWTService) ValidateToken(tokenString string) (*AuthCustomClaims, error) {
+func (jwtService *JWTService) ValidateToken(tokenString string) (*ValidatedClaims, error) {
 	if jwtService == nil {
 		return nil, myerrors.ErrServiceNullPtr
 	}
@@ -101,33 +163,101 @@ func (jwtService *JWTService) ValidateToken(tokenString string) (*AuthCustomClai
 	)
 
 	//nolint:exhaustruct // Only the type is what matters.
-	token, err := jwt.ParseWithClaims(tokenString, &myCompletelaims{}, func(_ *jwt.Token) (interface{}, error) {
-		return jwtService.publicKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &myCompletelaims{}, jwtService.keyFunc)
 	if err != nil {
 		return nil, ErrParsingToken
 	} else if claims, tokenClaimsOk = token.Claims.(*myCompletelaims); !tokenClaimsOk {
 		return nil, ErrWrongClaims
+	} else if claims.Audience != "" {
+		// the access tokens have no audience, unlike the invite and the challenge tokens.
+		return nil, ErrWrongClaims
 	}
 
-	return &claims.AuthCustomClaims, nil
+	return &ValidatedClaims{
+		AuthCustomClaims: claims.AuthCustomClaims,
+		IssuedAt:         time.Unix(claims.IssuedAt, 0),
+		ExpiresAt:        time.Unix(claims.ExpiresAt, 0),
+	}, nil
 }
 
-func (claims AuthCustomClaims) ContainAny(requested []ClaimUserRole) bool {
-	claimsMap := make(map[string]string, len(claims.Roles))
-	for _, role := range claims.Roles {
-		claimsMap[role.ServiceName] = role.UserRole
+// RoleHierarchy holds the roles directly implied by a role, keyed by the service name and the role.
+// A role implies the roles implied by its implied roles too.
+type RoleHierarchy map[string]map[string][]string
+
+// Implies tests if a user with the held role in the service has the requested role.
+// A role implies itself. A nil hierarchy only matches the equal roles.
+func (hierarchy RoleHierarchy) Implies(serviceName, held, requested string) bool {
+	visited := map[string]bool{held: true}
+	pending := []string{held}
+
+	for len(pending) > 0 {
+		role := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if role == requested {
+			return true
+		}
+
+		for _, implied := range hierarchy[serviceName][role] {
+			if !visited[implied] {
+				visited[implied] = true
+				pending = append(pending, implied)
+			}
+		}
+	}
+
+	return false
+}
+
+// ContainAny tests if any of the requested roles is claimed or implied by a claimed role.
+// A user may have several roles in a service, granted directly and through the groups.
+func (claims AuthCustomClaims) ContainAny(requested []ClaimUserRole, hierarchy RoleHierarchy) bool {
+	for _, requestedClaim := range requested {
+		if claims.contain(requestedClaim, hierarchy) {
+			return true
+		}
 	}
 
+	return false
+}
+
+// ContainAll tests if every requested role is claimed or implied by a claimed role.
+// Nothing requested is not contained.
+func (claims AuthCustomClaims) ContainAll(requested []ClaimUserRole, hierarchy RoleHierarchy) bool {
 	for _, requestedClaim := range requested {
-		if requestedClaim.ServiceName == "" || requestedClaim.UserRole == "" {
-			continue
+		if !claims.contain(requestedClaim, hierarchy) {
+			return false
 		}
+	}
+
+	return len(requested) > 0
+}
+
+func (claims AuthCustomClaims) contain(requested ClaimUserRole, hierarchy RoleHierarchy) bool {
+	if requested.ServiceName == "" || requested.UserRole == "" {
+		return false
+	}
 
-		if role, ok := claimsMap[requestedClaim.ServiceName]; ok && (role == requestedClaim.UserRole) {
+	for _, role := range claims.Roles {
+		if role.ServiceName == requested.ServiceName &&
+			hierarchy.Implies(role.ServiceName, role.UserRole, requested.UserRole) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// IsImpersonation tests if the token was issued to an actor impersonating the user.
+func (claims AuthCustomClaims) IsImpersonation() bool {
+	return claims.Impersonation || claims.Actor != nil
+}
+
+// HasPermission tests if the permission in the service is claimed.
+func (claims AuthCustomClaims) HasPermission(serviceName, permission string) bool {
+	if serviceName == "" || permission == "" {
+		return false
+	}
+
+	return slices.Contains(claims.Permissions[serviceName], permission)
+}
@@ -0,0 +1,133 @@
+package encrypt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJWTService makes a service signing with a new key pair of the algorithm.
+func newJWTService(t *testing.T, algorithm string, bits int) *encrypt.JWTService {
+	t.Helper()
+
+	privatePem, publicPem, err := encrypt.GenerateKeyPair(algorithm, bits)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+
+	require.NoError(t, os.WriteFile(privatePath, privatePem, 0o600))
+	require.NoError(t, os.WriteFile(publicPath, publicPem, 0o600))
+
+	jwtService, err := encrypt.NewJWTService(privatePath, publicPath, time.Hour)
+	require.NoError(t, err)
+
+	return jwtService
+}
+
+func TestTokenAudiences(t *testing.T) {
+	t.Parallel()
+
+	jwtService := newJWTService(t, encrypt.KeyAlgorithmECDSA, 256)
+
+	access, _, err := jwtService.IssueToken(encrypt.AuthCustomClaims{ //nolint:exhaustruct // a plain user.
+		Username: "user",
+		UserID:   "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	})
+	require.NoError(t, err)
+
+	challenge, _, err := jwtService.IssueChallengeToken(time.Minute)
+	require.NoError(t, err)
+
+	invitation, err := jwtService.IssueInvitationToken("6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+		time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	verification, _, err := jwtService.IssueEmailVerificationToken("6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"user@example.com", time.Hour)
+	require.NoError(t, err)
+
+	_, err = jwtService.ValidateToken(access)
+	require.NoError(t, err)
+	require.NoError(t, jwtService.ValidateChallengeToken(challenge))
+
+	_, err = jwtService.ValidateInvitationToken(invitation)
+	require.NoError(t, err)
+
+	userID, email, err := jwtService.ValidateEmailVerificationToken(verification)
+	require.NoError(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", userID)
+	assert.Equal(t, "user@example.com", email)
+
+	// every token is only accepted for its audience.
+	for _, token := range []string{challenge, invitation, verification} {
+		_, err = jwtService.ValidateToken(token)
+		require.ErrorIs(t, err, encrypt.ErrWrongClaims)
+	}
+
+	for _, token := range []string{access, invitation, verification} {
+		require.ErrorIs(t, jwtService.ValidateChallengeToken(token), encrypt.ErrWrongClaims)
+	}
+
+	for _, token := range []string{access, challenge, verification} {
+		_, err = jwtService.ValidateInvitationToken(token)
+		require.ErrorIs(t, err, encrypt.ErrWrongClaims)
+	}
+
+	for _, token := range []string{access, challenge, invitation} {
+		_, _, err = jwtService.ValidateEmailVerificationToken(token)
+		require.ErrorIs(t, err, encrypt.ErrWrongClaims)
+	}
+
+	// the tokens of another key are not valid.
+	require.ErrorIs(t, newJWTService(t, encrypt.KeyAlgorithmECDSA, 256).ValidateChallengeToken(challenge),
+		encrypt.ErrParsingToken)
+}
+
+func TestRoleHierarchyImplies(t *testing.T) {
+	t.Parallel()
+
+	// admin and moderator imply each other.
+	hierarchy := encrypt.RoleHierarchy{
+		"service": {
+			"root":      {"admin"},
+			"admin":     {"moderator"},
+			"moderator": {"admin", "user"},
+		},
+	}
+
+	assert.True(t, hierarchy.Implies("service", "root", "user"))
+	assert.True(t, hierarchy.Implies("service", "moderator", "admin"))
+	assert.True(t, hierarchy.Implies("service", "user", "user"))
+	assert.False(t, hierarchy.Implies("service", "admin", "root"))
+	assert.False(t, hierarchy.Implies("service", "moderator", "root"))
+	assert.False(t, hierarchy.Implies("other", "root", "user"))
+	assert.False(t, encrypt.RoleHierarchy(nil).Implies("service", "root", "user"))
+}
+
+func TestClaimsContain(t *testing.T) {
+	t.Parallel()
+
+	hierarchy := encrypt.RoleHierarchy{"service": {"admin": {"user"}}}
+	claims := encrypt.AuthCustomClaims{ //nolint:exhaustruct // only the roles are used.
+		Roles: []encrypt.ClaimUserRole{{ServiceName: "service", UserRole: "admin"}},
+	}
+
+	admin := encrypt.ClaimUserRole{ServiceName: "service", UserRole: "admin"}
+	user := encrypt.ClaimUserRole{ServiceName: "service", UserRole: "user"}
+	root := encrypt.ClaimUserRole{ServiceName: "service", UserRole: "root"}
+
+	assert.True(t, claims.ContainAll([]encrypt.ClaimUserRole{admin, user}, hierarchy))
+	assert.False(t, claims.ContainAll([]encrypt.ClaimUserRole{admin, user}, nil))
+	assert.False(t, claims.ContainAll([]encrypt.ClaimUserRole{admin, root}, hierarchy))
+	assert.False(t, claims.ContainAll([]encrypt.ClaimUserRole{}, hierarchy))
+	assert.False(t, claims.ContainAll(nil, hierarchy))
+	assert.True(t, claims.ContainAny([]encrypt.ClaimUserRole{root, user}, hierarchy))
+	assert.False(t, claims.ContainAny([]encrypt.ClaimUserRole{root, {ServiceName: "service"}}, hierarchy))
+	assert.False(t, claims.ContainAny(nil, hierarchy))
+}
@@ -0,0 +1,225 @@
+package encrypt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const bitsInByte = 8
+
+const (
+	KeyAlgorithmRSA   = "rsa"
+	KeyAlgorithmECDSA = "ecdsa"
+)
+
+var (
+	ErrUnknownKeyAlgorithm = errors.New("unknown key algorithm or size")
+	ErrParsingKey          = errors.New("couldn't parse the key")
+	ErrKeysMismatch        = errors.New("the public key doesn't match the private key")
+)
+
+// KeySizes are the key sizes supported for an algorithm, the first is the default.
+var KeySizes = map[string][]int{
+	KeyAlgorithmRSA:   {4096, 2048, 3072},
+	KeyAlgorithmECDSA: {256, 384, 521},
+}
+
+// KeyInfo describes a public key.
+type KeyInfo struct {
+	Algorithm     string `json:"algorithm"`
+	SigningMethod string `json:"signingMethod"`
+	Fingerprint   string `json:"fingerprint"`
+	JWKThumbprint string `json:"jwkThumbprint"`
+	Bits          int    `json:"bits"`
+}
+
+// GenerateKeyPair makes a new key pair of the algorithm and the size in bits, 0 is the default size.
+// The private key is PKCS #8 PEM, the public key is PKIX PEM.
+func GenerateKeyPair(algorithm string, bits int) (privatePem, publicPem []byte, err error) {
+	sizes, ok := KeySizes[algorithm]
+	if bits == 0 && ok {
+		bits = sizes[0]
+	}
+
+	if !slices.Contains(sizes, bits) {
+		return nil, nil, ErrUnknownKeyAlgorithm
+	}
+
+	var privateKey crypto.Signer
+
+	if algorithm == KeyAlgorithmRSA {
+		privateKey, err = rsa.GenerateKey(rand.Reader, bits)
+	} else {
+		privateKey, err = ecdsa.GenerateKey(ellipticCurves[bits], rand.Reader)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("GenerateKeyPair: %w", err)
+	}
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GenerateKeyPair: %w", err)
+	}
+
+	publicDer, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("GenerateKeyPair: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: nil, Bytes: privateDer}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Headers: nil, Bytes: publicDer}), nil
+}
+
+var ellipticCurves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+// ParsePrivateKeyPEM parses an RSA or ECDSA private key, PKCS #8, PKCS #1 or SEC 1.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return rsaKey, nil
+	}
+
+	if ecKey, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+		return ecKey, nil
+	}
+
+	return nil, ErrParsingKey
+}
+
+// ParsePublicKeyPEM parses an RSA or ECDSA public key, or the public part of a private key.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return rsaKey, nil
+	}
+
+	if ecKey, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return ecKey, nil
+	}
+
+	privateKey, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return privateKey.Public(), nil
+}
+
+// signingMethodFor picks the JWT signing method of the key: RS512 for RSA,
+// and ES256, ES384 or ES512 by the curve for ECDSA.
+func signingMethodFor(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS512, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	}
+
+	return nil, ErrUnknownKeyAlgorithm
+}
+
+// InspectPublicKey describes the key. The fingerprint is the sha256 of the PKIX DER,
+// the JWK thumbprint is the RFC 7638 one.
+func InspectPublicKey(publicKey crypto.PublicKey) (*KeyInfo, error) {
+	signingMethod, err := signingMethodFor(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("InspectPublicKey: %w", err)
+	}
+
+	fingerprint := sha256.Sum256(der)
+
+	thumbprint, err := jwkThumbprint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	info := KeyInfo{
+		Algorithm:     KeyAlgorithmRSA,
+		SigningMethod: signingMethod.Alg(),
+		Fingerprint:   "SHA256:" + base64.RawStdEncoding.EncodeToString(fingerprint[:]),
+		JWKThumbprint: thumbprint,
+		Bits:          0,
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		info.Bits = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.Algorithm = KeyAlgorithmECDSA
+		info.Bits = key.Curve.Params().BitSize
+	}
+
+	return &info, nil
+}
+
+// jwkThumbprint is the base64url sha256 of the key's required JWK members in the lexicographic order.
+func jwkThumbprint(publicKey crypto.PublicKey) (string, error) {
+	var members any
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + bitsInByte - 1) / bitsInByte
+
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{
+			Crv: key.Curve.Params().Name,
+			Kty: "EC",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}
+	default:
+		return "", ErrUnknownKeyAlgorithm
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("jwkThumbprint: %w", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
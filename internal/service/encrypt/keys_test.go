@@ -0,0 +1,89 @@
+package encrypt_test
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	t.Parallel()
+
+	// the RSA key of RFC 7638, section 3.1.
+	modulus, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4" +
+		"cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6C" +
+		"f0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTW" +
+		"hAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	require.NoError(t, err)
+
+	info, err := encrypt.InspectPublicKey(&rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537})
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", info.JWKThumbprint)
+	assert.Equal(t, encrypt.KeyAlgorithmRSA, info.Algorithm)
+	assert.Equal(t, 2048, info.Bits)
+}
+
+func TestKeyPairRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		algorithm     string
+		signingMethod string
+		bits          int
+	}{
+		{algorithm: encrypt.KeyAlgorithmRSA, signingMethod: "RS512", bits: 2048},
+		{algorithm: encrypt.KeyAlgorithmECDSA, signingMethod: "ES256", bits: 256},
+		{algorithm: encrypt.KeyAlgorithmECDSA, signingMethod: "ES384", bits: 384},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.signingMethod, func(t *testing.T) {
+			t.Parallel()
+
+			privatePem, publicPem, err := encrypt.GenerateKeyPair(testCase.algorithm, testCase.bits)
+			require.NoError(t, err)
+
+			privateKey, err := encrypt.ParsePrivateKeyPEM(privatePem)
+			require.NoError(t, err)
+
+			publicKey, err := encrypt.ParsePublicKeyPEM(publicPem)
+			require.NoError(t, err)
+
+			// the public part of the private key is the public key.
+			fromPrivate, err := encrypt.ParsePublicKeyPEM(privatePem)
+			require.NoError(t, err)
+			assert.Equal(t, privateKey.Public(), publicKey)
+			assert.Equal(t, publicKey, fromPrivate)
+
+			info, err := encrypt.InspectPublicKey(publicKey)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.algorithm, info.Algorithm)
+			assert.Equal(t, testCase.signingMethod, info.SigningMethod)
+			assert.Equal(t, testCase.bits, info.Bits)
+
+			// the service signs with the generated pair.
+			jwtService := newJWTService(t, testCase.algorithm, testCase.bits)
+
+			token, _, err := jwtService.IssueToken(encrypt.AuthCustomClaims{ //nolint:exhaustruct // a plain user.
+				Username: "user",
+				UserID:   "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			})
+			require.NoError(t, err)
+
+			unverified, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, testCase.signingMethod, unverified.Header["alg"])
+
+			claims, err := jwtService.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user", claims.Username)
+			assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", claims.UserID)
+		})
+	}
+}
@@ -37,6 +37,14 @@ func PasswordCompare(password, hash string) bool {
 	return err == nil
 }
 
+// IsPasswordHash returns true if the string is a hash produced by PasswordEncrypt
+// or by another bcrypt implementation.
+func IsPasswordHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+
+	return err == nil
+}
+
 // IsPrintableASCII returns true only if the string
 // consists of the printable ascii characters (space not allowed).
 func IsPrintableASCII(s string) bool {
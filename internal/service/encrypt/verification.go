@@ -0,0 +1,63 @@
+package encrypt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/myerrors"
+	"github.com/golang-jwt/jwt"
+)
+
+// emailVerificationAudience marks the email verification tokens, they are never accepted as the access tokens.
+const emailVerificationAudience = "go-auth-email-verification"
+
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+// IssueEmailVerificationToken signs a token binding the email to the user, valid for the ttl.
+func (jwtService *JWTService) IssueEmailVerificationToken(userID, email string, ttl time.Duration) (string,
+	*time.Time, error) {
+	if jwtService == nil {
+		return "", nil, myerrors.ErrServiceNullPtr
+	}
+
+	expiresAt := jwt.TimeFunc().Add(ttl)
+	claims := emailVerificationClaims{
+		Email: email,
+		StandardClaims: jwt.StandardClaims{ //nolint:exhaustruct // other fields are not used.
+			Audience:  emailVerificationAudience,
+			Subject:   userID,
+			IssuedAt:  jwt.TimeFunc().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	signedToken, err := jwtService.sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("jwtService.IssueEmailVerificationToken signing failed: %w", err)
+	}
+
+	return signedToken, &expiresAt, nil
+}
+
+// ValidateEmailVerificationToken returns the user and the email the valid verification token binds.
+func (jwtService *JWTService) ValidateEmailVerificationToken(tokenString string) (string, string, error) {
+	if jwtService == nil {
+		return "", "", myerrors.ErrServiceNullPtr
+	}
+
+	//nolint:exhaustruct // Only the type is what matters.
+	token, err := jwt.ParseWithClaims(tokenString, &emailVerificationClaims{}, jwtService.keyFunc)
+	if err != nil {
+		return "", "", ErrParsingToken
+	}
+
+	claims, ok := token.Claims.(*emailVerificationClaims)
+	if !ok || claims.Audience != emailVerificationAudience || claims.Subject == "" || claims.Email == "" {
+		return "", "", ErrWrongClaims
+	}
+
+	return claims.Subject, claims.Email, nil
+}
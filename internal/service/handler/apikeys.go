@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// authenticateKeysOwner writes the response and returns nil if the requester may not manage own API keys.
+// The keys are managed with a JWT, neither with an API key nor with an impersonation token.
+func (authHandl AuthHandl) authenticateKeysOwner(respWriter http.ResponseWriter,
+	request *http.Request) *encrypt.ValidatedClaims {
+	claims, _, err := authenticateToken(request.Context(), authHandl.dbInstance, authHandl.jwtService,
+		sessionToken(request))
+	if errors.Is(err, errUnauthorized) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+
+		return nil
+	}
+
+	if err != nil {
+		log.Printf("authenticateKeysOwner - authenticate err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return nil
+	}
+
+	setAuditActor(request.Context(), claims.UserID, claims.Username)
+
+	if claims.APIKeyID != "" || claims.IsImpersonation() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "forbidden"}, http.StatusForbidden)
+
+		return nil
+	}
+
+	return claims
+}
+
+// GetMyAPIKeys lists the requester's API keys.
+func (authHandl AuthHandl) GetMyAPIKeys(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request GetMyAPIKeys received")
+
+	claims := authHandl.authenticateKeysOwner(respWriter, request)
+	if claims == nil {
+		return
+	}
+
+	keys, err := storage.TableAPIKeys.GetByUserID(request.Context(), authHandl.dbInstance.GetPool(), claims.UserID)
+	if err != nil {
+		log.Printf("GetMyAPIKeys - get keys err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.APIKeysResponse{
+		UserID: claims.UserID,
+		Keys:   model.PrepareAPIKeys(keys),
+	}, http.StatusOK)
+}
+
+// CreateAPIKey makes a new API key of the requester, scoped to a subset of the requester's roles.
+// The key is only shown in the response.
+func (authHandl AuthHandl) CreateAPIKey(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request CreateAPIKey received")
+
+	var parsedBody model.APIKeyRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	claims := authHandl.authenticateKeysOwner(respWriter, request)
+	if claims == nil {
+		return
+	}
+
+	keyRoles := model.PrepareAPIKeyRoles(parsedBody.Roles)
+
+	effective, err := getEffectiveRoles(request.Context(), authHandl.dbInstance.GetPool(), claims.UserID)
+	if err == nil && len(model.APIKeyScope(effective, keyRoles)) != len(keyRoles) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the roles are not held"}, http.StatusBadRequest)
+
+		return
+	}
+
+	var key, hint, hash string
+
+	if err == nil {
+		key, hint, hash, err = encrypt.GenerateAPIKey()
+	}
+
+	if err != nil {
+		log.Printf("CreateAPIKey - prepare key err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	tx, err := authHandl.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("CreateAPIKey - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbKey, err := storage.TableAPIKeys.Add(request.Context(), tx, &storage.APIKey{ //nolint:exhaustruct // set by db.
+		ExpiresAt: parsedBody.ExpiresAt,
+		UserID:    claims.UserID,
+		Name:      parsedBody.Name,
+		Hint:      hint,
+		KeyHash:   hash,
+		Roles:     keyRoles,
+	})
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the key already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("CreateAPIKey - insert key err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		Target: "id=" + dbKey.ID,
+	}, "api key %s (%s) created", dbKey.ID, dbKey.Name)
+
+	writeJSONResponse(respWriter, model.APIKeyCreateResponse{
+		Key:        key,
+		APIKeyInfo: model.PrepareAPIKeys([]storage.APIKey{*dbKey})[0],
+	}, http.StatusOK)
+}
+
+// RevokeMyAPIKey deletes the requester's API key.
+func (authHandl AuthHandl) RevokeMyAPIKey(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeMyAPIKey received")
+
+	keyID := params.ByName("id")
+	if !model.ValidUUID(keyID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	claims := authHandl.authenticateKeysOwner(respWriter, request)
+	if claims == nil {
+		return
+	}
+
+	ctx := context.WithValue(request.Context(), ctxKeyRequesterUsername, claims.Username)
+
+	revokeAPIKey(respWriter, request.WithContext(ctx), authHandl.dbInstance, claims.UserID, keyID)
+}
+
+// GetUserAPIKeys lists the user's API keys.
+func (manage ManageHandl) GetUserAPIKeys(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetUserAPIKeys received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.userExists(respWriter, request, userID) {
+		return
+	}
+
+	keys, err := storage.TableAPIKeys.GetByUserID(request.Context(), manage.dbInstance.GetPool(), userID)
+	if err != nil {
+		log.Printf("GetUserAPIKeys - get keys err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.APIKeysResponse{
+		UserID: userID,
+		Keys:   model.PrepareAPIKeys(keys),
+	}, http.StatusOK)
+}
+
+// RevokeUserAPIKey deletes the user's API key.
+func (manage ManageHandl) RevokeUserAPIKey(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeUserAPIKey received")
+
+	userID := params.ByName("id")
+	keyID := params.ByName("keyId")
+
+	if !model.ValidUUID(userID) || !model.ValidUUID(keyID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.rootTargetAllowed(respWriter, request, userID) {
+		return
+	}
+
+	revokeAPIKey(respWriter, request, manage.dbInstance, userID, keyID)
+}
+
+func revokeAPIKey(respWriter http.ResponseWriter, request *http.Request, dbInstance *database.Database,
+	userID, keyID string) {
+	err := storage.TableAPIKeys.DeleteByID(request.Context(), dbInstance.GetPool(), userID, keyID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("revokeAPIKey - delete key err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, dbInstance, storage.AuditEvent{}, //nolint:exhaustruct // the request's event.
+		"api key %s of user %s revoked", keyID, userID)
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
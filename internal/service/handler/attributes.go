@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (manage ManageHandl) GetAttributeDefinitions(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request GetAttributeDefinitions received")
+
+	definitions, err := storage.TableAttributeDefinitions.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("GetAttributeDefinitions - get definitions err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.AttributeDefinitionsResponse{
+		Attributes: model.PrepareAttributeDefinitions(definitions),
+	}, http.StatusOK)
+}
+
+func (manage ManageHandl) CreateAttributeDefinition(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request CreateAttributeDefinition received")
+
+	var parsedBody model.AttributeDefinitionRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	definition := parsedBody.Definition()
+
+	err = storage.TableAttributeDefinitions.Add(request.Context(), manage.dbInstance.GetPool(), &definition)
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the attribute already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("CreateAttributeDefinition - insert definition err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareAttributeDefinitions([]storage.AttributeDefinition{definition})[0],
+		http.StatusOK)
+}
+
+// DeleteAttributeDefinition deletes the definition and removes the attribute from all the users.
+func (manage ManageHandl) DeleteAttributeDefinition(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteAttributeDefinition received")
+
+	response := model.AttributeDeleteResponse{Name: params.ByName("name")} //nolint:exhaustruct // counted below.
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("DeleteAttributeDefinition - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableAttributeDefinitions.Delete(request.Context(), tx, response.Name)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		response.UsersAffected, err = storage.TableUsers.DeleteAttribute(request.Context(), tx, response.Name)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("DeleteAttributeDefinition - delete definition err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, response, http.StatusOK)
+}
+
+func (manage ManageHandl) GetUserAttributes(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetUserAttributes received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	dbUser, err := storage.TableUsers.GetByID(request.Context(), manage.dbInstance.GetPool(), userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetUserAttributes - get user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.UserAttributesResponse{
+		UserID:     userID,
+		Attributes: dbUser.Attributes,
+	}, http.StatusOK)
+}
+
+// UpdateUserAttributes merges the body into the user's attributes, a null value removes the attribute.
+func (manage ManageHandl) UpdateUserAttributes(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request UpdateUserAttributes received")
+
+	var parsedBody map[string]any
+
+	userID := params.ByName("id")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || parsedBody == nil || !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.rootTargetAllowed(respWriter, request, userID) {
+		return
+	}
+
+	definitions, err := storage.TableAttributeDefinitions.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("UpdateUserAttributes - get definitions err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	if err = model.ValidateAttributes(parsedBody, definitions); err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+
+		return
+	}
+
+	attributes, err := storage.TableUsers.MergeAttributesByID(request.Context(), manage.dbInstance.GetPool(),
+		parsedBody, userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("UpdateUserAttributes - update attributes err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.UserAttributesResponse{
+		UserID:     userID,
+		Attributes: attributes,
+	}, http.StatusOK)
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ctxKeyAuditEvent holds the *storage.AuditEvent of an audited request, the handlers down the chain fill it in.
+const ctxKeyAuditEvent ctxKey = "AuditEvent"
+
+// The lengths of the audit log columns, the longer values are cut.
+const (
+	capAuditActor     = 100
+	capAuditAction    = 200
+	capAuditTarget    = 500
+	capAuditIP        = 100
+	capAuditUserAgent = 500
+	capAuditDetails   = 1000
+)
+
+// MiddlewareAudit writes the event of the request to the audit log once the request is handled.
+func (authHandl AuthHandl) MiddlewareAudit(action string, next httprouter.Handle) httprouter.Handle {
+	return auditRequest(authHandl.dbInstance, action, next)
+}
+
+// MiddlewareAudit writes the event of the request to the audit log once the request is handled.
+func (manage ManageHandl) MiddlewareAudit(action string, next httprouter.Handle) httprouter.Handle {
+	return auditRequest(manage.dbInstance, action, next)
+}
+
+// GetAuditLog responds with a page of the audit log, the newest events first.
+func (manage ManageHandl) GetAuditLog(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request GetAuditLog received")
+
+	filter, err := model.ParseAuditLogQuery(request.URL.Query())
+	if err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	// One extra event tells if there is a next page.
+	pageFilter := *filter
+	pageFilter.Limit++
+
+	events, err := storage.TableAuditLog.List(request.Context(), manage.dbInstance.GetPool(), &pageFilter)
+	if err != nil {
+		log.Printf("GetAuditLog - list events err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareAuditLog(filter, events), http.StatusOK)
+}
+
+// auditRequest makes an audit event of every request. The target is the route params, the outcome
+// follows the response status. The actor and the details are filled in by the authorization and the handler,
+// the details of a failure default to the status text.
+func auditRequest(dbInstance *database.Database, action string, next httprouter.Handle) httprouter.Handle {
+	return func(respWriter http.ResponseWriter, request *http.Request, routerParams httprouter.Params) {
+		event := storage.AuditEvent{ //nolint:exhaustruct // filled in by the handlers.
+			Action: action,
+			Target: auditTarget(routerParams),
+		}
+		recorder := statusRecorder{ResponseWriter: respWriter, status: http.StatusOK}
+
+		next(&recorder, request.WithContext(context.WithValue(request.Context(), ctxKeyAuditEvent, &event)),
+			routerParams)
+
+		event.Outcome = auditOutcome(recorder.status)
+		if event.Outcome != storage.AuditOutcomeSuccess && event.Details == "" {
+			event.Details = http.StatusText(recorder.status)
+		}
+
+		writeAuditEvent(request, dbInstance, &event)
+	}
+}
+
+// auditLog records a security relevant event of the request. Within an audited request the message adds to
+// the details of the request's event, the actor and the target are taken if the request's ones are unknown.
+// Otherwise the event is written on its own, by default as a success of the request's method and path.
+func auditLog(request *http.Request, dbInstance *database.Database, event storage.AuditEvent,
+	format string, args ...any) {
+	details := fmt.Sprintf(format, args...)
+
+	if requestEvent, ok := request.Context().Value(ctxKeyAuditEvent).(*storage.AuditEvent); ok {
+		if requestEvent.Actor == "" {
+			requestEvent.ActorID, requestEvent.Actor = event.ActorID, event.Actor
+		}
+
+		if requestEvent.Target == "" {
+			requestEvent.Target = event.Target
+		}
+
+		if requestEvent.Details != "" {
+			details = requestEvent.Details + "; " + details
+		}
+
+		requestEvent.Details = details
+
+		return
+	}
+
+	if event.Action == "" {
+		event.Action = request.Method + " " + request.URL.Path
+	}
+
+	if event.Outcome == "" {
+		event.Outcome = storage.AuditOutcomeSuccess
+	}
+
+	event.Details = details
+
+	writeAuditEvent(request, dbInstance, &event)
+}
+
+// setAuditActor names the actor of the audited request, an empty userID is an unknown user.
+func setAuditActor(ctx context.Context, userID, username string) {
+	event, ok := ctx.Value(ctxKeyAuditEvent).(*storage.AuditEvent)
+	if !ok {
+		return
+	}
+
+	event.ActorID, event.Actor = nil, username
+	if userID != "" {
+		event.ActorID = &userID
+	}
+}
+
+// writeAuditEvent appends the event with the client's address and agent to the audit log.
+// The event is logged too, so a failed write doesn't lose it, and doesn't fail the request.
+func writeAuditEvent(request *http.Request, dbInstance *database.Database, event *storage.AuditEvent) {
+	event.IP = clientIP(request)
+	event.UserAgent = request.UserAgent()
+
+	event.Actor = cut(event.Actor, capAuditActor)
+	event.Action = cut(event.Action, capAuditAction)
+	event.Target = cut(event.Target, capAuditTarget)
+	event.IP = cut(event.IP, capAuditIP)
+	event.UserAgent = cut(event.UserAgent, capAuditUserAgent)
+	event.Details = cut(event.Details, capAuditDetails)
+
+	log.Printf("audit: %s %s by %q on %q from %s: %s", event.Outcome, event.Action, event.Actor, event.Target,
+		event.IP, event.Details)
+
+	// the event is written even if the client has gone.
+	err := storage.TableAuditLog.Add(context.WithoutCancel(request.Context()), dbInstance.GetPool(), event)
+	if err != nil {
+		log.Printf("writeAuditEvent - add event err: %s", err.Error())
+	}
+}
+
+// auditTarget lists the route params as name=value.
+func auditTarget(routerParams httprouter.Params) string {
+	target := make([]string, 0, len(routerParams))
+	for _, param := range routerParams {
+		target = append(target, param.Key+"="+param.Value)
+	}
+
+	return strings.Join(target, " ")
+}
+
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return storage.AuditOutcomeDenied
+	case status >= http.StatusOK && status < http.StatusMultipleChoices:
+		return storage.AuditOutcomeSuccess
+	default:
+		return storage.AuditOutcomeFailure
+	}
+}
+
+// clientIP is the address the IP rate limiter sees, or the peer's address if there is none.
+func clientIP(request *http.Request) string {
+	if ip := request.Header.Get(defaultRateLimiterIPSourceHeader); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+}
+
+// cut shortens the string to the count of runes, replacing what the database can't store.
+func cut(str string, runes int) string {
+	str = strings.ReplaceAll(strings.ToValidUTF8(str, "\uFFFD"), "\x00", "")
+
+	if len(str) <= runes {
+		return str
+	}
+
+	if converted := []rune(str); len(converted) > runes {
+		return string(converted[:runes])
+	}
+
+	return str
+}
+
+// statusRecorder remembers the status of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
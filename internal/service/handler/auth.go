@@ -1,35 +1,47 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/eldarbr/go-auth/internal/model"
 	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/bootstrap"
 	"github.com/eldarbr/go-auth/internal/service/encrypt"
 	"github.com/eldarbr/go-auth/pkg/database"
 	"github.com/julienschmidt/httprouter"
 )
 
 type AuthHandl struct {
-	cache         CacheImpl
-	dbInstance    *database.Database
-	jwtService    *encrypt.JWTService
-	sessionDomain string
-	reqLimit      int
+	cache           CacheImpl
+	dbInstance      *database.Database
+	jwtService      *encrypt.JWTService
+	sessionDomain   string
+	tokenAttributes []string
+	registration    model.RegistrationPolicy
+	setupToken      *bootstrap.SetupToken
+	reqLimit        int
 }
 
+// NewAuthHandl creates the auth handler. The tokenAttributes are the user attributes copied into the tokens,
+// the registration governs the self-registration. A non-nil setupToken lets the first root be set up.
 func NewAuthHandl(dbInstance *database.Database, jwtService *encrypt.JWTService,
-	cache CacheImpl, limit int, sessionDomain string) AuthHandl {
+	cache CacheImpl, limit int, sessionDomain string, tokenAttributes []string,
+	registration model.RegistrationPolicy, setupToken *bootstrap.SetupToken) AuthHandl {
 	srv := AuthHandl{
-		dbInstance:    dbInstance,
-		jwtService:    jwtService,
-		cache:         cache,
-		reqLimit:      limit,
-		sessionDomain: sessionDomain,
+		dbInstance:      dbInstance,
+		jwtService:      jwtService,
+		cache:           cache,
+		reqLimit:        limit,
+		sessionDomain:   sessionDomain,
+		tokenAttributes: tokenAttributes,
+		registration:    registration,
+		setupToken:      setupToken,
 	}
 
 	return srv
@@ -62,7 +74,7 @@ func (authHandl AuthHandl) InitSession(respWriter http.ResponseWriter,
 	}
 
 	responseCookie := http.Cookie{
-		Name:     "tokenid",
+		Name:     sessionCookieName,
 		Value:    token,
 		Domain:   authHandl.sessionDomain,
 		Secure:   true,
@@ -87,6 +99,8 @@ func (authHandl AuthHandl) getToken(respWriter http.ResponseWriter, request *htt
 		return "", nil
 	}
 
+	setAuditActor(request.Context(), "", creds.Username)
+
 	lookups := authHandl.cache.GetAndIncrease("usr:" + creds.Username)
 	if lookups > authHandl.reqLimit {
 		writeJSONResponse(respWriter, model.ErrorResponse{Error: "rate limited"}, http.StatusTooManyRequests)
@@ -96,6 +110,10 @@ func (authHandl AuthHandl) getToken(respWriter http.ResponseWriter, request *htt
 
 	// Get username entry from the db.
 	dbUser, err := storage.TableUsers.GetByUsername(request.Context(), authHandl.dbInstance.GetPool(), creds.Username)
+	if err == nil {
+		setAuditActor(request.Context(), dbUser.ID, dbUser.Username)
+	}
+
 	if errors.Is(err, database.ErrNoRows) || !encrypt.PasswordCompare(creds.Password, dbUser.Password) {
 		// ErrNoRows or wrong hash -> unauthorized.
 		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
@@ -111,31 +129,120 @@ func (authHandl AuthHandl) getToken(respWriter http.ResponseWriter, request *htt
 		return "", nil
 	}
 
-	// Get user's roles.
-	dbUserRoles, err := storage.TableUsersRoles.GetByUserID(request.Context(), authHandl.dbInstance.GetPool(),
-		dbUser.ID)
-	if err != nil && !errors.Is(err, database.ErrNoRows) {
-		log.Printf("TableUsersGroups.GetByUsername %s: %s", creds.Username, err.Error())
-		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+	if dbUser.Status != storage.UserStatusTypeActive {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user is not active"}, http.StatusForbidden)
 
 		return "", nil
 	}
 
-	// Convert the roles to custom jwt claims.
-	claims := model.PrepareClaims(dbUserRoles)
-
-	// Issue a token.
-	token, expires, err := authHandl.jwtService.IssueToken(encrypt.AuthCustomClaims{
-		Username: dbUser.Username,
-		Roles:    claims,
-		UserID:   dbUser.ID,
-	})
+	token, expires, err := issueUserToken(request.Context(), authHandl.dbInstance.GetPool(), authHandl.jwtService,
+		dbUser, authHandl.tokenAttributes, nil, nil)
 	if err != nil {
-		log.Printf("jwtService.IssueToken: %s", err.Error())
+		log.Printf("issueUserToken %s: %s", creds.Username, err.Error())
 		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
 
 		return "", nil
 	}
 
+	// The token is issued already, a failed bookkeeping doesn't fail the login.
+	err = storage.TableUsers.UpdateLastLoginByID(request.Context(), authHandl.dbInstance.GetPool(), dbUser.ID)
+	if err != nil {
+		log.Printf("TableUsers.UpdateLastLoginByID %s: %s", creds.Username, err.Error())
+	}
+
 	return token, expires
 }
+
+// IssueUserToken issues a regular token of the user, the way it is issued on the authentication.
+func IssueUserToken(ctx context.Context, querier database.Querier, jwtService *encrypt.JWTService,
+	dbUser *storage.User, tokenAttributes []string, notAfter *time.Time) (string, *time.Time, error) {
+	return issueUserToken(ctx, querier, jwtService, dbUser, tokenAttributes, nil, notAfter)
+}
+
+// issueUserToken issues a token carrying the user's effective roles and permissions.
+// The token never outlives a role it carries, nor the notAfter moment if it is given.
+// The impersonation tokens are issued with the actor.
+func issueUserToken(ctx context.Context, querier database.Querier, jwtService *encrypt.JWTService,
+	dbUser *storage.User, tokenAttributes []string, actor *encrypt.ActorClaim,
+	notAfter *time.Time) (string, *time.Time, error) {
+	// Get user's roles, the direct and the groups' ones.
+	dbUserRoles, err := getEffectiveRoles(ctx, querier, dbUser.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Get the permissions the roles grant.
+	dbPermissions, err := storage.TableRolesPermissions.GetByRoles(ctx, querier, dbUserRoles)
+	if err != nil {
+		return "", nil, fmt.Errorf("issueUserToken get permissions: %w", err)
+	}
+
+	expiresAt := model.EarliestExpiry(dbUserRoles)
+	if notAfter != nil && (expiresAt == nil || notAfter.Before(*expiresAt)) {
+		expiresAt = notAfter
+	}
+
+	token, expires, err := jwtService.IssueTokenNotAfter(encrypt.AuthCustomClaims{
+		Attributes:    model.PrepareTokenAttributes(dbUser.Attributes, tokenAttributes),
+		Permissions:   model.PreparePermissionClaims(dbPermissions),
+		Actor:         actor,
+		Username:      dbUser.Username,
+		UserID:        dbUser.ID,
+		Roles:         model.PrepareClaims(dbUserRoles),
+		Impersonation: actor != nil,
+	}, expiresAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("issueUserToken: %w", err)
+	}
+
+	return token, expires, nil
+}
+
+// Me responds with the info of the token's owner. The token is either a bearer or the session cookie.
+// The roles and permissions are read from the database, so the changes made after the token was issued are seen.
+func (authHandl AuthHandl) Me(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request Me received")
+
+	claims, dbUser, err := authenticateToken(request.Context(), authHandl.dbInstance, authHandl.jwtService,
+		sessionToken(request))
+	if errors.Is(err, errUnauthorized) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("Me - authenticate err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	if claims.IsImpersonation() {
+		auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the rest is defaults.
+			ActorID: &claims.Actor.Subject,
+			Actor:   claims.Actor.Username,
+			Target:  "id=" + claims.UserID,
+		}, "impersonation token of %s used by %s on %s %s", claims.Username, claims.Actor.Username,
+			request.Method, request.URL.Path)
+	}
+
+	dbUserRoles, err := getEffectiveRoles(request.Context(), authHandl.dbInstance.GetPool(), dbUser.ID)
+	if err != nil {
+		log.Printf("Me - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	dbPermissions, err := storage.TableRolesPermissions.GetByRoles(request.Context(), authHandl.dbInstance.GetPool(),
+		dbUserRoles)
+	if err != nil {
+		log.Printf("Me - get permissions err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareMe(dbUser, dbUserRoles, dbPermissions), http.StatusOK)
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+const (
+	bearerPrefix      = "Bearer "
+	sessionCookieName = "tokenid"
+)
+
+// bearerToken returns the token of the Authorization header. The "Bearer " prefix is optional.
+func bearerToken(request *http.Request) string {
+	header := request.Header.Get("Authorization")
+	if len(header) >= len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return header[len(bearerPrefix):]
+	}
+
+	return header
+}
+
+// sessionToken returns the bearer token, or the session cookie's token if there is no bearer one.
+func sessionToken(request *http.Request) string {
+	if token := bearerToken(request); token != "" {
+		return token
+	}
+
+	cookie, err := request.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// authenticateToken validates the token, a JWT or an API key, and checks that its owner may still use it:
+// the user exists, is active, and the token was issued after the last status change of the user.
+// The actor of an impersonation token must be an active user too.
+// Returns errUnauthorized if the token must be rejected.
+func authenticateToken(ctx context.Context, dbInstance *database.Database, jwtService *encrypt.JWTService,
+	token string) (*encrypt.ValidatedClaims, *storage.User, error) {
+	if encrypt.IsAPIKey(token) {
+		return authenticateAPIKey(ctx, dbInstance, token)
+	}
+
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, nil, errUnauthorized
+	}
+
+	dbUser, err := storage.TableUsers.GetByID(ctx, dbInstance.GetPool(), claims.UserID)
+	if errors.Is(err, database.ErrNoRows) {
+		return nil, nil, errUnauthorized
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("authenticateToken get user: %w", err)
+	}
+
+	if dbUser.Status != storage.UserStatusTypeActive {
+		return nil, nil, errUnauthorized
+	}
+
+	// iat has a precision of seconds.
+	if dbUser.StatusChangedTS != nil && claims.IssuedAt.Unix() < dbUser.StatusChangedTS.Unix() {
+		return nil, nil, errUnauthorized
+	}
+
+	if claims.IsImpersonation() {
+		if err = authenticateActor(ctx, dbInstance, claims); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return claims, dbUser, nil
+}
+
+// authenticateActor checks that the actor of the impersonation token exists and is active.
+func authenticateActor(ctx context.Context, dbInstance *database.Database, claims *encrypt.ValidatedClaims) error {
+	if claims.Actor == nil {
+		return errUnauthorized
+	}
+
+	dbActor, err := storage.TableUsers.GetByID(ctx, dbInstance.GetPool(), claims.Actor.Subject)
+	if errors.Is(err, database.ErrNoRows) {
+		return errUnauthorized
+	}
+
+	if err != nil {
+		return fmt.Errorf("authenticateActor get user: %w", err)
+	}
+
+	if dbActor.Status != storage.UserStatusTypeActive {
+		return errUnauthorized
+	}
+
+	return nil
+}
+
+// authenticateAPIKey makes the claims of the API key: the roles of its owner the key is scoped to,
+// and the permissions they grant. The key must not be expired, its owner must be active.
+func authenticateAPIKey(ctx context.Context, dbInstance *database.Database,
+	key string) (*encrypt.ValidatedClaims, *storage.User, error) {
+	dbKey, err := storage.TableAPIKeys.GetByHash(ctx, dbInstance.GetPool(), encrypt.HashAPIKey(key))
+	if errors.Is(err, database.ErrNoRows) {
+		return nil, nil, errUnauthorized
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("authenticateAPIKey get key: %w", err)
+	}
+
+	if dbKey.ExpiresAt != nil && !dbKey.ExpiresAt.After(time.Now()) {
+		return nil, nil, errUnauthorized
+	}
+
+	dbUser, err := storage.TableUsers.GetByID(ctx, dbInstance.GetPool(), dbKey.UserID)
+	if errors.Is(err, database.ErrNoRows) {
+		return nil, nil, errUnauthorized
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("authenticateAPIKey get user: %w", err)
+	}
+
+	if dbUser.Status != storage.UserStatusTypeActive {
+		return nil, nil, errUnauthorized
+	}
+
+	// the keys made before a status change are invalidated like the tokens issued before it.
+	if dbUser.StatusChangedTS != nil && dbKey.CreatedTS.Before(*dbUser.StatusChangedTS) {
+		return nil, nil, errUnauthorized
+	}
+
+	effective, err := getEffectiveRoles(ctx, dbInstance.GetPool(), dbUser.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope := model.APIKeyScope(effective, dbKey.Roles)
+
+	dbPermissions, err := storage.TableRolesPermissions.GetByRoles(ctx, dbInstance.GetPool(), scope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("authenticateAPIKey get permissions: %w", err)
+	}
+
+	// The key is authenticated already, a failed bookkeeping doesn't fail the request.
+	err = storage.TableAPIKeys.UpdateLastUsedByID(ctx, dbInstance.GetPool(), dbKey.ID)
+	if err != nil {
+		log.Printf("authenticateAPIKey update last used %s: %s", dbKey.ID, err.Error())
+	}
+
+	claims := &encrypt.ValidatedClaims{
+		IssuedAt: dbKey.CreatedTS,
+		APIKeyID: dbKey.ID,
+		AuthCustomClaims: encrypt.AuthCustomClaims{ //nolint:exhaustruct // the keys carry no attributes.
+			Permissions: model.PreparePermissionClaims(dbPermissions),
+			Username:    dbUser.Username,
+			UserID:      dbUser.ID,
+			Roles:       model.PrepareClaims(scope),
+		},
+	}
+
+	if dbKey.ExpiresAt != nil {
+		claims.ExpiresAt = *dbKey.ExpiresAt
+	}
+
+	return claims, dbUser, nil
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyInvalidatedByStatusChange(t *testing.T) {
+	t.Parallel()
+	checkDB(t)
+
+	ctx := context.Background()
+
+	user, err := storage.TableUsers.Add(ctx, testDB.GetPool(), &storage.AddUser{
+		Username: "keyed" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	key, hint, hash, err := encrypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	_, err = storage.TableAPIKeys.Add(ctx, testDB.GetPool(), &storage.APIKey{ //nolint:exhaustruct // set by db.
+		UserID:  user.ID,
+		Name:    "key",
+		Hint:    hint,
+		KeyHash: hash,
+	})
+	require.NoError(t, err)
+
+	_, _, err = authenticateAPIKey(ctx, testDB, key)
+	require.NoError(t, err)
+
+	// locked and unlocked, the key made before is not valid anymore.
+	for _, status := range []string{storage.UserStatusTypeLocked, storage.UserStatusTypeActive} {
+		err = storage.TableUsers.UpdateStatusByID(ctx, testDB.GetPool(),
+			&storage.UserStatus{Status: status, Reason: ""}, user.ID)
+		require.NoError(t, err)
+	}
+
+	_, _, err = authenticateAPIKey(ctx, testDB, key)
+	require.ErrorIs(t, err, errUnauthorized)
+}
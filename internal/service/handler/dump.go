@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/service/backup"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ExportState responds with the full state dump. The signing keys are never exported over http,
+// use the dump command for them.
+func (manage ManageHandl) ExportState(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request ExportState received")
+
+	dump, err := backup.Export(request.Context(), manage.dbInstance)
+	if err != nil {
+		log.Printf("ExportState - export err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	respWriter.Header().Set("Content-Disposition", `attachment; filename="go-auth-dump.json"`)
+	writeJSONResponse(respWriter, dump, http.StatusOK)
+}
@@ -0,0 +1,504 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// getUsersRoles reads the roles granted to the users directly and through their groups,
+// see model.EffectiveRoles.
+func getUsersRoles(ctx context.Context, querier database.Querier,
+	userIDs []string) ([]storage.UserRole, []storage.UserGroupRole, error) {
+	direct, err := storage.TableUsersRoles.GetByUserIDs(ctx, querier, userIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getUsersRoles direct: %w", err)
+	}
+
+	viaGroups, err := storage.TableGroupsRoles.GetByUserIDs(ctx, querier, userIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getUsersRoles via groups: %w", err)
+	}
+
+	return direct, viaGroups, nil
+}
+
+// getEffectiveRoles reads the user's effective roles.
+func getEffectiveRoles(ctx context.Context, querier database.Querier, userID string) ([]storage.UserRole, error) {
+	direct, viaGroups, err := getUsersRoles(ctx, querier, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return model.EffectiveRoles(direct, viaGroups), nil
+}
+
+func (manage ManageHandl) GetGroups(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request GetGroups received")
+
+	groups, err := storage.TableGroups.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("GetGroups - get groups err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.GroupsResponse{Groups: model.PrepareGroups(groups)}, http.StatusOK)
+}
+
+func (manage ManageHandl) CreateGroup(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request CreateGroup received")
+
+	var parsedBody model.GroupRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	group, err := storage.TableGroups.Add(request.Context(), manage.dbInstance.GetPool(), parsedBody.Name)
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the group already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("CreateGroup - insert group err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareGroups([]storage.Group{*group})[0], http.StatusOK)
+}
+
+// DeleteGroup deletes the group, its members lose the group's roles.
+func (manage ManageHandl) DeleteGroup(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteGroup received")
+
+	groupName := params.ByName("name")
+
+	if !manage.groupChangeAllowed(respWriter, request, groupName, nil) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("DeleteGroup - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	roles, err := storage.TableGroupsRoles.GetByGroupName(request.Context(), tx, groupName)
+
+	var members []storage.GroupMember
+
+	if err == nil {
+		members, err = storage.TableGroupsUsers.GetByGroupName(request.Context(), tx, groupName)
+	}
+
+	if err == nil {
+		err = storage.TableGroups.Delete(request.Context(), tx, groupName)
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	for i := 0; err == nil && i < len(roles); i++ {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventGroupRoleRevoked,
+			model.PrepareWebhookGroupRole(roles[i]))
+	}
+
+	for i := 0; err == nil && i < len(members); i++ {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventGroupMemberRemoved, model.WebhookGroupMemberData{
+			GroupName: groupName,
+			UserID:    members[i].UserID,
+			Roles:     model.PrepareGroupRoles(roles),
+		})
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("DeleteGroup - delete group err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+func (manage ManageHandl) GetGroupRoles(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetGroupRoles received")
+
+	groupName := params.ByName("name")
+
+	if !manage.groupExists(respWriter, request, groupName) {
+		return
+	}
+
+	roles, err := storage.TableGroupsRoles.GetByGroupName(request.Context(), manage.dbInstance.GetPool(), groupName)
+	if err != nil {
+		log.Printf("GetGroupRoles - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.GroupRolesResponse{
+		GroupName: groupName,
+		Roles:     model.PrepareGroupRoles(roles),
+	}, http.StatusOK)
+}
+
+func (manage ManageHandl) GrantGroupRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GrantGroupRole received")
+
+	var parsedBody model.UserRoleRequest
+
+	groupName := params.ByName("name")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if parsedBody.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	if !manage.groupExists(respWriter, request, groupName) || !manage.serviceExists(respWriter, request,
+		parsedBody.ServiceName) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("GrantGroupRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbRole, err := storage.TableGroupsRoles.Add(request.Context(), tx, &storage.AddGroupRole{
+		GroupName:   groupName,
+		UserRole:    parsedBody.UserRole,
+		ServiceName: parsedBody.ServiceName,
+	})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the group already has a role in the service"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventGroupRoleGranted,
+			model.PrepareWebhookGroupRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("GrantGroupRole - insert role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareGroupRoles([]storage.GroupRole{*dbRole})[0], http.StatusOK)
+}
+
+func (manage ManageHandl) RevokeGroupRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeGroupRole received")
+
+	roleID, err := strconv.ParseUint(params.ByName("roleId"), 10, 0)
+	if err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.groupChangeAllowed(respWriter, request, params.ByName("name"), func(role storage.GroupRole) bool {
+		return role.ID == uint(roleID)
+	}) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RevokeGroupRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	roles, err := storage.TableGroupsRoles.GetByGroupName(request.Context(), tx, params.ByName("name"))
+	roleIdx := slices.IndexFunc(roles, func(role storage.GroupRole) bool { return role.ID == uint(roleID) })
+
+	if err == nil && roleIdx < 0 {
+		err = database.ErrNoRows
+	}
+
+	if err == nil {
+		err = storage.TableGroupsRoles.DeleteByID(request.Context(), tx, params.ByName("name"), uint(roleID))
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventGroupRoleRevoked,
+			model.PrepareWebhookGroupRole(roles[roleIdx]))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("RevokeGroupRole - delete role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+func (manage ManageHandl) GetGroupMembers(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetGroupMembers received")
+
+	groupName := params.ByName("name")
+
+	if !manage.groupExists(respWriter, request, groupName) {
+		return
+	}
+
+	members, err := storage.TableGroupsUsers.GetByGroupName(request.Context(), manage.dbInstance.GetPool(), groupName)
+	if err != nil {
+		log.Printf("GetGroupMembers - get members err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.GroupMembersResponse{
+		GroupName: groupName,
+		Members:   model.PrepareGroupMembers(members),
+	}, http.StatusOK)
+}
+
+func (manage ManageHandl) AddGroupMember(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request AddGroupMember received")
+
+	var parsedBody model.GroupMemberRequest
+
+	groupName := params.ByName("name")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.groupExists(respWriter, request, groupName) || !manage.userExists(respWriter, request,
+		parsedBody.UserID) || !manage.groupChangeAllowed(respWriter, request, groupName, nil) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("AddGroupMember - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableGroupsUsers.Add(request.Context(), tx, groupName, parsedBody.UserID)
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the user is a member already"}, http.StatusConflict)
+
+		return
+	}
+
+	if err == nil {
+		err = publishGroupMember(request.Context(), tx, model.WebhookEventGroupMemberAdded, groupName,
+			parsedBody.UserID)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("AddGroupMember - insert member err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, parsedBody, http.StatusOK)
+}
+
+func (manage ManageHandl) RemoveGroupMember(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RemoveGroupMember received")
+
+	userID := params.ByName("userId")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.groupChangeAllowed(respWriter, request, params.ByName("name"), nil) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RemoveGroupMember - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableGroupsUsers.Delete(request.Context(), tx, params.ByName("name"), userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		err = publishGroupMember(request.Context(), tx, model.WebhookEventGroupMemberRemoved, params.ByName("name"),
+			userID)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("RemoveGroupMember - delete member err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// publishGroupMember publishes the membership event with the roles the member gains or loses.
+func publishGroupMember(ctx context.Context, querier database.Querier, eventType, groupName, userID string) error {
+	roles, err := storage.TableGroupsRoles.GetByGroupName(ctx, querier, groupName)
+	if err != nil {
+		return fmt.Errorf("publishGroupMember: %w", err)
+	}
+
+	err = webhook.Publish(ctx, querier, eventType, model.WebhookGroupMemberData{
+		GroupName: groupName,
+		UserID:    userID,
+		Roles:     model.PrepareGroupRoles(roles),
+	})
+	if err != nil {
+		return fmt.Errorf("publishGroupMember: %w", err)
+	}
+
+	return nil
+}
+
+// groupExists writes the response and returns false if there is no such group.
+func (manage ManageHandl) groupExists(respWriter http.ResponseWriter, request *http.Request,
+	groupName string) bool {
+	_, err := storage.TableGroups.GetByName(request.Context(), manage.dbInstance.GetPool(), groupName)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "group not found"}, http.StatusNotFound)
+
+		return false
+	}
+
+	if err != nil {
+		log.Printf("groupExists - get group err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return false
+	}
+
+	return true
+}
+
+// groupChangeAllowed writes the response and returns false if the change touches a go-auth role of the group,
+// unless the requester is the go-auth root. The members of a group hold its roles. Only the roles the filter
+// matches are touched, a nil filter matches every role of the group.
+func (manage ManageHandl) groupChangeAllowed(respWriter http.ResponseWriter, request *http.Request,
+	groupName string, filter func(role storage.GroupRole) bool) bool {
+	roles, err := storage.TableGroupsRoles.GetByGroupName(request.Context(), manage.dbInstance.GetPool(), groupName)
+	if err != nil && !errors.Is(err, database.ErrNoRows) {
+		log.Printf("groupChangeAllowed - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return false
+	}
+
+	if slices.ContainsFunc(roles, func(role storage.GroupRole) bool {
+		return role.ServiceName == model.MyOwnServiceName && (filter == nil || filter(role))
+	}) {
+		return rootOnly(respWriter, request)
+	}
+
+	return true
+}
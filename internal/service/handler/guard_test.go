@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDBUri = flag.String("t-db-uri", "", "perform sql tests on the `t-db-uri` database")
+
+var testDB *database.Database
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if testDBUri != nil && *testDBUri != "" {
+		// Not checking the error as if there is an error, the tests won't run.
+		testDB, _ = database.Setup(context.Background(), *testDBUri, storage.Migrations())
+
+		defer testDB.ClosePool()
+	}
+
+	m.Run()
+}
+
+func checkDB(t *testing.T) {
+	t.Helper()
+
+	pool := testDB.GetPool()
+	if pool == nil {
+		t.Skip("database was not initialized")
+	}
+}
+
+// requestAs makes a request of an authorized requester, the go-auth root or not.
+func requestAs(isRoot bool, target, body string) *http.Request {
+	ctx := context.WithValue(context.Background(), ctxKeyRequesterUsername, "requester")
+	ctx = context.WithValue(ctx, ctxKeyRequesterUserID, "00000000-0000-0000-0000-000000000000")
+	ctx = context.WithValue(ctx, ctxKeyRequesterIsRoot, isRoot)
+
+	return httptest.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+}
+
+// serveAs runs the handler as an authorized requester, the go-auth root or not, and returns the response status.
+func serveAs(handle httprouter.Handle, isRoot bool, body string, params httprouter.Params) int {
+	recorder := httptest.NewRecorder()
+	handle(recorder, requestAs(isRoot, "/", body), params)
+
+	return recorder.Code
+}
+
+func TestOwnRolesNeedRoot(t *testing.T) {
+	t.Parallel()
+
+	manage := ManageHandl{} //nolint:exhaustruct // the refusals don't reach the database.
+	userID := httprouter.Param{Key: "id", Value: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+	ownService := httprouter.Param{Key: "name", Value: model.MyOwnServiceName}
+	ownRole := `{"serviceName":"go-auth","userRole":"root"}`
+
+	cases := []struct {
+		handle httprouter.Handle
+		name   string
+		body   string
+		params httprouter.Params
+	}{
+		{name: "grant user role", handle: manage.GrantUserRole, body: ownRole, params: httprouter.Params{userID}},
+		{
+			name:   "update user role",
+			handle: manage.UpdateUserRole,
+			body:   ownRole,
+			params: httprouter.Params{userID, {Key: "roleId", Value: "1"}},
+		},
+		{
+			name:   "grant group role",
+			handle: manage.GrantGroupRole,
+			body:   ownRole,
+			params: httprouter.Params{{Key: "name", Value: "group"}},
+		},
+		{
+			name:   "grant role permission",
+			handle: manage.GrantRolePermission,
+			body:   `{"permission":"roles:write"}`,
+			params: httprouter.Params{ownService, {Key: "role", Value: "user"}},
+		},
+		{
+			name:   "revoke role permission",
+			handle: manage.RevokeRolePermission,
+			params: httprouter.Params{ownService, {Key: "role", Value: "user"}, {Key: "permission", Value: "roles:write"}},
+		},
+		{
+			name:   "add implied role",
+			handle: manage.AddImpliedRole,
+			body:   `{"impliedRole":"admin"}`,
+			params: httprouter.Params{ownService, {Key: "role", Value: "user"}},
+		},
+		{
+			name:   "add implied root",
+			handle: manage.AddImpliedRole,
+			body:   `{"impliedRole":"root"}`,
+			params: httprouter.Params{{Key: "name", Value: "service"}, {Key: "role", Value: "user"}},
+		},
+		{
+			name:   "remove implied role",
+			handle: manage.RemoveImpliedRole,
+			params: httprouter.Params{ownService, {Key: "role", Value: "user"}, {Key: "implied", Value: "admin"}},
+		},
+		{
+			name:   "create invitation",
+			handle: manage.CreateInvitation,
+			body:   `{"roles":[{"serviceName":"service","userRole":"user"},` + ownRole + `]}`,
+		},
+		{
+			name:   "create service role",
+			handle: manage.CreateServiceRole,
+			body:   `{"name":"auditor"}`,
+			params: httprouter.Params{ownService},
+		},
+		{
+			name:   "delete service role",
+			handle: manage.DeleteServiceRole,
+			params: httprouter.Params{ownService, {Key: "role", Value: "admin"}},
+		},
+		{
+			name:   "add service member",
+			handle: manage.AddServiceMember,
+			body:   `{"username":"member"}`,
+			params: httprouter.Params{ownService},
+		},
+		{
+			name:   "remove service member",
+			handle: manage.RemoveServiceMember,
+			params: httprouter.Params{ownService, {Key: "userId", Value: userID.Value}},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, http.StatusForbidden, serveAs(testCase.handle, false, testCase.body, testCase.params))
+		})
+	}
+}
+
+func TestImportOwnRolesNeedRoot(t *testing.T) {
+	t.Parallel()
+
+	manage := ManageHandl{} //nolint:exhaustruct // the refusal doesn't reach the database.
+	body := "username,password,roles\nimported,password,service:user;go-auth:root\n"
+
+	recorder := httptest.NewRecorder()
+	manage.ImportUsers(recorder, requestAs(false, "/?format=csv&dryRun=true", body), nil)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestOwnRolesOfGroupsAndUsersNeedRoot(t *testing.T) {
+	t.Parallel()
+	checkDB(t)
+
+	ctx := context.Background()
+	manage := ManageHandl{dbInstance: testDB} //nolint:exhaustruct // only the database is used.
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	err := storage.TableServices.Add(ctx, testDB.GetPool(), &storage.Service{Name: model.MyOwnServiceName})
+	if !errors.Is(err, database.ErrUniqueKeyViolation) {
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, storage.TableServicesRoles.AddDefaults(ctx, testDB.GetPool(), model.MyOwnServiceName))
+
+	user, err := storage.TableUsers.Add(ctx, testDB.GetPool(), &storage.AddUser{
+		Username: "guarded" + suffix,
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	group, err := storage.TableGroups.Add(ctx, testDB.GetPool(), "roots"+suffix)
+	require.NoError(t, err)
+
+	_, err = storage.TableGroupsRoles.Add(ctx, testDB.GetPool(), &storage.AddGroupRole{
+		GroupName:   group.Name,
+		UserRole:    storage.UserRoleTypeRoot,
+		ServiceName: model.MyOwnServiceName,
+	})
+	require.NoError(t, err)
+
+	ownRole, err := storage.TableUsersRoles.Add(ctx, testDB.GetPool(),
+		&storage.AddUserRole{ //nolint:exhaustruct // not time-bound.
+			UserID:      user.ID,
+			UserRole:    storage.UserRoleTypeUser,
+			ServiceName: model.MyOwnServiceName,
+		})
+	require.NoError(t, err)
+
+	groupParam := httprouter.Param{Key: "name", Value: group.Name}
+	member := `{"userId":"` + user.ID + `"}`
+	memberParams := httprouter.Params{groupParam, {Key: "userId", Value: user.ID}}
+	roleParams := httprouter.Params{{Key: "id", Value: user.ID}, {Key: "roleId", Value: strconv.Itoa(int(ownRole.ID))}}
+
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.AddGroupMember, false, member, httprouter.Params{groupParam}))
+	assert.Equal(t, http.StatusOK, serveAs(manage.AddGroupMember, true, member, httprouter.Params{groupParam}))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.RemoveGroupMember, false, "", memberParams))
+	assert.Equal(t, http.StatusOK, serveAs(manage.RemoveGroupMember, true, "", memberParams))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.DeleteGroup, false, "", httprouter.Params{groupParam}))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.RevokeUserRole, false, "", roleParams))
+	assert.Equal(t, http.StatusOK, serveAs(manage.RevokeUserRole, true, "", roleParams))
+	assert.Equal(t, http.StatusOK, serveAs(manage.DeleteGroup, true, "", httprouter.Params{groupParam}))
+}
+
+func TestRootTargetNeedsRoot(t *testing.T) {
+	t.Parallel()
+	checkDB(t)
+
+	ctx := context.Background()
+	manage := ManageHandl{dbInstance: testDB} //nolint:exhaustruct // only the database is used.
+
+	err := storage.TableServices.Add(ctx, testDB.GetPool(), &storage.Service{Name: model.MyOwnServiceName})
+	if !errors.Is(err, database.ErrUniqueKeyViolation) {
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, storage.TableServicesRoles.AddDefaults(ctx, testDB.GetPool(), model.MyOwnServiceName))
+
+	root, err := storage.TableUsers.Add(ctx, testDB.GetPool(), &storage.AddUser{
+		Username: "root" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	rootRole, err := storage.TableUsersRoles.Add(ctx, testDB.GetPool(),
+		&storage.AddUserRole{ //nolint:exhaustruct // not time-bound.
+			UserID:      root.ID,
+			UserRole:    storage.UserRoleTypeRoot,
+			ServiceName: model.MyOwnServiceName,
+		})
+	require.NoError(t, err)
+
+	defer storage.TableUsersRoles.DeleteByID(ctx, testDB.GetPool(), rootRole.ID) //nolint:errcheck // cleanup.
+
+	rootParams := httprouter.Params{{Key: "id", Value: root.ID}}
+	keyParams := httprouter.Params{rootParams[0], {Key: "keyId", Value: "6ba7b811-9dad-11d1-80b4-00c04fd430c8"}}
+	locked := `{"status":"locked"}`
+
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.UpdateUserStatus, false, locked, rootParams))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.DeleteUser, false, "", rootParams))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.RevokeUserAPIKey, false, "", keyParams))
+	assert.Equal(t, http.StatusForbidden, serveAs(manage.UpdateUserAttributes, false, `{}`, rootParams))
+	assert.Equal(t, http.StatusOK, serveAs(manage.UpdateUserStatus, true, `{"status":"active"}`, rootParams))
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetImpliedRoles lists the roles directly implied by the role of the service.
+func (manage ManageHandl) GetImpliedRoles(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetImpliedRoles received")
+
+	serviceName := params.ByName("name")
+	roleName := params.ByName("role")
+
+	implications, err := storage.TableRolesHierarchy.GetByServiceName(request.Context(), manage.dbInstance.GetPool(),
+		serviceName)
+	if err != nil {
+		log.Printf("GetImpliedRoles - get hierarchy err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.RoleImpliedResponse{
+		ServiceName:  serviceName,
+		UserRole:     roleName,
+		ImpliedRoles: model.PrepareImpliedRoles(implications, roleName),
+	}, http.StatusOK)
+}
+
+// AddImpliedRole makes the role of the service imply another role of the same service.
+func (manage ManageHandl) AddImpliedRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request AddImpliedRole received")
+
+	var parsedBody model.RoleImplicationRequest
+
+	roleName := params.ByName("role")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() || parsedBody.ImpliedRole == roleName {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !hierarchyChangeAllowed(respWriter, request, params.ByName("name"), parsedBody.ImpliedRole) {
+		return
+	}
+
+	_, err = storage.TableRolesHierarchy.Add(request.Context(), manage.dbInstance.GetPool(),
+		&storage.RoleImplication{
+			ServiceName: params.ByName("name"),
+			UserRole:    roleName,
+			ImpliedRole: parsedBody.ImpliedRole,
+		})
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the role already implies the role"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("AddImpliedRole - insert implication err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// RemoveImpliedRole makes the role of the service stop implying another role.
+func (manage ManageHandl) RemoveImpliedRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RemoveImpliedRole received")
+
+	if !hierarchyChangeAllowed(respWriter, request, params.ByName("name"), params.ByName("implied")) {
+		return
+	}
+
+	err := storage.TableRolesHierarchy.Delete(request.Context(), manage.dbInstance.GetPool(),
+		params.ByName("name"), params.ByName("role"), params.ByName("implied"))
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RemoveImpliedRole - delete implication err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// hierarchyChangeAllowed writes the response and returns false if the change is to the go-auth hierarchy
+// or to an implication of a root, unless the requester is the go-auth root.
+func hierarchyChangeAllowed(respWriter http.ResponseWriter, request *http.Request,
+	serviceName, impliedRole string) bool {
+	if serviceName != model.MyOwnServiceName && impliedRole != string(storage.UserRoleTypeRoot) {
+		return true
+	}
+
+	return rootOnly(respWriter, request)
+}
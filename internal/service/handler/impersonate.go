@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ImpersonateUser issues a short-lived token of the user to the requester. The token names the requester
+// in its act claim, it is refused on the /manage routes.
+func (manage ManageHandl) ImpersonateUser(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request ImpersonateUser received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	actorID, _ := request.Context().Value(ctxKeyRequesterUserID).(string)
+	actorUsername, _ := request.Context().Value(ctxKeyRequesterUsername).(string)
+
+	if actorID == "" || actorID == userID {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	dbUser, err := storage.TableUsers.GetByID(request.Context(), manage.dbInstance.GetPool(), userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("ImpersonateUser - get user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	if dbUser.Status != storage.UserStatusTypeActive {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user is not active"}, http.StatusForbidden)
+
+		return
+	}
+
+	notAfter := time.Now().Add(manage.impersonationTTL)
+
+	token, expires, err := issueUserToken(request.Context(), manage.dbInstance.GetPool(), manage.jwtService,
+		dbUser, manage.tokenAttributes, &encrypt.ActorClaim{Subject: actorID, Username: actorUsername}, &notAfter)
+	if err != nil {
+		log.Printf("ImpersonateUser - issue token err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{}, //nolint:exhaustruct // the request's event.
+		"impersonation token of %s issued to %s, expires at %s", dbUser.Username, actorUsername,
+		expires.UTC().Format(time.RFC3339))
+
+	writeJSONResponse(respWriter, model.ImpersonationResponse{
+		ExpiresAt:    *expires,
+		UserID:       dbUser.ID,
+		Username:     dbUser.Username,
+		AuthResponse: model.AuthResponse{Token: token},
+	}, http.StatusOK)
+}
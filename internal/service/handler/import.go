@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/service/importer"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	importMaxBodyBytes = 32 << 20
+	importTimeout      = 10 * time.Minute
+)
+
+// ImportUsers creates users in bulk from the csv or jsonl body, see importer.Import.
+// Hashing thousands of passwords takes minutes, so the import lifts the server timeouts.
+func (manage ManageHandl) ImportUsers(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request ImportUsers received")
+
+	query := request.URL.Query()
+
+	opts := importer.Options{
+		DryRun: query.Get("dryRun") == "true",
+	}
+
+	if chunkSize := query.Get("chunkSize"); chunkSize != "" {
+		var err error
+
+		opts.ChunkSize, err = strconv.Atoi(chunkSize)
+		if err != nil || opts.ChunkSize < 0 {
+			writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	controller := http.NewResponseController(respWriter)
+	deadline := time.Now().Add(importTimeout)
+
+	if controller.SetReadDeadline(deadline) != nil || controller.SetWriteDeadline(deadline) != nil {
+		log.Printf("ImportUsers - the server timeouts can't be lifted")
+	}
+
+	rows, parseErrors, err := importer.Parse(http.MaxBytesReader(respWriter, request.Body, importMaxBodyBytes),
+		query.Get("format"))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONResponse(respWriter, model.ErrorResponse{Error: "request too large"},
+				http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if slices.ContainsFunc(rows, func(row model.ImportRow) bool {
+		return slices.ContainsFunc(row.Roles, func(role model.UserRoleRequest) bool {
+			return role.ServiceName == model.MyOwnServiceName
+		})
+	}) && !rootOnly(respWriter, request) {
+		return
+	}
+
+	report, err := importer.Import(request.Context(), manage.dbInstance, rows, parseErrors, opts)
+	if err != nil {
+		log.Printf("ImportUsers - import err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, report, http.StatusOK)
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateInvitation makes an invitation with the pre-assigned roles and returns its invite token.
+func (manage ManageHandl) CreateInvitation(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request CreateInvitation received")
+
+	var parsedBody model.InvitationRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	// the invitee is granted the roles on accepting, so the go-auth ones are the root's to give.
+	if slices.ContainsFunc(parsedBody.Roles, func(role model.UserRoleRequest) bool {
+		return role.ServiceName == model.MyOwnServiceName
+	}) && !rootOnly(respWriter, request) {
+		return
+	}
+
+	expiresAt := time.Now().Add(model.DefaultInvitationTTL)
+	if parsedBody.ExpiresAt != nil {
+		expiresAt = *parsedBody.ExpiresAt
+	}
+
+	var createdBy *string
+
+	requesterID, _ := request.Context().Value(ctxKeyRequesterUserID).(string)
+	if requesterID != "" {
+		createdBy = &requesterID
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("CreateInvitation - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbInvitation := storage.Invitation{ //nolint:exhaustruct // the rest is set by db.
+		ExpiresAt: expiresAt,
+		CreatedBy: createdBy,
+		Roles:     model.PrepareInvitationRoles(parsedBody.Roles),
+	}
+
+	invitation, err := storage.TableInvitations.Add(request.Context(), tx, &dbInvitation)
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	var token string
+
+	if err == nil {
+		token, err = manage.jwtService.IssueInvitationToken(invitation.ID, invitation.ExpiresAt)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("CreateInvitation - create invitation err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		Target: "id=" + invitation.ID,
+	}, "invitation %s created, expires at %s", invitation.ID, invitation.ExpiresAt.UTC().Format(time.RFC3339))
+
+	writeJSONResponse(respWriter, model.InvitationCreateResponse{
+		Token:          token,
+		InvitationInfo: model.PrepareInvitations([]storage.Invitation{*invitation})[0],
+	}, http.StatusOK)
+}
+
+// GetInvitations lists the invitations, the latest first.
+func (manage ManageHandl) GetInvitations(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request GetInvitations received")
+
+	invitations, err := storage.TableInvitations.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("GetInvitations - get invitations err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.InvitationsResponse{
+		Invitations: model.PrepareInvitations(invitations),
+	}, http.StatusOK)
+}
+
+// RevokeInvitation deletes the invitation unless it has been redeemed, its token is no longer valid.
+func (manage ManageHandl) RevokeInvitation(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeInvitation received")
+
+	invitationID := params.ByName("id")
+	if !model.ValidUUID(invitationID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	err := storage.TableInvitations.DeleteByID(request.Context(), manage.dbInstance.GetPool(), invitationID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RevokeInvitation - delete invitation err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{}, //nolint:exhaustruct // the request's event.
+		"invitation %s revoked", invitationID)
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// RedeemInvitation creates the invitee's user with the chosen credentials and grants the invitation's roles.
+// An invitation is redeemed once.
+func (authHandl AuthHandl) RedeemInvitation(respWriter http.ResponseWriter, request *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request RedeemInvitation received")
+
+	var parsedBody model.RedeemInvitationRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	invitationID, err := authHandl.jwtService.ValidateInvitationToken(parsedBody.Token)
+	if err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+
+		return
+	}
+
+	hashedPassword, err := encrypt.PasswordEncrypt(parsedBody.Password)
+	if err != nil {
+		log.Printf("RedeemInvitation - hash password err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	tx, err := authHandl.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RedeemInvitation - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	invitation, err := storage.TableInvitations.GetByID(request.Context(), tx, invitationID)
+	if err == nil && model.InvitationStatus(*invitation, time.Now()) != model.InvitationStatusPending {
+		err = database.ErrNoRows
+	}
+
+	var dbUser *storage.User
+
+	if err == nil {
+		dbUser, err = storage.TableUsers.Add(request.Context(), tx, &storage.AddUser{
+			Username: parsedBody.Username,
+			Password: hashedPassword,
+		})
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserCreated, model.PrepareWebhookUser(dbUser))
+	}
+
+	for i := 0; err == nil && i < len(invitation.Roles); i++ {
+		role := storage.AddUserRole{ //nolint:exhaustruct // the role is not time-bound.
+			UserID:      dbUser.ID,
+			ServiceName: invitation.Roles[i].ServiceName,
+			UserRole:    invitation.Roles[i].UserRole,
+		}
+
+		var dbRole *storage.UserRole
+
+		dbRole, err = storage.TableUsersRoles.Add(request.Context(), tx, &role)
+		if err == nil {
+			err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleGranted,
+				model.PrepareWebhookRole(*dbRole))
+		}
+	}
+
+	if err == nil {
+		err = storage.TableInvitations.Redeem(request.Context(), tx, invitationID, dbUser.ID)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the invitation is not valid"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the username is taken"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RedeemInvitation - create user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		ActorID: &dbUser.ID,
+		Actor:   dbUser.Username,
+		Target:  "id=" + invitationID,
+	}, "invitation %s redeemed by %s", invitationID, dbUser.Username)
+
+	writeJSONResponse(respWriter, model.UserCreateResponse{
+		UserID:      dbUser.ID,
+		UserUsernme: model.UserUsernme{Username: dbUser.Username},
+	}, http.StatusOK)
+}
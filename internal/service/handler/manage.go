@@ -4,36 +4,48 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/eldarbr/go-auth/internal/model"
 	"github.com/eldarbr/go-auth/internal/provider/storage"
 	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
 	"github.com/eldarbr/go-auth/pkg/database"
 	"github.com/julienschmidt/httprouter"
 )
 
 type ManageHandl struct {
-	dbInstance *database.Database
-	jwtService *encrypt.JWTService
-	cache      CacheImpl
-	reqLimit   int
+	dbInstance       *database.Database
+	jwtService       *encrypt.JWTService
+	cache            CacheImpl
+	tokenAttributes  []string
+	reqLimit         int
+	impersonationTTL time.Duration
 }
 
 type ctxKey string
 
 const (
 	ctxKeyRequesterUsername ctxKey = "RequesterUsername"
+	ctxKeyRequesterUserID   ctxKey = "RequesterUserID"
+	ctxKeyRequesterIsRoot   ctxKey = "RequesterIsRoot"
 )
 
+// NewManageHandl creates the manage handler. The tokenAttributes are the user attributes copied into
+// the impersonation tokens, which live for the impersonationTTL at most.
 func NewManageHandl(dbInstance *database.Database, jwtService *encrypt.JWTService,
-	cache CacheImpl, limit int) ManageHandl {
+	cache CacheImpl, limit int, tokenAttributes []string, impersonationTTL time.Duration) ManageHandl {
 	srv := ManageHandl{
-		dbInstance: dbInstance,
-		jwtService: jwtService,
-		cache:      cache,
-		reqLimit:   limit,
+		dbInstance:       dbInstance,
+		jwtService:       jwtService,
+		cache:            cache,
+		tokenAttributes:  tokenAttributes,
+		reqLimit:         limit,
+		impersonationTTL: impersonationTTL,
 	}
 
 	return srv
@@ -64,7 +76,32 @@ func (manage ManageHandl) CreateUser(respWriter http.ResponseWriter, request *ht
 		Password: hashedPassword,
 	}
 
-	dbCreatedUser, err := storage.TableUsers.Add(request.Context(), manage.dbInstance.GetPool(), &dbUser)
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("CreateUser - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbCreatedUser, err := storage.TableUsers.Add(request.Context(), tx, &dbUser)
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the username is taken"}, http.StatusConflict)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserCreated,
+			model.PrepareWebhookUser(dbCreatedUser))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
 	if err != nil {
 		log.Printf("CreateUser - insert user err: %s", err.Error())
 		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
@@ -97,9 +134,9 @@ func (manage ManageHandl) GetUserInfo(respWriter http.ResponseWriter, request *h
 		return
 	}
 
-	roles, rolesErr := storage.TableUsersRoles.GetByUserID(request.Context(),
-		manage.dbInstance.GetPool(), userInfo.ID)
-	if rolesErr != nil && !errors.Is(rolesErr, database.ErrNoRows) {
+	direct, viaGroups, rolesErr := getUsersRoles(request.Context(), manage.dbInstance.GetPool(),
+		[]string{userInfo.ID})
+	if rolesErr != nil {
 		log.Printf("GetUserInfo - get user err: %s", rolesErr.Error())
 		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
 
@@ -107,37 +144,422 @@ func (manage ManageHandl) GetUserInfo(respWriter http.ResponseWriter, request *h
 	}
 
 	response := model.UserInfoResponse{
-		Username: requestedUsername,
-		UserID:   userInfo.ID,
-		Roles:    model.PrepareClaims(roles),
+		Username:    requestedUsername,
+		UserID:      userInfo.ID,
+		Status:      userInfo.Status,
+		Roles:       model.PrepareClaims(model.EffectiveRoles(direct, viaGroups)),
+		RoleSources: model.PrepareRoleSources(direct, viaGroups),
 	}
 
 	writeJSONResponse(respWriter, response, http.StatusOK)
 }
 
-// Checks if the user has any of the claims.
+// GetUsers serves the user info if the username is requested, and the users list otherwise.
+func (manage ManageHandl) GetUsers(respWriter http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	if request.URL.Query().Has("username") {
+		manage.GetUserInfo(respWriter, request, params)
+
+		return
+	}
+
+	manage.ListUsers(respWriter, request, params)
+}
+
+func (manage ManageHandl) ListUsers(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request ListUsers received")
+
+	filter, err := model.ParseUserListQuery(request.URL.Query())
+	if err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	// One extra user tells if there is a next page.
+	pageFilter := *filter
+	pageFilter.Limit++
+
+	users, err := storage.TableUsers.List(request.Context(), manage.dbInstance.GetPool(), &pageFilter)
+	if err != nil {
+		log.Printf("ListUsers - list users err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	direct, viaGroups, err := getUsersRoles(request.Context(), manage.dbInstance.GetPool(), userIDs)
+	if err != nil {
+		log.Printf("ListUsers - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareUserList(filter, users, model.EffectiveRoles(direct, viaGroups)),
+		http.StatusOK)
+}
+
+// UpdateUserStatus disables, locks or reactivates the user.
+// Any status change invalidates the tokens issued before it.
+func (manage ManageHandl) UpdateUserStatus(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request UpdateUserStatus received")
+
+	var parsedBody model.UserStatusRequest
+
+	userID := params.ByName("id")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() || !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if requesterID, _ := request.Context().Value(ctxKeyRequesterUserID).(string); requesterID == userID {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "can't change own status"}, http.StatusForbidden)
+
+		return
+	}
+
+	if !manage.rootTargetAllowed(respWriter, request, userID) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("UpdateUserStatus - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableUsers.UpdateStatusByID(request.Context(), tx, &storage.UserStatus{
+		Status: parsedBody.Status,
+		Reason: parsedBody.Reason,
+	}, userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	var dbUser *storage.User
+
+	if err == nil {
+		dbUser, err = storage.TableUsers.GetByID(request.Context(), tx, userID)
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserStatusChanged,
+			model.PrepareWebhookUser(dbUser))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("UpdateUserStatus - update status err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareUserStatus(dbUser), http.StatusOK)
+}
+
+// DeleteUser soft deletes the user. The user can be restored until purged.
+func (manage ManageHandl) DeleteUser(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteUser received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if requesterID, _ := request.Context().Value(ctxKeyRequesterUserID).(string); requesterID == userID {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "can't delete self"}, http.StatusForbidden)
+
+		return
+	}
+
+	if !manage.rootTargetAllowed(respWriter, request, userID) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("DeleteUser - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	// the user is looked up first, a deleted one is not found.
+	dbUser, err := storage.TableUsers.GetByID(request.Context(), tx, userID)
+	if err == nil {
+		err = storage.TableUsers.SoftDeleteByID(request.Context(), tx, userID)
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserDeleted, model.PrepareWebhookUser(dbUser))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("DeleteUser - delete user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+func (manage ManageHandl) RestoreUser(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RestoreUser received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RestoreUser - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableUsers.RestoreByID(request.Context(), tx, userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "deleted user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	var dbUser *storage.User
+
+	if err == nil {
+		dbUser, err = storage.TableUsers.GetByID(request.Context(), tx, userID)
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRestored, model.PrepareWebhookUser(dbUser))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("RestoreUser - restore user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.UserCreateResponse{
+		UserID:      dbUser.ID,
+		UserUsernme: model.UserUsernme{Username: dbUser.Username},
+	}, http.StatusOK)
+}
+
+// Checks if the user has any of the claims, the roles implied by the user's roles count.
 func (manage ManageHandl) MiddlewareAuthorizeAnyClaim(requestedClaims []encrypt.ClaimUserRole,
+	next httprouter.Handle) httprouter.Handle {
+	return manage.authorizeClaims(func(request *http.Request, claims *encrypt.ValidatedClaims) (bool, error) {
+		hierarchy, err := getRoleHierarchy(request.Context(), manage.dbInstance.GetPool(), requestedClaims)
+
+		return err == nil && claims.ContainAny(requestedClaims, hierarchy), err
+	}, next)
+}
+
+// MiddlewareAuthorizeAllClaims checks if the user has every one of the claims,
+// the roles implied by the user's roles count.
+func (manage ManageHandl) MiddlewareAuthorizeAllClaims(requestedClaims []encrypt.ClaimUserRole,
+	next httprouter.Handle) httprouter.Handle {
+	return manage.authorizeClaims(func(request *http.Request, claims *encrypt.ValidatedClaims) (bool, error) {
+		hierarchy, err := getRoleHierarchy(request.Context(), manage.dbInstance.GetPool(), requestedClaims)
+
+		return err == nil && claims.ContainAll(requestedClaims, hierarchy), err
+	}, next)
+}
+
+// MiddlewareAuthorizePermission checks if the user has the permission in the service.
+// The go-auth root has every permission.
+func (manage ManageHandl) MiddlewareAuthorizePermission(serviceName, permission string,
+	next httprouter.Handle) httprouter.Handle {
+	return manage.authorizeClaims(func(_ *http.Request, claims *encrypt.ValidatedClaims) (bool, error) {
+		return claims.HasPermission(serviceName, permission) || claims.ContainAny([]encrypt.ClaimUserRole{
+			{ServiceName: model.MyOwnServiceName, UserRole: storage.UserRoleTypeRoot},
+		}, nil), nil
+	}, next)
+}
+
+// authorizeClaims authenticates the bearer token and passes the request on if the claims are allowed.
+// The impersonation tokens are never allowed.
+// The requester is put into the context for the next handler.
+func (manage ManageHandl) authorizeClaims(
+	allowed func(request *http.Request, claims *encrypt.ValidatedClaims) (bool, error),
 	next httprouter.Handle) httprouter.Handle {
 	return func(respWriter http.ResponseWriter, request *http.Request, routerParams httprouter.Params) {
-		claims, err := manage.jwtService.ValidateToken(request.Header.Get("Authorization"))
-		if err != nil {
+		claims, _, err := authenticateToken(request.Context(), manage.dbInstance, manage.jwtService,
+			bearerToken(request))
+		if errors.Is(err, errUnauthorized) {
 			writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
 
 			return
 		}
 
-		if !claims.ContainAny(requestedClaims) {
+		if err == nil {
+			setAuditActor(request.Context(), claims.UserID, claims.Username)
+		}
+
+		if err == nil && claims.IsImpersonation() {
+			auditLog(request, manage.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the rest is defaults.
+				ActorID: &claims.Actor.Subject,
+				Actor:   claims.Actor.Username,
+				Target:  "id=" + claims.UserID,
+				Outcome: storage.AuditOutcomeDenied,
+			}, "impersonation token of %s by %s refused on %s %s", claims.Username, claims.Actor.Username,
+				request.Method, request.URL.Path)
+			writeJSONResponse(respWriter, model.ErrorResponse{Error: "impersonation token is not allowed"},
+				http.StatusForbidden)
+
+			return
+		}
+
+		var ok bool
+
+		if err == nil {
+			ok, err = allowed(request, claims)
+		}
+
+		if err != nil {
+			log.Printf("authorizeClaims - authorize err: %s", err.Error())
+			writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+			return
+		}
+
+		if !ok {
 			writeJSONResponse(respWriter, model.ErrorResponse{Error: "forbidden"}, http.StatusForbidden)
 
 			return
 		}
 
 		nextCtx := context.WithValue(request.Context(), ctxKeyRequesterUsername, claims.Username)
+		nextCtx = context.WithValue(nextCtx, ctxKeyRequesterUserID, claims.UserID)
+		// a held root only, the root implied through the hierarchy doesn't count.
+		nextCtx = context.WithValue(nextCtx, ctxKeyRequesterIsRoot, claims.ContainAny([]encrypt.ClaimUserRole{
+			{ServiceName: model.MyOwnServiceName, UserRole: storage.UserRoleTypeRoot},
+		}, nil))
 
 		next(respWriter, request.WithContext(nextCtx), routerParams)
 	}
 }
 
+// rootOnly writes the response and returns false unless the requester is the go-auth root.
+// The go-auth roles, their permissions and hierarchy grant the manage permissions, so only the root changes them,
+// otherwise a delegated admin could mint the root.
+func rootOnly(respWriter http.ResponseWriter, request *http.Request) bool {
+	if isRoot, _ := request.Context().Value(ctxKeyRequesterIsRoot).(bool); isRoot {
+		return true
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: "only the go-auth root may change the go-auth roles"},
+		http.StatusForbidden)
+
+	return false
+}
+
+// rootTargetAllowed writes the response and returns false if the user holds the go-auth root,
+// unless the requester is the go-auth root too. A delegated user admin can't lock the root out.
+func (manage ManageHandl) rootTargetAllowed(respWriter http.ResponseWriter, request *http.Request,
+	userID string) bool {
+	if isRoot, _ := request.Context().Value(ctxKeyRequesterIsRoot).(bool); isRoot {
+		return true
+	}
+
+	roles, err := getEffectiveRoles(request.Context(), manage.dbInstance.GetPool(), userID)
+	if err != nil {
+		log.Printf("rootTargetAllowed - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return false
+	}
+
+	if slices.ContainsFunc(roles, func(role storage.UserRole) bool {
+		return role.ServiceName == model.MyOwnServiceName && role.UserRole == storage.UserRoleTypeRoot
+	}) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "only the go-auth root may change the go-auth root"},
+			http.StatusForbidden)
+
+		return false
+	}
+
+	return true
+}
+
+// getRoleHierarchy reads the roles hierarchy of the services of the requested claims.
+func getRoleHierarchy(ctx context.Context, querier database.Querier,
+	requestedClaims []encrypt.ClaimUserRole) (encrypt.RoleHierarchy, error) {
+	serviceNames := make([]string, 0, len(requestedClaims))
+	for _, claim := range requestedClaims {
+		serviceNames = append(serviceNames, claim.ServiceName)
+	}
+
+	implications, err := storage.TableRolesHierarchy.GetByServiceNames(ctx, querier, serviceNames)
+	if err != nil {
+		return nil, fmt.Errorf("getRoleHierarchy: %w", err)
+	}
+
+	return model.PrepareRoleHierarchy(implications), nil
+}
+
+// MiddlewareAuthorizeServiceAdmin checks if the user administers the service named by the serviceParam
+// route param. The go-auth root administers every service.
+func (manage ManageHandl) MiddlewareAuthorizeServiceAdmin(serviceParam string,
+	next httprouter.Handle) httprouter.Handle {
+	return func(respWriter http.ResponseWriter, request *http.Request, routerParams httprouter.Params) {
+		requestedClaims := []encrypt.ClaimUserRole{
+			{ServiceName: model.MyOwnServiceName, UserRole: storage.UserRoleTypeRoot},
+			{ServiceName: routerParams.ByName(serviceParam), UserRole: storage.UserRoleTypeAdmin},
+		}
+
+		manage.MiddlewareAuthorizeAnyClaim(requestedClaims, next)(respWriter, request, routerParams)
+	}
+}
+
 func (manage ManageHandl) MiddlewareRateLimit(next httprouter.Handle) httprouter.Handle {
 	return func(respWriter http.ResponseWriter, request *http.Request, routerParams httprouter.Params) {
 		if manage.cache == nil {
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// The members handlers are available to the service admins, so they only ever
+// grant and revoke the plain user role. Other roles are managed by the go-auth root.
+
+func (manage ManageHandl) GetServiceMembers(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetServiceMembers received")
+
+	serviceName := params.ByName("name")
+
+	if !manage.serviceExists(respWriter, request, serviceName) {
+		return
+	}
+
+	members, err := storage.TableUsersRoles.GetMembersByServiceName(request.Context(),
+		manage.dbInstance.GetPool(), serviceName)
+	if err != nil {
+		log.Printf("GetServiceMembers - get members err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ServiceMembersResponse{
+		ServiceName: serviceName,
+		Members:     model.PrepareServiceMembers(members),
+	}, http.StatusOK)
+}
+
+func (manage ManageHandl) AddServiceMember(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request AddServiceMember received")
+
+	var parsedBody model.ServiceMemberRequest
+
+	serviceName := params.ByName("name")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || parsedBody.Username == "" {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if serviceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	if !manage.serviceExists(respWriter, request, serviceName) {
+		return
+	}
+
+	dbUser, err := storage.TableUsers.GetByUsername(request.Context(), manage.dbInstance.GetPool(),
+		parsedBody.Username)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("AddServiceMember - get user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("AddServiceMember - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbRole, err := storage.TableUsersRoles.Add(request.Context(), tx, &storage.AddUserRole{
+		UserID:      dbUser.ID,
+		UserRole:    storage.UserRoleTypeUser,
+		ServiceName: serviceName,
+	})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the user already has a role in the service"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("AddServiceMember - insert role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareServiceMembers([]storage.ServiceMember{{
+		Username: dbUser.Username,
+		UserRole: *dbRole,
+	}})[0], http.StatusOK)
+}
+
+func (manage ManageHandl) RemoveServiceMember(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RemoveServiceMember received")
+
+	serviceName := params.ByName("name")
+	userID := params.ByName("userId")
+
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if serviceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	dbRole, err := storage.TableUsersRoles.GetByUserIDAndServiceName(request.Context(), manage.dbInstance.GetPool(),
+		userID, serviceName)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RemoveServiceMember - get role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	if dbRole.UserRole != storage.UserRoleTypeUser {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "forbidden"}, http.StatusForbidden)
+
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RemoveServiceMember - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableUsersRoles.DeleteByID(request.Context(), tx, dbRole.ID)
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleRevoked, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil && !errors.Is(err, database.ErrNoRows) {
+		log.Printf("RemoveServiceMember - delete role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
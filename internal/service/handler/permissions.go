@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetServicePermissions lists the permissions catalog of the service.
+func (manage ManageHandl) GetServicePermissions(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetServicePermissions received")
+
+	serviceName := params.ByName("name")
+
+	if !manage.serviceExists(respWriter, request, serviceName) {
+		return
+	}
+
+	permissions, err := storage.TableServicesPermissions.GetByServiceName(request.Context(),
+		manage.dbInstance.GetPool(), serviceName)
+	if err != nil {
+		log.Printf("GetServicePermissions - get permissions err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ServicePermissionsResponse{
+		ServiceName: serviceName,
+		Permissions: model.PrepareServicePermissions(permissions),
+	}, http.StatusOK)
+}
+
+// CreateServicePermission defines a new permission in the service's catalog.
+func (manage ManageHandl) CreateServicePermission(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request CreateServicePermission received")
+
+	var parsedBody model.ServicePermissionRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	dbPermission, err := storage.TableServicesPermissions.Add(request.Context(), manage.dbInstance.GetPool(),
+		&storage.ServicePermission{
+			ServiceName: params.ByName("name"),
+			Name:        parsedBody.Name,
+			Description: parsedBody.Description,
+		})
+	if errors.Is(err, database.ErrForeignKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the permission already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("CreateServicePermission - insert permission err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter,
+		model.PrepareServicePermissions([]storage.ServicePermission{*dbPermission})[0], http.StatusOK)
+}
+
+// DeleteServicePermission removes the permission from the service's catalog, the roles stop granting it.
+func (manage ManageHandl) DeleteServicePermission(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteServicePermission received")
+
+	err := storage.TableServicesPermissions.Delete(request.Context(), manage.dbInstance.GetPool(),
+		params.ByName("name"), params.ByName("permission"))
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("DeleteServicePermission - delete permission err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// GetRolePermissions lists the permissions granted by the role of the service.
+func (manage ManageHandl) GetRolePermissions(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetRolePermissions received")
+
+	serviceName := params.ByName("name")
+	roleName := params.ByName("role")
+
+	permissions, err := storage.TableRolesPermissions.GetByRole(request.Context(), manage.dbInstance.GetPool(),
+		serviceName, roleName)
+	if err != nil {
+		log.Printf("GetRolePermissions - get permissions err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.RolePermissionsResponse{
+		ServiceName: serviceName,
+		UserRole:    roleName,
+		Permissions: model.PrepareRolePermissions(permissions),
+	}, http.StatusOK)
+}
+
+// GrantRolePermission makes the role of the service grant the permission.
+func (manage ManageHandl) GrantRolePermission(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GrantRolePermission received")
+
+	var parsedBody model.RolePermissionRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if params.ByName("name") == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	_, err = storage.TableRolesPermissions.Add(request.Context(), manage.dbInstance.GetPool(),
+		&storage.RolePermission{
+			ServiceName: params.ByName("name"),
+			UserRole:    params.ByName("role"),
+			Permission:  parsedBody.Permission,
+		})
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownPermission) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown permission"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the role already has the permission"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("GrantRolePermission - insert permission err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// RevokeRolePermission makes the role of the service stop granting the permission.
+func (manage ManageHandl) RevokeRolePermission(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeRolePermission received")
+
+	if params.ByName("name") == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	err := storage.TableRolesPermissions.Delete(request.Context(), manage.dbInstance.GetPool(),
+		params.ByName("name"), params.ByName("role"), params.ByName("permission"))
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RevokeRolePermission - delete permission err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
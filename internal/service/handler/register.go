@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	registerChallengeTTL    = 5 * time.Minute
+	registerVerificationTTL = 24 * time.Hour
+)
+
+// GetRegisterChallenge issues a proof-of-work challenge to be solved before signing up.
+func (authHandl AuthHandl) GetRegisterChallenge(respWriter http.ResponseWriter, _ *http.Request,
+	_ httprouter.Params) {
+	log.Printf("request GetRegisterChallenge received")
+
+	if !authHandl.registration.Enabled() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "registration is disabled"}, http.StatusForbidden)
+
+		return
+	}
+
+	challenge, expiresAt, err := authHandl.jwtService.IssueChallengeToken(registerChallengeTTL)
+	if err != nil {
+		log.Printf("GetRegisterChallenge - issue challenge err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ChallengeResponse{
+		ExpiresAt:  *expiresAt,
+		Challenge:  challenge,
+		Difficulty: authHandl.registration.ProofOfWorkBits,
+	}, http.StatusOK)
+}
+
+// Register signs up a user as the registration policy allows, and grants the policy's sign up roles.
+// The proof of work is bound to the username, so a solved challenge signs up one user.
+// The verification of the given email is requested with a webhook event, see VerifyEmail.
+func (authHandl AuthHandl) Register(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request Register received")
+
+	policy := authHandl.registration
+
+	if !policy.Enabled() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "registration is disabled"}, http.StatusForbidden)
+
+		return
+	}
+
+	var parsedBody model.RegisterRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !policy.AllowsEmail(parsedBody.Email) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the email is not allowed"}, http.StatusForbidden)
+
+		return
+	}
+
+	if policy.ProofOfWorkBits > 0 && (authHandl.jwtService.ValidateChallengeToken(parsedBody.Challenge) != nil ||
+		!encrypt.SolvesChallenge(parsedBody.Challenge, parsedBody.Username, parsedBody.Solution,
+			policy.ProofOfWorkBits)) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the challenge is not solved"}, http.StatusForbidden)
+
+		return
+	}
+
+	hashedPassword, err := encrypt.PasswordEncrypt(parsedBody.Password)
+	if err != nil {
+		log.Printf("Register - hash password err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	tx, err := authHandl.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("Register - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbUser, err := storage.TableUsers.Add(request.Context(), tx, &storage.AddUser{
+		Username: parsedBody.Username,
+		Password: hashedPassword,
+	})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the username is taken"}, http.StatusConflict)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserCreated, model.PrepareWebhookUser(dbUser))
+	}
+
+	if err == nil {
+		err = grantRegistrationRoles(request.Context(), tx, dbUser.ID, policy.SignUpRoles(), nil)
+	}
+
+	if err == nil && parsedBody.Email != "" {
+		err = authHandl.requestEmailVerification(request.Context(), tx, dbUser, parsedBody.Email)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("Register - create user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		ActorID: &dbUser.ID,
+		Actor:   dbUser.Username,
+		Target:  "id=" + dbUser.ID,
+	}, "user %s registered", dbUser.Username)
+
+	writeJSONResponse(respWriter, model.UserCreateResponse{
+		UserID:      dbUser.ID,
+		UserUsernme: model.UserUsernme{Username: dbUser.Username},
+	}, http.StatusOK)
+}
+
+// VerifyEmail saves the email the verification token binds to the policy's email attribute, and grants
+// the roles withheld until the email is verified. A token verifies the email once.
+func (authHandl AuthHandl) VerifyEmail(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request VerifyEmail received")
+
+	policy := authHandl.registration
+
+	if !policy.Enabled() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "registration is disabled"}, http.StatusForbidden)
+
+		return
+	}
+
+	var parsedBody model.VerifyEmailRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, email, err := authHandl.jwtService.ValidateEmailVerificationToken(parsedBody.Token)
+	if err != nil {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+
+		return
+	}
+
+	// the policy may have changed since the sign up.
+	if !policy.AllowsEmail(email) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the email is not allowed"}, http.StatusForbidden)
+
+		return
+	}
+
+	tx, err := authHandl.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("VerifyEmail - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbUser, err := storage.TableUsers.GetByID(request.Context(), tx, userID)
+	if err == nil && (dbUser.DeletedTS != nil || dbUser.Status != storage.UserStatusTypeActive) {
+		err = database.ErrNoRows
+	}
+
+	if err == nil && policy.EmailAttribute != "" && dbUser.Attributes[policy.EmailAttribute] == email {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the email is already verified"}, http.StatusConflict)
+
+		return
+	}
+
+	var heldRoles []storage.UserRole
+
+	if err == nil {
+		heldRoles, err = storage.TableUsersRoles.GetAllByUserID(request.Context(), tx, userID)
+	}
+
+	if err == nil {
+		err = grantRegistrationRoles(request.Context(), tx, userID, policy.VerifiedRoles(), heldRoles)
+	}
+
+	if err == nil && policy.EmailAttribute != "" {
+		_, err = storage.TableUsers.MergeAttributesByID(request.Context(), tx,
+			map[string]any{policy.EmailAttribute: email}, userID)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the user is not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("VerifyEmail - verify email err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		ActorID: &dbUser.ID,
+		Actor:   dbUser.Username,
+		Target:  "id=" + dbUser.ID,
+	}, "user %s verified the email", dbUser.Username)
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// requestEmailVerification publishes the verification token of the user's email to the webhooks.
+func (authHandl AuthHandl) requestEmailVerification(ctx context.Context, querier database.Querier,
+	dbUser *storage.User, email string) error {
+	token, expiresAt, err := authHandl.jwtService.IssueEmailVerificationToken(dbUser.ID, email,
+		registerVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("requestEmailVerification: %w", err)
+	}
+
+	err = webhook.Publish(ctx, querier, model.WebhookEventUserEmailVerificationRequested,
+		model.WebhookEmailVerificationData{
+			ExpiresAt: *expiresAt,
+			UserID:    dbUser.ID,
+			Username:  dbUser.Username,
+			Email:     email,
+			Token:     token,
+		})
+	if err != nil {
+		return fmt.Errorf("requestEmailVerification: %w", err)
+	}
+
+	return nil
+}
+
+// grantRegistrationRoles grants the roles to the user, but for the services the user already has a role in.
+// The held roles are all the user's grants, a scheduled or an expired one holds the service too.
+func grantRegistrationRoles(ctx context.Context, querier database.Querier, userID string,
+	roles []model.UserRoleRequest, heldRoles []storage.UserRole) error {
+	for _, role := range roles {
+		if slices.ContainsFunc(heldRoles, func(held storage.UserRole) bool {
+			return held.ServiceName == role.ServiceName
+		}) {
+			continue
+		}
+
+		grant := storage.AddUserRole{ //nolint:exhaustruct // the role is not time-bound.
+			UserID:      userID,
+			ServiceName: role.ServiceName,
+			UserRole:    role.UserRole,
+		}
+
+		dbRole, err := storage.TableUsersRoles.Add(ctx, querier, &grant)
+		if err == nil {
+			err = webhook.Publish(ctx, querier, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+		}
+
+		if err != nil {
+			return fmt.Errorf("grantRegistrationRoles: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetUserRoles lists the user's direct grants, the scheduled and the expired ones too.
+func (manage ManageHandl) GetUserRoles(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetUserRoles received")
+
+	userID := params.ByName("id")
+	if !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if !manage.userExists(respWriter, request, userID) {
+		return
+	}
+
+	roles, err := storage.TableUsersRoles.GetAllByUserID(request.Context(), manage.dbInstance.GetPool(), userID)
+	if err != nil && !errors.Is(err, database.ErrNoRows) {
+		log.Printf("GetUserRoles - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.UserRolesResponse{
+		UserID: userID,
+		Roles:  model.PrepareUserRoles(roles),
+	}, http.StatusOK)
+}
+
+func (manage ManageHandl) GrantUserRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GrantUserRole received")
+
+	var parsedBody model.UserRoleGrantRequest
+
+	userID := params.ByName("id")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() || !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if parsedBody.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	if !manage.userExists(respWriter, request, userID) || !manage.serviceExists(respWriter, request,
+		parsedBody.ServiceName) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("GrantUserRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	dbRole, err := storage.TableUsersRoles.Add(request.Context(), tx, &storage.AddUserRole{
+		ValidFrom:   parsedBody.ValidFrom,
+		ExpiresAt:   parsedBody.ExpiresAt,
+		UserID:      userID,
+		UserRole:    parsedBody.UserRole,
+		ServiceName: parsedBody.ServiceName,
+	})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the user already has a role in the service"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("GrantUserRole - insert role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareUserRoles([]storage.UserRole{*dbRole})[0], http.StatusOK)
+}
+
+func (manage ManageHandl) UpdateUserRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request UpdateUserRole received")
+
+	var parsedBody model.UserRoleGrantRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if parsedBody.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	dbRole := manage.getUserRoleByParams(respWriter, request, params)
+	if dbRole == nil {
+		return
+	}
+
+	if dbRole.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	if dbRole.ServiceName != parsedBody.ServiceName &&
+		!manage.serviceExists(respWriter, request, parsedBody.ServiceName) {
+		return
+	}
+
+	dbRole.ServiceName = parsedBody.ServiceName
+	dbRole.UserRole = parsedBody.UserRole
+	dbRole.ValidFrom = parsedBody.ValidFrom
+	dbRole.ExpiresAt = parsedBody.ExpiresAt
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("UpdateUserRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableUsersRoles.UpdateByID(request.Context(), tx, dbRole, dbRole.ID)
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the user already has a role in the service"},
+			http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrUnknownRole) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown role"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleUpdated, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("UpdateUserRole - update role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareUserRoles([]storage.UserRole{*dbRole})[0], http.StatusOK)
+}
+
+func (manage ManageHandl) RevokeUserRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RevokeUserRole received")
+
+	dbRole := manage.getUserRoleByParams(respWriter, request, params)
+	if dbRole == nil {
+		return
+	}
+
+	if dbRole.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("RevokeUserRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableUsersRoles.DeleteByID(request.Context(), tx, dbRole.ID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		err = webhook.Publish(request.Context(), tx, model.WebhookEventUserRoleRevoked, model.PrepareWebhookRole(*dbRole))
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("RevokeUserRole - delete role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// getUserRoleByParams looks up the role entry addressed by the route params.
+// An entry that belongs to another user is reported as not found.
+// On failure the response is written and nil is returned.
+func (manage ManageHandl) getUserRoleByParams(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) *storage.UserRole {
+	userID := params.ByName("id")
+
+	roleID, err := strconv.ParseUint(params.ByName("roleId"), 10, 0)
+	if err != nil || !model.ValidUUID(userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return nil
+	}
+
+	dbRole, err := storage.TableUsersRoles.GetByID(request.Context(), manage.dbInstance.GetPool(), uint(roleID))
+	if errors.Is(err, database.ErrNoRows) || (err == nil && dbRole.UserID != userID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return nil
+	}
+
+	if err != nil {
+		log.Printf("getUserRoleByParams - get role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return nil
+	}
+
+	return dbRole
+}
+
+// userExists writes the response and returns false if there is no such user.
+func (manage ManageHandl) userExists(respWriter http.ResponseWriter, request *http.Request, userID string) bool {
+	_, err := storage.TableUsers.GetByID(request.Context(), manage.dbInstance.GetPool(), userID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "user not found"}, http.StatusNotFound)
+
+		return false
+	}
+
+	if err != nil {
+		log.Printf("userExists - get user err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return false
+	}
+
+	return true
+}
+
+// serviceExists writes the response and returns false if there is no such service.
+func (manage ManageHandl) serviceExists(respWriter http.ResponseWriter, request *http.Request,
+	serviceName string) bool {
+	_, err := storage.TableServices.GetByServiceName(request.Context(), manage.dbInstance.GetPool(), serviceName)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unknown service"}, http.StatusBadRequest)
+
+		return false
+	}
+
+	if err != nil {
+		log.Printf("serviceExists - get service err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetServiceRoles lists the roles catalog of the service.
+func (manage ManageHandl) GetServiceRoles(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetServiceRoles received")
+
+	serviceName := params.ByName("name")
+
+	if !manage.serviceExists(respWriter, request, serviceName) {
+		return
+	}
+
+	roles, err := storage.TableServicesRoles.GetByServiceName(request.Context(), manage.dbInstance.GetPool(),
+		serviceName)
+	if err != nil {
+		log.Printf("GetServiceRoles - get roles err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ServiceRolesResponse{
+		ServiceName: serviceName,
+		Roles:       model.PrepareServiceRoles(roles),
+	}, http.StatusOK)
+}
+
+// CreateServiceRole defines a new role in the service's catalog.
+func (manage ManageHandl) CreateServiceRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request CreateServiceRole received")
+
+	var parsedBody model.ServiceRoleRequest
+
+	serviceName := params.ByName("name")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if serviceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	dbRole, err := storage.TableServicesRoles.Add(request.Context(), manage.dbInstance.GetPool(),
+		&storage.ServiceRole{
+			ServiceName: serviceName,
+			Name:        parsedBody.Name,
+			Description: parsedBody.Description,
+		})
+	if errors.Is(err, database.ErrForeignKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the role already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("CreateServiceRole - insert role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.PrepareServiceRoles([]storage.ServiceRole{*dbRole})[0], http.StatusOK)
+}
+
+// DeleteServiceRole removes the role from the service's catalog, revoking it from the users and the groups.
+// With the dryRun=true query the role is kept, only the affected grants are counted.
+func (manage ManageHandl) DeleteServiceRole(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteServiceRole received")
+
+	response := model.ServiceRoleDeleteResponse{
+		ServiceName: params.ByName("name"),
+		Name:        params.ByName("role"),
+		DryRun:      request.URL.Query().Get("dryRun") == "true",
+	}
+
+	if response.ServiceName == model.MyOwnServiceName && response.Name == storage.UserRoleTypeRoot {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the role is protected"}, http.StatusForbidden)
+
+		return
+	}
+
+	if response.ServiceName == model.MyOwnServiceName && !rootOnly(respWriter, request) {
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("DeleteServiceRole - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	roles, err := storage.TableServicesRoles.GetByServiceName(request.Context(), tx, response.ServiceName)
+	if err == nil && !slices.ContainsFunc(roles, func(role storage.ServiceRole) bool {
+		return role.Name == response.Name
+	}) {
+		err = database.ErrNoRows
+	}
+
+	if err == nil {
+		response.GrantsAffected, err = storage.TableServicesRoles.CountGrants(request.Context(), tx,
+			response.ServiceName, response.Name)
+	}
+
+	if err == nil {
+		response.ScopesAffected, err = storage.TableServicesRoles.CountScopes(request.Context(), tx,
+			response.ServiceName, response.Name)
+	}
+
+	if err == nil && !response.DryRun {
+		err = publishCascadedRevocations(request.Context(), tx, response.ServiceName, response.Name)
+	}
+
+	if err == nil && !response.DryRun {
+		err = storage.TableServicesRoles.Delete(request.Context(), tx, response.ServiceName, response.Name)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("DeleteServiceRole - delete role err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, response, http.StatusOK)
+}
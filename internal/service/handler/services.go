@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (manage ManageHandl) GetServices(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request GetServices received")
+
+	services, err := storage.TableServices.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("GetServices - get services err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.ServicesResponse{Services: model.PrepareServices(services)}, http.StatusOK)
+}
+
+func (manage ManageHandl) CreateService(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request CreateService received")
+
+	var parsedBody model.ServiceRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("CreateService - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	err = storage.TableServices.Add(request.Context(), tx, &storage.Service{Name: parsedBody.Name})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the service already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	// the service starts with the default roles in its catalog.
+	if err == nil {
+		err = storage.TableServicesRoles.AddDefaults(request.Context(), tx, parsedBody.Name)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("CreateService - insert service err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, parsedBody, http.StatusOK)
+}
+
+// RenameService changes the service name. The granted roles follow the service.
+func (manage ManageHandl) RenameService(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RenameService received")
+
+	var parsedBody model.ServiceRequest
+
+	serviceName := params.ByName("name")
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	if serviceName == model.MyOwnServiceName {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the service is protected"}, http.StatusForbidden)
+
+		return
+	}
+
+	err = storage.TableServices.Update(request.Context(), manage.dbInstance.GetPool(),
+		&storage.Service{Name: parsedBody.Name}, serviceName)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the service already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RenameService - update service err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, parsedBody, http.StatusOK)
+}
+
+// DeleteService deletes the service together with all the roles granted in it.
+// With the dryRun=true query the service is kept, only the affected roles are counted.
+func (manage ManageHandl) DeleteService(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteService received")
+
+	response := model.ServiceDeleteResponse{
+		Name:   params.ByName("name"),
+		DryRun: request.URL.Query().Get("dryRun") == "true",
+	}
+
+	if response.Name == model.MyOwnServiceName {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the service is protected"}, http.StatusForbidden)
+
+		return
+	}
+
+	tx, err := manage.dbInstance.Begin(request.Context())
+	if err != nil {
+		log.Printf("DeleteService - begin tx err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	defer tx.Rollback(request.Context()) //nolint:errcheck // no-op after commit.
+
+	_, err = storage.TableServices.GetByServiceName(request.Context(), tx, response.Name)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err == nil {
+		response.RolesAffected, err = storage.TableServicesRoles.CountGrants(request.Context(), tx, response.Name, "")
+	}
+
+	if err == nil {
+		response.ScopesAffected, err = storage.TableServicesRoles.CountScopes(request.Context(), tx, response.Name, "")
+	}
+
+	if err == nil && !response.DryRun {
+		err = publishCascadedRevocations(request.Context(), tx, response.Name, "")
+	}
+
+	if err == nil && !response.DryRun {
+		err = storage.TableServices.Delete(request.Context(), tx, response.Name)
+	}
+
+	if err == nil {
+		err = tx.Commit(request.Context())
+	}
+
+	if err != nil {
+		log.Printf("DeleteService - delete service err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, response, http.StatusOK)
+}
+
+// publishCascadedRevocations publishes the revocation of the users' and the groups' roles in the service
+// that a deletion from the catalog cascades away. An empty roleName stands for every role of the service.
+func publishCascadedRevocations(ctx context.Context, querier database.Querier, serviceName,
+	roleName string) error {
+	userRoles, err := storage.TableUsersRoles.GetByServiceName(ctx, querier, serviceName)
+	if err != nil {
+		return fmt.Errorf("publishCascadedRevocations: %w", err)
+	}
+
+	groupRoles, err := storage.TableGroupsRoles.GetAll(ctx, querier)
+	if err != nil {
+		return fmt.Errorf("publishCascadedRevocations: %w", err)
+	}
+
+	userRoles = slices.DeleteFunc(userRoles, func(role storage.UserRole) bool {
+		return roleName != "" && role.UserRole != roleName
+	})
+
+	err = webhook.PublishRevokedRoles(ctx, querier, userRoles)
+	if err != nil {
+		return fmt.Errorf("publishCascadedRevocations: %w", err)
+	}
+
+	for _, role := range groupRoles {
+		if role.ServiceName != serviceName || (roleName != "" && role.UserRole != roleName) {
+			continue
+		}
+
+		err = webhook.Publish(ctx, querier, model.WebhookEventGroupRoleRevoked, model.PrepareWebhookGroupRole(role))
+		if err != nil {
+			return fmt.Errorf("publishCascadedRevocations: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/bootstrap"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Setup creates the first go-auth root with the one-time setup token printed at the startup.
+func (authHandl AuthHandl) Setup(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request Setup received")
+
+	var parsedBody model.SetupRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	var response model.UserCreateResponse
+
+	err = authHandl.setupToken.Use(parsedBody.Token, func() error {
+		dbUser, createErr := bootstrap.CreateRoot(request.Context(), authHandl.dbInstance, parsedBody.UserCreds)
+		if createErr == nil {
+			response.UserID = dbUser.ID
+			response.Username = dbUser.Username
+		}
+
+		return createErr //nolint:wrapcheck // not an actual return
+	})
+	if errors.Is(err, bootstrap.ErrWrongSetupToken) || errors.Is(err, bootstrap.ErrSetupTokenIsUsed) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+
+		return
+	}
+
+	if errors.Is(err, bootstrap.ErrRootExists) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the root already exists"}, http.StatusConflict)
+
+		return
+	}
+
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "the username is taken"}, http.StatusConflict)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("Setup - create root err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, authHandl.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		ActorID: &response.UserID,
+		Actor:   response.Username,
+		Target:  "id=" + response.UserID,
+	}, "the go-auth root %s was set up", response.Username)
+
+	writeJSONResponse(respWriter, response, http.StatusOK)
+}
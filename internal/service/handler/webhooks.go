@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateWebhook subscribes the webhook to the events and returns the secret its deliveries are signed with.
+func (manage ManageHandl) CreateWebhook(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request CreateWebhook received")
+
+	var parsedBody model.WebhookRequest
+
+	err := json.NewDecoder(request.Body).Decode(&parsedBody)
+	if err != nil || !parsedBody.ValidFormat() {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	var createdBy *string
+
+	requesterID, _ := request.Context().Value(ctxKeyRequesterUserID).(string)
+	if requesterID != "" {
+		createdBy = &requesterID
+	}
+
+	events := parsedBody.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	secret, err := webhook.NewSecret()
+
+	var dbWebhook *storage.Webhook
+
+	if err == nil {
+		dbWebhook, err = storage.TableWebhooks.Add(request.Context(), manage.dbInstance.GetPool(),
+			&storage.Webhook{ //nolint:exhaustruct // the rest is set by db.
+				CreatedBy: createdBy,
+				URL:       parsedBody.URL,
+				Secret:    secret,
+				Events:    events,
+			})
+	}
+
+	if err != nil {
+		log.Printf("CreateWebhook - create webhook err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{ //nolint:exhaustruct // the request's event.
+		Target: "id=" + dbWebhook.ID,
+	}, "webhook %s to %s created", dbWebhook.ID, dbWebhook.URL)
+
+	writeJSONResponse(respWriter, model.WebhookCreateResponse{
+		Secret:      dbWebhook.Secret,
+		WebhookInfo: model.PrepareWebhooks([]storage.Webhook{*dbWebhook})[0],
+	}, http.StatusOK)
+}
+
+// GetWebhooks lists the webhooks, the latest first.
+func (manage ManageHandl) GetWebhooks(respWriter http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	log.Printf("request GetWebhooks received")
+
+	webhooks, err := storage.TableWebhooks.GetAll(request.Context(), manage.dbInstance.GetPool())
+	if err != nil {
+		log.Printf("GetWebhooks - get webhooks err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.WebhooksResponse{
+		Webhooks: model.PrepareWebhooks(webhooks),
+	}, http.StatusOK)
+}
+
+// DeleteWebhook removes the webhook, its pending deliveries and dead letters are dropped.
+func (manage ManageHandl) DeleteWebhook(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request DeleteWebhook received")
+
+	webhookID := params.ByName("id")
+	if !model.ValidUUID(webhookID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	err := storage.TableWebhooks.DeleteByID(request.Context(), manage.dbInstance.GetPool(), webhookID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("DeleteWebhook - delete webhook err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{}, //nolint:exhaustruct // the request's event.
+		"webhook %s deleted", webhookID)
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
+
+// GetWebhookDeadLetters lists the deliveries of the webhook that have run out of attempts, the latest first.
+func (manage ManageHandl) GetWebhookDeadLetters(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request GetWebhookDeadLetters received")
+
+	webhookID := params.ByName("id")
+	if !model.ValidUUID(webhookID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	deliveries, err := storage.TableWebhookDeliveries.GetDeadByWebhookID(request.Context(),
+		manage.dbInstance.GetPool(), webhookID)
+	if err != nil {
+		log.Printf("GetWebhookDeadLetters - get dead letters err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSONResponse(respWriter, model.WebhookDeadLettersResponse{
+		WebhookID:   webhookID,
+		DeadLetters: model.PrepareWebhookDeliveries(deliveries),
+	}, http.StatusOK)
+}
+
+// RetryWebhookDelivery makes the dead letter pending again, it is attempted as a new delivery.
+func (manage ManageHandl) RetryWebhookDelivery(respWriter http.ResponseWriter, request *http.Request,
+	params httprouter.Params) {
+	log.Printf("request RetryWebhookDelivery received")
+
+	webhookID := params.ByName("id")
+
+	deliveryID, err := strconv.ParseInt(params.ByName("deliveryId"), 10, 64)
+	if err != nil || !model.ValidUUID(webhookID) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "bad request"}, http.StatusBadRequest)
+
+		return
+	}
+
+	err = storage.TableWebhookDeliveries.RetryDead(request.Context(), manage.dbInstance.GetPool(), webhookID,
+		deliveryID)
+	if errors.Is(err, database.ErrNoRows) {
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "dead letter not found"}, http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Printf("RetryWebhookDelivery - retry delivery err: %s", err.Error())
+		writeJSONResponse(respWriter, model.ErrorResponse{Error: "internal error"}, http.StatusInternalServerError)
+
+		return
+	}
+
+	auditLog(request, manage.dbInstance, storage.AuditEvent{}, //nolint:exhaustruct // the request's event.
+		"webhook %s delivery %d retried", webhookID, deliveryID)
+
+	writeJSONResponse(respWriter, model.ErrorResponse{Error: ""}, http.StatusOK)
+}
@@ -0,0 +1,255 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/encrypt"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+	"github.com/jackc/pgx/v5"
+)
+
+type Options struct {
+	// ChunkSize is the number of rows imported in a transaction, 0 - all the rows in one transaction.
+	ChunkSize int
+	// DryRun rolls back every transaction, the report tells what would have happened.
+	DryRun bool
+}
+
+// rowError is a failure of a single row. It is reported and doesn't abort the import.
+type rowError string
+
+func (err rowError) Error() string {
+	return string(err)
+}
+
+const (
+	errChunkFailed  rowError = "not imported, another row of the transaction has failed"
+	errDupUsername  rowError = "duplicate username in the import"
+	errUsernameUsed rowError = "the username is taken"
+)
+
+// Import creates the users with their roles. A chunk of rows is imported atomically:
+// if any of its rows is invalid or fails to be inserted, none of the chunk is imported.
+// The parseErrors are the rows that failed to be parsed, they fail their chunks too.
+func Import(ctx context.Context, dbInstance *database.Database, rows []model.ImportRow,
+	parseErrors []model.ImportRowError, opts Options) (*model.ImportReport, error) {
+	report := &model.ImportReport{ //nolint:exhaustruct // counters start at zero.
+		Total:  len(rows) + len(parseErrors),
+		DryRun: opts.DryRun,
+		Errors: []model.ImportRowError{},
+	}
+
+	entries := prepareEntries(rows, parseErrors)
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = len(entries)
+	}
+
+	for start := 0; start < len(entries); start += chunkSize {
+		chunk := entries[start:min(start+chunkSize, len(entries))]
+
+		err := importChunk(ctx, dbInstance, chunk, opts.DryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, model.ImportRowError{
+				Line:     entry.row.Line,
+				Username: entry.row.Username,
+				Error:    entry.err.Error(),
+			})
+		} else {
+			report.Imported++
+		}
+	}
+
+	return report, nil
+}
+
+type importEntry struct {
+	err          error
+	passwordHash string
+	row          model.ImportRow
+}
+
+// prepareEntries validates the rows and hashes the passwords. The entries are ordered by the line.
+func prepareEntries(rows []model.ImportRow, parseErrors []model.ImportRowError) []importEntry {
+	entries := make([]importEntry, 0, len(rows)+len(parseErrors))
+	seenUsernames := make(map[string]bool, len(rows))
+
+	for _, row := range rows {
+		entry := importEntry{row: row, passwordHash: row.PasswordHash, err: row.Validate()}
+
+		if entry.err == nil && seenUsernames[row.Username] {
+			entry.err = errDupUsername
+		}
+
+		seenUsernames[row.Username] = true
+		entries = append(entries, entry)
+	}
+
+	hashPasswords(entries)
+
+	for _, parseErr := range parseErrors {
+		entries = append(entries, importEntry{
+			row: model.ImportRow{ //nolint:exhaustruct // only used in the report.
+				UserUsernme: model.UserUsernme{Username: parseErr.Username},
+				Line:        parseErr.Line,
+			},
+			err: rowError(parseErr.Error),
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b importEntry) int {
+		return a.row.Line - b.row.Line
+	})
+
+	return entries
+}
+
+// hashPasswords hashes the plain passwords of the valid entries in parallel, as hashing is slow by design.
+func hashPasswords(entries []importEntry) {
+	jobs := make(chan *importEntry)
+	waitGroup := sync.WaitGroup{}
+
+	for range runtime.NumCPU() {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for entry := range jobs {
+				hash, err := encrypt.PasswordEncrypt(entry.row.Password)
+				if err != nil {
+					entry.err = model.ErrImportBadPassword
+				}
+
+				entry.passwordHash = hash
+			}
+		}()
+	}
+
+	for i := range entries {
+		if entries[i].err == nil && entries[i].row.Password != "" {
+			jobs <- &entries[i]
+		}
+	}
+
+	close(jobs)
+	waitGroup.Wait()
+}
+
+// importChunk inserts the chunk in a transaction. Every row is inserted under a savepoint,
+// so all the failed rows of the chunk are reported, not only the first one.
+func importChunk(ctx context.Context, dbInstance *database.Database, chunk []importEntry, dryRun bool) error {
+	tx, err := dbInstance.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("importer.importChunk: %w", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	chunkFailed := false
+
+	for i := range chunk {
+		if chunk[i].err == nil {
+			var rowErr rowError
+
+			err = importEntryTx(ctx, tx, &chunk[i])
+			if errors.As(err, &rowErr) {
+				chunk[i].err = rowErr
+			} else if err != nil {
+				return err
+			}
+		}
+
+		chunkFailed = chunkFailed || chunk[i].err != nil
+	}
+
+	if chunkFailed {
+		for i := range chunk {
+			if chunk[i].err == nil {
+				chunk[i].err = errChunkFailed
+			}
+		}
+
+		return nil
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("importer.importChunk commit: %w", err)
+	}
+
+	return nil
+}
+
+// importEntryTx inserts the entry under a savepoint. A rowError is returned if the row can't be inserted,
+// any other error is unexpected.
+func importEntryTx(ctx context.Context, tx pgx.Tx, entry *importEntry) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("importer.importEntryTx savepoint: %w", err)
+	}
+
+	defer savepoint.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+	dbUser, err := storage.TableUsers.Add(ctx, savepoint, &storage.AddUser{
+		Username: entry.row.Username,
+		Password: entry.passwordHash,
+	})
+	if errors.Is(err, database.ErrUniqueKeyViolation) {
+		return errUsernameUsed
+	}
+
+	if err == nil {
+		err = webhook.Publish(ctx, savepoint, model.WebhookEventUserCreated, model.PrepareWebhookUser(dbUser))
+	}
+
+	if err != nil {
+		return fmt.Errorf("importer.importEntryTx add user: %w", err)
+	}
+
+	var dbRole *storage.UserRole
+
+	for _, role := range entry.row.Roles {
+		dbRole, err = storage.TableUsersRoles.Add(ctx, savepoint, &storage.AddUserRole{
+			UserID:      dbUser.ID,
+			UserRole:    role.UserRole,
+			ServiceName: role.ServiceName,
+		})
+		if errors.Is(err, storage.ErrUnknownRole) {
+			return rowError("unknown role " + role.UserRole + " in service " + role.ServiceName)
+		}
+
+		if err == nil {
+			err = webhook.Publish(ctx, savepoint, model.WebhookEventUserRoleGranted, model.PrepareWebhookRole(*dbRole))
+		}
+
+		if err != nil {
+			return fmt.Errorf("importer.importEntryTx add role: %w", err)
+		}
+	}
+
+	if err = savepoint.Commit(ctx); err != nil {
+		return fmt.Errorf("importer.importEntryTx release savepoint: %w", err)
+	}
+
+	return nil
+}
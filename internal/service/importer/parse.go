@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eldarbr/go-auth/internal/model"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const (
+	csvRolesSeparator    = ";"
+	csvRoleSeparator     = ":"
+	maxJSONLineLength    = 64 * 1024
+	csvColumnUsername    = "username"
+	csvColumnPassword    = "password"
+	csvColumnPassHash    = "passwordHash"
+	csvColumnRoles       = "roles"
+	firstDataLineOfCSV   = 2
+	firstDataLineOfJSONL = 1
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrBadHeader     = errors.New("bad csv header, the username column is required")
+)
+
+// Parse reads the rows in the format. A row that can't be parsed is returned
+// along with the parse error, so it is reported like any other invalid row.
+func Parse(reader io.Reader, format string) ([]model.ImportRow, []model.ImportRowError, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(reader)
+	case FormatJSONL:
+		return ParseJSONL(reader)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+}
+
+// ParseCSV reads the csv with a header. The known columns are username, password,
+// passwordHash and roles. The roles are listed as service:role separated by semicolons.
+func ParseCSV(reader io.Reader) ([]model.ImportRow, []model.ImportRowError, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1 // the length is checked per row.
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("importer.ParseCSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+
+	if _, ok := columns[csvColumnUsername]; !ok {
+		return nil, nil, ErrBadHeader
+	}
+
+	var (
+		rows      []model.ImportRow
+		rowErrors []model.ImportRowError
+	)
+
+	for line := firstDataLineOfCSV; ; line++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, fmt.Errorf("importer.ParseCSV read: %w", err)
+			}
+
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Error: parseErr.Err.Error()})
+
+			continue
+		}
+
+		if len(record) != len(header) {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Error: "wrong number of fields"})
+
+			continue
+		}
+
+		row := model.ImportRow{ //nolint:exhaustruct // the rest is filled below.
+			UserUsernme:  model.UserUsernme{Username: csvField(record, columns, csvColumnUsername)},
+			Password:     csvField(record, columns, csvColumnPassword),
+			PasswordHash: csvField(record, columns, csvColumnPassHash),
+			Line:         line,
+		}
+
+		row.Roles, err = parseCSVRoles(csvField(record, columns, csvColumnRoles))
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Username: row.Username, Error: err.Error()})
+
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// ParseJSONL reads a json object of model.ImportRow per line. Empty lines are skipped.
+func ParseJSONL(reader io.Reader) ([]model.ImportRow, []model.ImportRowError, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, maxJSONLineLength), maxJSONLineLength)
+
+	var (
+		rows      []model.ImportRow
+		rowErrors []model.ImportRowError
+	)
+
+	for line := firstDataLineOfJSONL; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var row model.ImportRow
+
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&row); err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Error: "bad json"})
+
+			continue
+		}
+
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("importer.ParseJSONL read: %w", err)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func csvField(record []string, columns map[string]int, column string) string {
+	i, ok := columns[column]
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
+
+func parseCSVRoles(field string) ([]model.UserRoleRequest, error) {
+	if field == "" {
+		return nil, nil
+	}
+
+	entries := strings.Split(field, csvRolesSeparator)
+	roles := make([]model.UserRoleRequest, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		// the role can't contain the separator, the service name might.
+		separatorIdx := strings.LastIndex(entry, csvRoleSeparator)
+		if separatorIdx < 0 {
+			return nil, model.ErrImportBadRole
+		}
+
+		roles = append(roles, model.UserRoleRequest{
+			ServiceName: entry[:separatorIdx],
+			UserRole:    entry[separatorIdx+len(csvRoleSeparator):],
+		})
+	}
+
+	return roles, nil
+}
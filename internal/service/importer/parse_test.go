@@ -0,0 +1,88 @@
+package importer_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/service/importer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ = flag.String("t-db-uri", "", "perform sql tests on the `t-db-uri` database")
+
+func TestParseCSV(t *testing.T) {
+	t.Parallel()
+
+	input := "username,password,roles\n" +
+		"username1,password1,service:user\n" +
+		"username2,password2,service:admin;my:service:root\n" +
+		"username3,password3\n" +
+		"username4,password4,service\n" +
+		"username5,password5,\n"
+
+	rows, rowErrors, err := importer.Parse(strings.NewReader(input), importer.FormatCSV)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, model.ImportRow{
+		UserUsernme: model.UserUsernme{Username: "username2"},
+		Password:    "password2",
+		Roles: []model.UserRoleRequest{
+			{ServiceName: "service", UserRole: "admin"},
+			{ServiceName: "my:service", UserRole: "root"},
+		},
+		Line: 3,
+	}, rows[1])
+	assert.Equal(t, 6, rows[2].Line)
+	assert.Empty(t, rows[2].Roles)
+
+	require.Len(t, rowErrors, 2)
+	assert.Equal(t, 4, rowErrors[0].Line)
+	assert.Equal(t, 5, rowErrors[1].Line)
+	assert.Equal(t, "username4", rowErrors[1].Username)
+}
+
+func TestParseCSVBadHeader(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := importer.Parse(strings.NewReader("login,password\nusername,password\n"), importer.FormatCSV)
+
+	require.ErrorIs(t, err, importer.ErrBadHeader)
+}
+
+func TestParseJSONL(t *testing.T) {
+	t.Parallel()
+
+	input := `{"username":"username1","passwordHash":"hash","roles":[{"serviceName":"service","userRole":"user"}]}` +
+		"\n\n" +
+		`{"username":"username2","password":"password2","extra":1}` + "\n" +
+		`{"username":` + "\n" +
+		`{"username":"username4","password":"password4"}`
+
+	rows, rowErrors, err := importer.Parse(strings.NewReader(input), importer.FormatJSONL)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, model.ImportRow{
+		UserUsernme:  model.UserUsernme{Username: "username1"},
+		PasswordHash: "hash",
+		Roles:        []model.UserRoleRequest{{ServiceName: "service", UserRole: "user"}},
+		Line:         1,
+	}, rows[0])
+	assert.Equal(t, 5, rows[1].Line)
+
+	require.Len(t, rowErrors, 2)
+	assert.Equal(t, 3, rowErrors[0].Line)
+	assert.Equal(t, 4, rowErrors[1].Line)
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := importer.Parse(strings.NewReader(""), "xml")
+
+	require.ErrorIs(t, err, importer.ErrUnknownFormat)
+}
@@ -0,0 +1,133 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+// Task is a periodic housekeeping job.
+type Task func(ctx context.Context) error
+
+// Every runs the task at once and then every period until the context is done,
+// the work piled up while the service was down is not left for a whole period.
+// Task errors are logged and don't stop the loop.
+func Every(ctx context.Context, period time.Duration, name string, task Task) {
+	if period <= 0 {
+		return
+	}
+
+	run := func() {
+		if err := task(ctx); err != nil {
+			log.Printf("janitor %s: %s", name, err.Error())
+		}
+	}
+
+	run()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// PurgeDeletedUsers removes for good the users soft deleted more than purgeAfter ago.
+// The revocation of their roles is published.
+func PurgeDeletedUsers(dbInstance *database.Database, purgeAfter time.Duration) Task {
+	return func(ctx context.Context) error {
+		deletedBefore := time.Now().Add(-purgeAfter)
+
+		tx, err := dbInstance.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("PurgeDeletedUsers: %w", err)
+		}
+
+		defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+		roles, err := storage.TableUsersRoles.DeleteByDeletedUsers(ctx, tx, deletedBefore)
+		if err == nil {
+			err = webhook.PublishRevokedRoles(ctx, tx, roles)
+		}
+
+		var purged int64
+
+		if err == nil {
+			purged, err = storage.TableUsers.PurgeDeleted(ctx, tx, deletedBefore)
+		}
+
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+
+		if err != nil {
+			return fmt.Errorf("PurgeDeletedUsers: %w", err)
+		}
+
+		if purged > 0 {
+			log.Printf("janitor purged %d deleted users", purged)
+		}
+
+		return nil
+	}
+}
+
+// PurgeExpiredRoles removes the role grants that have expired, their revocation is published.
+func PurgeExpiredRoles(dbInstance *database.Database) Task {
+	return func(ctx context.Context) error {
+		tx, err := dbInstance.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("PurgeExpiredRoles: %w", err)
+		}
+
+		defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit.
+
+		purged, err := storage.TableUsersRoles.DeleteExpired(ctx, tx, time.Now())
+		if err == nil {
+			err = webhook.PublishRevokedRoles(ctx, tx, purged)
+		}
+
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+
+		if err != nil {
+			return fmt.Errorf("PurgeExpiredRoles: %w", err)
+		}
+
+		if len(purged) > 0 {
+			log.Printf("janitor purged %d expired roles", len(purged))
+		}
+
+		return nil
+	}
+}
+
+// PurgeDeliveredWebhooks removes the webhook deliveries delivered more than purgeAfter ago.
+// The dead letters are kept.
+func PurgeDeliveredWebhooks(dbInstance *database.Database, purgeAfter time.Duration) Task {
+	return func(ctx context.Context) error {
+		purged, err := storage.TableWebhookDeliveries.PurgeDelivered(ctx, dbInstance.GetPool(),
+			time.Now().Add(-purgeAfter))
+		if err != nil {
+			return fmt.Errorf("PurgeDeliveredWebhooks: %w", err)
+		}
+
+		if purged > 0 {
+			log.Printf("janitor purged %d delivered webhook events", purged)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package janitor_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/janitor"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.String("t-db-uri", "", "perform sql tests on the `t-db-uri` database")
+
+func TestEveryRunsAtOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+
+	// the first run stops the loop, long before the period.
+	janitor.Every(ctx, time.Hour, "test", func(context.Context) error {
+		runs++
+
+		cancel()
+
+		return nil
+	})
+
+	assert.Equal(t, 1, runs)
+
+	janitor.Every(context.Background(), 0, "disabled", func(context.Context) error {
+		runs++
+
+		return nil
+	})
+
+	assert.Equal(t, 1, runs)
+}
@@ -3,13 +3,12 @@ package server
 import (
 	"net/http"
 
+	"github.com/eldarbr/go-auth/internal/model"
 	"github.com/eldarbr/go-auth/internal/provider/storage"
 	"github.com/eldarbr/go-auth/internal/service/encrypt"
 	"github.com/julienschmidt/httprouter"
 )
 
-const myOwnServiceName = "go-auth"
-
 type CommonHandlingModule interface {
 	MethodNotAllowed(w http.ResponseWriter, _ *http.Request)
 	NotFound(w http.ResponseWriter, _ *http.Request)
@@ -18,12 +17,79 @@ type CommonHandlingModule interface {
 type AuthHandlingModule interface {
 	Authenticate(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	InitSession(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	Me(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetMyAPIKeys(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateAPIKey(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	RevokeMyAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RedeemInvitation(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetRegisterChallenge(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	Register(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	VerifyEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	Setup(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	MiddlewareAudit(action string, next httprouter.Handle) httprouter.Handle
 }
 
 type ManageHandlingModule interface {
 	CreateUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
-	GetUserInfo(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ImportUsers(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ExportState(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetUsers(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteUser(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RestoreUser(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	UpdateUserStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ImpersonateUser(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetUserAPIKeys(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RevokeUserAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetInvitations(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateInvitation(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	RevokeInvitation(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetUserAttributes(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	UpdateUserAttributes(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetAttributeDefinitions(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateAttributeDefinition(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	DeleteAttributeDefinition(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetUserRoles(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GrantUserRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	UpdateUserRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RevokeUserRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetServices(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateService(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	RenameService(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteService(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetServiceRoles(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateServiceRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteServiceRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetServicePermissions(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateServicePermission(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteServicePermission(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetRolePermissions(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GrantRolePermission(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RevokeRolePermission(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetImpliedRoles(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	AddImpliedRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RemoveImpliedRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetServiceMembers(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	AddServiceMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RemoveServiceMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetGroups(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	DeleteGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetGroupRoles(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GrantGroupRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RevokeGroupRole(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetGroupMembers(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	AddGroupMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RemoveGroupMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetAuditLog(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetWebhooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	CreateWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetWebhookDeadLetters(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RetryWebhookDelivery(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	MiddlewareAudit(action string, next httprouter.Handle) httprouter.Handle
 	MiddlewareAuthorizeAnyClaim(requestedClaims []encrypt.ClaimUserRole, next httprouter.Handle) httprouter.Handle
+	MiddlewareAuthorizePermission(serviceName, permission string, next httprouter.Handle) httprouter.Handle
+	MiddlewareAuthorizeServiceAdmin(serviceParam string, next httprouter.Handle) httprouter.Handle
 	MiddlewareRateLimit(next httprouter.Handle) httprouter.Handle
 }
 
@@ -31,6 +97,28 @@ type RateLimitHandlingModule interface {
 	MiddlewareIPRateLimit(next httprouter.Handle) httprouter.Handle
 }
 
+// auditedRouter registers the routes, the ones of the changes are audited with the route as the action.
+type auditedRouter struct {
+	*httprouter.Router
+	audit func(action string, next httprouter.Handle) httprouter.Handle
+}
+
+func (router auditedRouter) POST(path string, handle httprouter.Handle) {
+	router.Router.POST(path, router.audit(http.MethodPost+" "+path, handle))
+}
+
+func (router auditedRouter) PUT(path string, handle httprouter.Handle) {
+	router.Router.PUT(path, router.audit(http.MethodPut+" "+path, handle))
+}
+
+func (router auditedRouter) PATCH(path string, handle httprouter.Handle) {
+	router.Router.PATCH(path, router.audit(http.MethodPatch+" "+path, handle))
+}
+
+func (router auditedRouter) DELETE(path string, handle httprouter.Handle) {
+	router.Router.DELETE(path, router.audit(http.MethodDelete+" "+path, handle))
+}
+
 func NewRouter(common CommonHandlingModule, auth AuthHandlingModule,
 	manage ManageHandlingModule, ratelimiter RateLimitHandlingModule) http.Handler {
 
@@ -41,21 +129,163 @@ func NewRouter(common CommonHandlingModule, auth AuthHandlingModule,
 	handler.MethodNotAllowed = http.HandlerFunc(common.MethodNotAllowed)
 	handler.NotFound = http.HandlerFunc(common.NotFound)
 
+	// the sign-ins, the sign-ups and the changes are written to the audit log.
+	authRoutes := auditedRouter{Router: handler, audit: auth.MiddlewareAudit}
+	manageRoutes := auditedRouter{Router: handler, audit: manage.MiddlewareAudit}
+
 	// authenticate
-	handler.POST("/auth/authenticate", auth.Authenticate)
-	handler.POST("/auth/initsession", auth.InitSession)
+	authRoutes.POST("/auth/authenticate", auth.Authenticate)
+	authRoutes.POST("/auth/initsession", auth.InitSession)
+
+	// the current user's info.
+	authRoutes.GET("/auth/me", ratelimiter.MiddlewareIPRateLimit(auth.Me))
+
+	// the current user's API keys.
+	authRoutes.GET("/auth/keys", ratelimiter.MiddlewareIPRateLimit(auth.GetMyAPIKeys))
+	authRoutes.POST("/auth/keys", ratelimiter.MiddlewareIPRateLimit(auth.CreateAPIKey))
+	authRoutes.DELETE("/auth/keys/:id", ratelimiter.MiddlewareIPRateLimit(auth.RevokeMyAPIKey))
+
+	// set up the first root with the one-time token printed at the startup.
+	authRoutes.POST("/auth/setup", ratelimiter.MiddlewareIPRateLimit(auth.Setup))
+
+	// sign up as the registration policy allows.
+	authRoutes.GET("/auth/register/challenge", ratelimiter.MiddlewareIPRateLimit(auth.GetRegisterChallenge))
+	authRoutes.POST("/auth/register", ratelimiter.MiddlewareIPRateLimit(auth.Register))
+	authRoutes.POST("/auth/register/verify", ratelimiter.MiddlewareIPRateLimit(auth.VerifyEmail))
+
+	// sign up with an invite token.
+	authRoutes.POST("/auth/invitations/redeem", ratelimiter.MiddlewareIPRateLimit(auth.RedeemInvitation))
+
+	// permitted wraps the handler to be available to the holders of the go-auth permission.
+	permitted := func(permission string, next httprouter.Handle) httprouter.Handle {
+		return ratelimiter.MiddlewareIPRateLimit(manage.MiddlewareAuthorizePermission(model.MyOwnServiceName,
+			permission, manage.MiddlewareRateLimit(next),
+		))
+	}
+
+	// serviceAdmin wraps the handler to be available to the admins of the service
+	// named by the route's :name param, and to the go-auth root.
+	serviceAdmin := func(next httprouter.Handle) httprouter.Handle {
+		return ratelimiter.MiddlewareIPRateLimit(manage.MiddlewareAuthorizeServiceAdmin("name",
+			manage.MiddlewareRateLimit(next),
+		))
+	}
 
 	// create a user.
-	handler.POST("/manage/users", ratelimiter.MiddlewareIPRateLimit(manage.MiddlewareAuthorizeAnyClaim(
-		[]encrypt.ClaimUserRole{{ServiceName: myOwnServiceName, UserRole: storage.UserRoleTypeRoot}},
-		manage.MiddlewareRateLimit(manage.CreateUser),
-	)))
-
-	// get a user.
-	handler.GET("/manage/users", ratelimiter.MiddlewareIPRateLimit(manage.MiddlewareAuthorizeAnyClaim(
-		[]encrypt.ClaimUserRole{{ServiceName: myOwnServiceName, UserRole: storage.UserRoleTypeRoot}},
-		manage.MiddlewareRateLimit(manage.GetUserInfo),
-	)))
+	manageRoutes.POST("/manage/users", permitted(model.PermissionUsersWrite, manage.CreateUser))
+
+	// invite users with pre-assigned roles.
+	manageRoutes.GET("/manage/invitations", permitted(model.PermissionInvitationsRead, manage.GetInvitations))
+	manageRoutes.POST("/manage/invitations", permitted(model.PermissionInvitationsWrite, manage.CreateInvitation))
+	manageRoutes.DELETE("/manage/invitations/:id", permitted(model.PermissionInvitationsWrite, manage.RevokeInvitation))
+
+	// get a user or list users.
+	manageRoutes.GET("/manage/users", permitted(model.PermissionUsersRead, manage.GetUsers))
+
+	// create users in bulk.
+	manageRoutes.POST("/manage/import/users", permitted(model.PermissionUsersWrite, manage.ImportUsers))
+
+	// dump the full state.
+	manageRoutes.GET("/manage/dump", permitted(model.PermissionStateExport, manage.ExportState))
+
+	// soft delete and restore a user.
+	manageRoutes.DELETE("/manage/users/:id", permitted(model.PermissionUsersWrite, manage.DeleteUser))
+	manageRoutes.POST("/manage/users/:id/restore", permitted(model.PermissionUsersWrite, manage.RestoreUser))
+
+	// disable or reactivate a user.
+	manageRoutes.PUT("/manage/users/:id/status", permitted(model.PermissionUsersWrite, manage.UpdateUserStatus))
+
+	// issue an impersonation token of a user, only to the go-auth root.
+	manageRoutes.POST("/manage/users/:id/impersonate", ratelimiter.MiddlewareIPRateLimit(
+		manage.MiddlewareAuthorizeAnyClaim([]encrypt.ClaimUserRole{
+			{ServiceName: model.MyOwnServiceName, UserRole: storage.UserRoleTypeRoot},
+		}, manage.MiddlewareRateLimit(manage.ImpersonateUser)),
+	))
+
+	// look up and revoke user's API keys.
+	manageRoutes.GET("/manage/users/:id/keys", permitted(model.PermissionUsersRead, manage.GetUserAPIKeys))
+	manageRoutes.DELETE("/manage/users/:id/keys/:keyId", permitted(model.PermissionUsersWrite, manage.RevokeUserAPIKey))
+
+	// manage user's attributes.
+	manageRoutes.GET("/manage/users/:id/attributes", permitted(model.PermissionUsersRead, manage.GetUserAttributes))
+	manageRoutes.PATCH("/manage/users/:id/attributes", permitted(model.PermissionUsersWrite, manage.UpdateUserAttributes))
+
+	// manage the attributes schema.
+	manageRoutes.GET("/manage/attributes", permitted(model.PermissionAttributesRead, manage.GetAttributeDefinitions))
+	manageRoutes.POST("/manage/attributes", permitted(model.PermissionAttributesWrite, manage.CreateAttributeDefinition))
+	manageRoutes.DELETE("/manage/attributes/:name",
+		permitted(model.PermissionAttributesWrite, manage.DeleteAttributeDefinition))
+
+	// manage user's roles.
+	manageRoutes.GET("/manage/users/:id/roles", permitted(model.PermissionUsersRead, manage.GetUserRoles))
+	manageRoutes.POST("/manage/users/:id/roles", permitted(model.PermissionRolesWrite, manage.GrantUserRole))
+	manageRoutes.PUT("/manage/users/:id/roles/:roleId", permitted(model.PermissionRolesWrite, manage.UpdateUserRole))
+	manageRoutes.DELETE("/manage/users/:id/roles/:roleId", permitted(model.PermissionRolesWrite, manage.RevokeUserRole))
+
+	// manage services.
+	manageRoutes.GET("/manage/services", permitted(model.PermissionServicesRead, manage.GetServices))
+	manageRoutes.POST("/manage/services", permitted(model.PermissionServicesWrite, manage.CreateService))
+	manageRoutes.PUT("/manage/services/:name", permitted(model.PermissionServicesWrite, manage.RenameService))
+	manageRoutes.DELETE("/manage/services/:name", permitted(model.PermissionServicesWrite, manage.DeleteService))
+
+	// manage service's roles catalog, the service admins may look it up.
+	manageRoutes.GET("/manage/services/:name/roles", serviceAdmin(manage.GetServiceRoles))
+	manageRoutes.POST("/manage/services/:name/roles", permitted(model.PermissionServicesWrite, manage.CreateServiceRole))
+	manageRoutes.DELETE("/manage/services/:name/roles/:role",
+		permitted(model.PermissionServicesWrite, manage.DeleteServiceRole))
+
+	// manage service's permissions catalog and the permissions of the roles.
+	manageRoutes.GET("/manage/services/:name/permissions", serviceAdmin(manage.GetServicePermissions))
+	manageRoutes.POST("/manage/services/:name/permissions",
+		permitted(model.PermissionServicesWrite, manage.CreateServicePermission))
+	manageRoutes.DELETE("/manage/services/:name/permissions/:permission",
+		permitted(model.PermissionServicesWrite, manage.DeleteServicePermission))
+	manageRoutes.GET("/manage/services/:name/roles/:role/permissions", serviceAdmin(manage.GetRolePermissions))
+	manageRoutes.POST("/manage/services/:name/roles/:role/permissions",
+		permitted(model.PermissionServicesWrite, manage.GrantRolePermission))
+	manageRoutes.DELETE("/manage/services/:name/roles/:role/permissions/:permission",
+		permitted(model.PermissionServicesWrite, manage.RevokeRolePermission))
+
+	// manage service's roles hierarchy.
+	manageRoutes.GET("/manage/services/:name/roles/:role/implies", serviceAdmin(manage.GetImpliedRoles))
+	manageRoutes.POST("/manage/services/:name/roles/:role/implies",
+		permitted(model.PermissionServicesWrite, manage.AddImpliedRole))
+	manageRoutes.DELETE("/manage/services/:name/roles/:role/implies/:implied",
+		permitted(model.PermissionServicesWrite, manage.RemoveImpliedRole))
+
+	// manage service's members, delegated to the service admins.
+	manageRoutes.GET("/manage/services/:name/members", serviceAdmin(manage.GetServiceMembers))
+	manageRoutes.POST("/manage/services/:name/members", serviceAdmin(manage.AddServiceMember))
+	manageRoutes.DELETE("/manage/services/:name/members/:userId", serviceAdmin(manage.RemoveServiceMember))
+
+	// manage groups, their roles and members.
+	manageRoutes.GET("/manage/groups", permitted(model.PermissionGroupsRead, manage.GetGroups))
+	manageRoutes.POST("/manage/groups", permitted(model.PermissionGroupsWrite, manage.CreateGroup))
+	manageRoutes.DELETE("/manage/groups/:name", permitted(model.PermissionGroupsWrite, manage.DeleteGroup))
+	manageRoutes.GET("/manage/groups/:name/roles", permitted(model.PermissionGroupsRead, manage.GetGroupRoles))
+	manageRoutes.POST("/manage/groups/:name/roles", permitted(model.PermissionRolesWrite, manage.GrantGroupRole))
+	manageRoutes.DELETE("/manage/groups/:name/roles/:roleId",
+		permitted(model.PermissionRolesWrite, manage.RevokeGroupRole))
+	manageRoutes.GET("/manage/groups/:name/members", permitted(model.PermissionGroupsRead, manage.GetGroupMembers))
+	manageRoutes.POST("/manage/groups/:name/members", permitted(model.PermissionGroupsWrite, manage.AddGroupMember))
+	manageRoutes.DELETE("/manage/groups/:name/members/:userId",
+		permitted(model.PermissionGroupsWrite, manage.RemoveGroupMember))
+
+	// subscribe webhooks to the events, look up and retry their dead letters.
+	manageRoutes.GET("/manage/webhooks", permitted(model.PermissionWebhooksRead, manage.GetWebhooks))
+	manageRoutes.POST("/manage/webhooks", permitted(model.PermissionWebhooksWrite, manage.CreateWebhook))
+	manageRoutes.DELETE("/manage/webhooks/:id", permitted(model.PermissionWebhooksWrite, manage.DeleteWebhook))
+	manageRoutes.GET("/manage/webhooks/:id/dead-letters",
+		permitted(model.PermissionWebhooksRead, manage.GetWebhookDeadLetters))
+	manageRoutes.POST("/manage/webhooks/:id/dead-letters/:deliveryId/retry",
+		permitted(model.PermissionWebhooksWrite, manage.RetryWebhookDelivery))
+
+	// query the audit log, only the go-auth root.
+	manageRoutes.GET("/manage/audit", ratelimiter.MiddlewareIPRateLimit(
+		manage.MiddlewareAuthorizeAnyClaim([]encrypt.ClaimUserRole{
+			{ServiceName: model.MyOwnServiceName, UserRole: storage.UserRoleTypeRoot},
+		}, manage.MiddlewareRateLimit(manage.GetAuditLog)),
+	))
 
 	return handler
 }
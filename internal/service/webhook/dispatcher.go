@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+const (
+	dispatchBatch     = 50
+	responseBodyLimit = 4 << 10
+)
+
+// Dispatcher delivers the pending events of the outbox.
+type Dispatcher struct {
+	dbInstance *database.Database
+	client     *http.Client
+	timeout    time.Duration
+}
+
+// NewDispatcher makes a dispatcher whose deliveries time out after the timeout.
+func NewDispatcher(dbInstance *database.Database, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		dbInstance: dbInstance,
+		client:     &http.Client{Timeout: timeout}, //nolint:exhaustruct // the defaults.
+		timeout:    timeout,
+	}
+}
+
+// Deliver attempts the due deliveries. A delivery is done once the webhook responds with a 2xx status,
+// otherwise it is retried later. Deliver is a janitor.Task.
+func (dispatcher *Dispatcher) Deliver(ctx context.Context) error {
+	// the lease outlasts the attempts of the batch, so a delivery is not attempted twice at a time.
+	deliveries, err := storage.TableWebhookDeliveries.Claim(ctx, dispatcher.dbInstance.GetPool(), dispatchBatch,
+		dispatchBatch*dispatcher.timeout+time.Minute)
+	if err != nil {
+		return fmt.Errorf("Deliver: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		deliveryErr := dispatcher.post(ctx, &delivery)
+		if deliveryErr == nil {
+			err = storage.TableWebhookDeliveries.MarkDelivered(ctx, dispatcher.dbInstance.GetPool(), delivery.ID)
+		} else {
+			err = dispatcher.markFailed(ctx, &delivery, deliveryErr)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Deliver: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) markFailed(ctx context.Context, delivery *storage.WebhookDelivery,
+	deliveryErr error) error {
+	var retryAt *time.Time
+
+	if attempts := delivery.Attempts + 1; attempts < MaxAttempts {
+		nextAttempt := time.Now().Add(Backoff(attempts))
+		retryAt = &nextAttempt
+	} else {
+		log.Printf("webhook delivery %d to %s is dead: %s", delivery.ID, delivery.URL, deliveryErr.Error())
+	}
+
+	err := storage.TableWebhookDeliveries.MarkFailed(ctx, dispatcher.dbInstance.GetPool(), delivery.ID,
+		deliveryErr.Error(), retryAt)
+	if err != nil {
+		return fmt.Errorf("markFailed: %w", err)
+	}
+
+	return nil
+}
+
+// post sends the signed event to the webhook.
+func (dispatcher *Dispatcher) post(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderSignature, Sign(delivery.Secret, time.Now(), delivery.Payload))
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+
+	response, err := dispatcher.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	// drained, so the connection is reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, responseBodyLimit))
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("post: %w %d", ErrUnexpectedStatus, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+// Package webhook notifies the subscribed webhooks of the events.
+//
+// The events are published to an outbox within the transaction of the change, and are delivered once committed.
+// A delivery is a POST of the event as JSON, signed with the secret of the webhook:
+//
+//	X-Go-Auth-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">
+//
+// Failed deliveries are retried with an exponential backoff, and are dead letters after MaxAttempts.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/model"
+	"github.com/eldarbr/go-auth/internal/provider/storage"
+	"github.com/eldarbr/go-auth/pkg/database"
+)
+
+var (
+	ErrBadSignature     = errors.New("the signature is not valid")
+	ErrSignatureExpired = errors.New("the signature is out of the tolerance")
+	ErrUnexpectedStatus = errors.New("the webhook responded with the status")
+)
+
+const (
+	HeaderSignature = "X-Go-Auth-Signature"
+	HeaderEvent     = "X-Go-Auth-Event"
+	HeaderDelivery  = "X-Go-Auth-Delivery"
+)
+
+const (
+	// MaxAttempts is the count of the attempts before a delivery is dead.
+	MaxAttempts = 10
+
+	backoffBase = 30 * time.Second
+	backoffMax  = 6 * time.Hour
+
+	secretPrefix  = "whsec_"
+	secretBytes   = 32
+	eventIDPrefix = "evt_"
+	eventIDBytes  = 16
+)
+
+// Publish enqueues the event for the webhooks subscribed to it.
+// Publish within the transaction of the change, the event is only delivered if the change is committed.
+func Publish(ctx context.Context, querier database.Querier, eventType string, data any) error {
+	eventID, err := randomHex(eventIDBytes)
+	if err != nil {
+		return fmt.Errorf("Publish: %w", err)
+	}
+
+	payload, err := json.Marshal(model.WebhookEvent{
+		CreatedTS: time.Now().UTC(),
+		Data:      data,
+		ID:        eventIDPrefix + eventID,
+		Type:      eventType,
+	})
+	if err != nil {
+		return fmt.Errorf("Publish: %w", err)
+	}
+
+	err = storage.TableWebhookDeliveries.Enqueue(ctx, querier, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("Publish: %w", err)
+	}
+
+	return nil
+}
+
+// PublishRevokedRoles publishes the revocation of every role, see Publish.
+func PublishRevokedRoles(ctx context.Context, querier database.Querier, roles []storage.UserRole) error {
+	for _, role := range roles {
+		err := Publish(ctx, querier, model.WebhookEventUserRoleRevoked, model.PrepareWebhookRole(role))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewSecret generates a webhook secret.
+func NewSecret() (string, error) {
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return "", fmt.Errorf("NewSecret: %w", err)
+	}
+
+	return secretPrefix + secret, nil
+}
+
+// Sign makes the signature header of the body sent at the moment.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	return "t=" + unix + ",v1=" + hex.EncodeToString(signature(secret, unix, body))
+}
+
+// Verify tests the signature header of the body, the signature must have been made within the tolerance of now.
+// The receivers are to verify the deliveries the same way.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix, signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+
+		switch key {
+		case "t":
+			unix = append(unix, value)
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if len(unix) != 1 {
+		return ErrBadSignature
+	}
+
+	signedAt, err := strconv.ParseInt(unix[0], 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+
+	if age := now.Sub(time.Unix(signedAt, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := signature(secret, unix[0], body)
+
+	for _, candidate := range signatures {
+		decoded, err := hex.DecodeString(candidate)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrBadSignature
+}
+
+// Backoff is the delay before the next attempt after the count of failed attempts,
+// doubling from backoffBase up to backoffMax.
+func Backoff(attempts int) time.Duration {
+	delay := backoffBase
+
+	for i := 1; i < attempts && delay < backoffMax; i++ {
+		delay *= 2
+	}
+
+	return min(delay, backoffMax)
+}
+
+func signature(secret, unix string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err //nolint:wrapcheck // wrapped by the callers.
+	}
+
+	return hex.EncodeToString(buf), nil
+}
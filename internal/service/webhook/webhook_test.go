@@ -0,0 +1,61 @@
+package webhook_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/go-auth/internal/service/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	const secret = "whsec_secret"
+
+	body := []byte(`{"type":"user.created"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := webhook.Sign(secret, signedAt, body)
+
+	assert.True(t, strings.HasPrefix(header, "t=1700000000,v1="))
+	require.NoError(t, webhook.Verify(secret, header, body, signedAt.Add(time.Minute), 5*time.Minute))
+
+	// rotated secrets are verified by any of the signatures.
+	require.NoError(t, webhook.Verify(secret, header+",v1=00ff", body, signedAt, time.Minute))
+
+	require.ErrorIs(t, webhook.Verify("whsec_other", header, body, signedAt, time.Minute), webhook.ErrBadSignature)
+	require.ErrorIs(t, webhook.Verify(secret, header, []byte(`{}`), signedAt, time.Minute), webhook.ErrBadSignature)
+	require.ErrorIs(t, webhook.Verify(secret, header, body, signedAt.Add(time.Hour), time.Minute),
+		webhook.ErrSignatureExpired)
+	require.ErrorIs(t, webhook.Verify(secret, "v1=00ff", body, signedAt, time.Minute), webhook.ErrBadSignature)
+	require.ErrorIs(t, webhook.Verify(secret, "", body, signedAt, time.Minute), webhook.ErrBadSignature)
+}
+
+func TestNewSecret(t *testing.T) {
+	t.Parallel()
+
+	first, err := webhook.NewSecret()
+	require.NoError(t, err)
+
+	second, err := webhook.NewSecret()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "whsec_"))
+	assert.NotEqual(t, first, second)
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 30*time.Second, webhook.Backoff(0))
+	assert.Equal(t, 30*time.Second, webhook.Backoff(1))
+	assert.Equal(t, time.Minute, webhook.Backoff(2))
+	assert.Equal(t, 4*time.Minute, webhook.Backoff(4))
+	assert.Equal(t, 6*time.Hour, webhook.Backoff(webhook.MaxAttempts*10))
+
+	for attempts := 1; attempts < webhook.MaxAttempts; attempts++ {
+		assert.LessOrEqual(t, webhook.Backoff(attempts), webhook.Backoff(attempts+1))
+	}
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
-	_ "github.com/golang-migrate/migrate/v4/source/file" // File driver import for .sql migrations.
+	pgxv5 "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	ErrAlreadyInitialized = errors.New("the database is initialized already")
-	ErrDBNotInitilized    = errors.New("database was not initialized")
-	ErrNilArgument        = errors.New("nil argument received")
-	ErrNoRows             = errors.New("query has returned no rows")
-	ErrUniqueKeyViolation = errors.New("unique key constraint was vioalted")
+	ErrAlreadyInitialized  = errors.New("the database is initialized already")
+	ErrDBNotInitilized     = errors.New("database was not initialized")
+	ErrNilArgument         = errors.New("nil argument received")
+	ErrNoRows              = errors.New("query has returned no rows")
+	ErrUniqueKeyViolation  = errors.New("unique key constraint was vioalted")
+	ErrForeignKeyViolation = errors.New("foreign key constraint was violated")
 )
 
 // Database object should be passed from the owner to users via reference, so after the owner closes
@@ -25,8 +25,8 @@ type Database struct {
 	dbPool *pgxpool.Pool
 }
 
-// Setup prepares the connection pool and runs MigrateAuthUp.
-func Setup(ctx context.Context, dbURI, migrationsPath string) (*Database, error) {
+// Setup prepares the connection pool and runs MigrateAuthUp, unless the migrations are nil.
+func Setup(ctx context.Context, dbURI string, migrations fs.FS) (*Database, error) {
 	var err error
 
 	// Connect to the db.
@@ -44,35 +44,29 @@ func Setup(ctx context.Context, dbURI, migrationsPath string) (*Database, error)
 	}
 
 	// Migrate the db.
-	err = MigrateAuthUp(dbURI, migrationsPath)
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		dbInstancePtr.ClosePool()
+	if migrations != nil {
+		err = MigrateAuthUp(dbURI, migrations)
+		if err != nil {
+			dbInstancePtr.ClosePool()
 
-		return nil, fmt.Errorf("database.Setup Migration failed: %w", err)
+			return nil, fmt.Errorf("database.Setup Migration failed: %w", err)
+		}
 	}
 
 	return dbInstancePtr, nil
 }
 
-func MigrateAuthUp(dbURI, migrationsPath string) error {
-	var err error
-
-	postgres := &pgx.Postgres{}
-
-	dbConn, err := postgres.Open(dbURI)
+// MigrateAuthUp applies all the pending migrations.
+func MigrateAuthUp(dbURI string, migrations fs.FS) error {
+	migrator, err := NewMigrator(dbURI, migrations)
 	if err != nil {
-		return fmt.Errorf("database.migrateAuth Connection failed: %w", err)
+		return fmt.Errorf("database.migrateAuth: %w", err)
 	}
-	defer dbConn.Close()
 
-	migration, err := migrate.NewWithDatabaseInstance(migrationsPath, "pgx", dbConn)
-	if err != nil {
-		return fmt.Errorf("database.migrateAuth Migration failed: %w", err)
-	}
+	defer migrator.Close() //nolint:errcheck // the migrations are applied already.
 
-	err = migration.Up()
-	if err != nil {
-		return fmt.Errorf("database.migrateAuth Migration.Up failed: %w", err)
+	if err = migrator.Up(0); err != nil {
+		return fmt.Errorf("database.migrateAuth: %w", err)
 	}
 
 	return nil
@@ -86,6 +80,25 @@ func (db *Database) GetPool() *pgxpool.Pool {
 	return db.dbPool
 }
 
+// Begin starts a transaction on the pool.
+func (db *Database) Begin(ctx context.Context) (pgxv5.Tx, error) {
+	return db.BeginTx(ctx, pgxv5.TxOptions{}) //nolint:exhaustruct // the defaults.
+}
+
+// BeginTx starts a transaction with the options on the pool.
+func (db *Database) BeginTx(ctx context.Context, txOptions pgxv5.TxOptions) (pgxv5.Tx, error) {
+	if db == nil || db.dbPool == nil {
+		return nil, ErrDBNotInitilized
+	}
+
+	tx, err := db.dbPool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, fmt.Errorf("database.BeginTx failed: %w", err)
+	}
+
+	return tx, nil
+}
+
 func (db *Database) ClosePool() {
 	if db == nil {
 		return
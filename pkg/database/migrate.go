@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrator applies the migrations of a file system to the database.
+// The file system holds the NNN_name.up.sql and NNN_name.down.sql files at its root.
+type Migrator struct {
+	migration *migrate.Migrate
+}
+
+func NewMigrator(dbURI string, migrations fs.FS) (*Migrator, error) {
+	source, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("database.NewMigrator Source failed: %w", err)
+	}
+
+	postgres := &pgx.Postgres{}
+
+	dbConn, err := postgres.Open(dbURI)
+	if err != nil {
+		source.Close() //nolint:errcheck // the original error is returned.
+
+		return nil, fmt.Errorf("database.NewMigrator Connection failed: %w", err)
+	}
+
+	migration, err := migrate.NewWithInstance("iofs", source, "pgx", dbConn)
+	if err != nil {
+		source.Close() //nolint:errcheck // the original error is returned.
+		dbConn.Close() //nolint:errcheck // the original error is returned.
+
+		return nil, fmt.Errorf("database.NewMigrator Migration failed: %w", err)
+	}
+
+	return &Migrator{migration: migration}, nil
+}
+
+// Up applies the next steps migrations, all of them if steps is 0.
+func (migrator *Migrator) Up(steps int) error {
+	if steps == 0 {
+		return noChangeIsOk(migrator.migration.Up(), "Up")
+	}
+
+	return noChangeIsOk(migrator.migration.Steps(steps), "Up")
+}
+
+// Down reverts the last steps migrations, all of them if steps is 0.
+func (migrator *Migrator) Down(steps int) error {
+	if steps == 0 {
+		return noChangeIsOk(migrator.migration.Down(), "Down")
+	}
+
+	return noChangeIsOk(migrator.migration.Steps(-steps), "Down")
+}
+
+// Goto migrates up or down to the version.
+func (migrator *Migrator) Goto(version uint) error {
+	return noChangeIsOk(migrator.migration.Migrate(version), "Goto")
+}
+
+// Force sets the version without migrating and clears the dirty state. The version -1 means no migrations.
+func (migrator *Migrator) Force(version int) error {
+	if err := migrator.migration.Force(version); err != nil {
+		return fmt.Errorf("database.Migrator.Force failed: %w", err)
+	}
+
+	return nil
+}
+
+// Version returns the current version, 0 if no migrations were applied.
+// A dirty version has failed to migrate and has to be fixed manually and forced.
+func (migrator *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := migrator.migration.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("database.Migrator.Version failed: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.migration.Close()
+
+	return errors.Join(sourceErr, dbErr)
+}
+
+func noChangeIsOk(err error, operation string) error {
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("database.Migrator.%s failed: %w", operation, err)
+	}
+
+	return nil
+}